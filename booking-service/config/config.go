@@ -5,6 +5,7 @@ import (
 	"fmt"
 	"log"
 	"os"
+	"time"
 
 	"github.com/joho/godotenv"
 	_ "github.com/lib/pq"
@@ -42,3 +43,13 @@ func InitDB() {
 
 	log.Println("Database connection established successfully!")
 }
+
+// HoldSweepInterval returns how often expired booking holds are released,
+// read from HOLD_SWEEP_INTERVAL and defaulting to one minute.
+func HoldSweepInterval() time.Duration {
+	interval, err := time.ParseDuration(os.Getenv("HOLD_SWEEP_INTERVAL"))
+	if err != nil || interval <= 0 {
+		return time.Minute
+	}
+	return interval
+}
@@ -1,5 +1,9 @@
 package dto
 
+import (
+	model "booking-service/models"
+	"time"
+)
 
 type ErrorResponse struct {
 	Message string `json:"message"`
@@ -7,29 +11,61 @@ type ErrorResponse struct {
 
 
 type CreateBookingRequest struct {
-	UserID        int     `json:"user_id"`
-	RoomID        int     `json:"room_id"`
-	CheckinDate   string  `json:"checkin_date"`
-	CheckoutDate  string  `json:"checkout_date"`
-	TotalPrice    float64 `json:"total_price"`
+	UserID       int            `json:"user_id"`
+	RoomID       int            `json:"room_id"`
+	CheckinDate  string         `json:"checkin_date"`
+	CheckoutDate string         `json:"checkout_date"`
+	TotalPrice   float64        `json:"total_price"`
+	Adults       int            `json:"adults"`
+	Children     int            `json:"children"`
+	Guests       []GuestRequest `json:"guests"`
+}
+
+// GuestRequest describes a guest staying under a booking. Only the name is
+// required up front; the rest can be filled in by the front desk before
+// check-in.
+type GuestRequest struct {
+	IsPrimary      bool   `json:"is_primary"`
+	FirstName      string `json:"first_name"`
+	LastName       string `json:"last_name"`
+	Email          string `json:"email"`
+	Phone          string `json:"phone"`
+	Nationality    string `json:"nationality"`
+	DateOfBirth    string `json:"date_of_birth"`
+	DocumentType   string `json:"document_type"`
+	DocumentNumber string `json:"document_number"`
+}
+
+type UpdatePartyRequest struct {
+	Adults   int `json:"adults"`
+	Children int `json:"children"`
 }
 
+// CreateRoomRequest leaves MaxAdults, MaxChildren and BedConfiguration
+// out to take the defaults of the room type.
 type CreateRoomRequest struct {
-	HotelID       int     `json:"hotel_id"`
-	RoomNumber    string  `json:"room_number"`
-	RoomType      string  `json:"room_type"`
-	PricePerNight float64 `json:"price_per_night"`
-	Description   string  `json:"description"`
-	Status        string  `json:"status"`
+	HotelID          int                     `json:"hotel_id"`
+	RoomNumber       string                  `json:"room_number"`
+	RoomType         string                  `json:"room_type"`
+	PricePerNight    float64                 `json:"price_per_night"`
+	Description      string                  `json:"description"`
+	Status           string                  `json:"status"`
+	MaxAdults        *int                    `json:"max_adults"`
+	MaxChildren      *int                    `json:"max_children"`
+	BedConfiguration *model.BedConfiguration `json:"bed_configuration"`
 }
 
 type CreateHotelRequest struct {
-	Name        string `json:"name"`
-	Address     string `json:"address"`
-	City        string `json:"city"`
-	Country     string `json:"country"`
-	PhoneNumber string `json:"phone_number"`
-	Email       string `json:"email"`
+	Name           string `json:"name"`
+	Address        string `json:"address"`
+	City           string `json:"city"`
+	Country        string `json:"country"`
+	PhoneNumber    string `json:"phone_number"`
+	Email          string `json:"email"`
+	HoldTTLMinutes *int   `json:"hold_ttl_minutes"`
+
+	FreeCancellationDays       *int     `json:"free_cancellation_days"`
+	CancellationPenaltyPercent *float64 `json:"cancellation_penalty_percent"`
 }
 
 type CreateHotelResponse struct {
@@ -44,13 +80,44 @@ type CreateRoomResponse struct {
 
 
 type CreateBookingResponse struct {
-	BookingId int `json:"booking_id"`
-	Message string `json:"message"`
+	BookingId    int           `json:"booking_id"`
+	TotalPrice   float64       `json:"total_price"`
+	NightlyRates []NightlyRate `json:"nightly_rates"`
+	ExpiresAt    time.Time     `json:"expires_at"`
+	Message      string        `json:"message"`
+}
+
+type NightlyRate struct {
+	Date       string  `json:"date"`
+	Price      float64 `json:"price"`
+	RatePlanID *int    `json:"rate_plan_id,omitempty"`
+	RatePlan   string  `json:"rate_plan,omitempty"`
 }
 
+type QuoteBookingRequest struct {
+	RoomID       int    `json:"room_id"`
+	CheckinDate  string `json:"checkin_date"`
+	CheckoutDate string `json:"checkout_date"`
+	Adults       int    `json:"adults"`
+	Children     int    `json:"children"`
+}
+
+type QuoteBookingResponse struct {
+	RoomID       int           `json:"room_id"`
+	CheckinDate  string        `json:"checkin_date"`
+	CheckoutDate string        `json:"checkout_date"`
+	Nights       int           `json:"nights"`
+	NightlyRates []NightlyRate `json:"nightly_rates"`
+	TotalPrice   float64       `json:"total_price"`
+	Available    bool          `json:"available"`
+}
+
+// UpdateBookingStatusRequest is sent by payment-service once a booking is
+// paid. Amount is what was paid.
 type UpdateBookingStatusRequest struct {
-	BookingID int    `json:"booking_id"`
-	Status    string `json:"status"`
+	BookingID int     `json:"booking_id"`
+	Status    string  `json:"status"`
+	Amount    float64 `json:"amount"`
 }
 
 type SuccessResponse struct {
@@ -58,13 +125,224 @@ type SuccessResponse struct {
 }
 
 
-type UpdateBookingRefundStatusRequest struct {
-	BookingID *int    `json:"booking_id" validate:"required"`
-	UserID    *int    `json:"user_id" validate:"required"`
-	Status    string `json:"status" validate:"required"`
-}
-
 type UpdateCheckinStatusRequest struct {
 	BookingID     *int   `json:"booking_id"`
 	CheckinStatus string `json:"checkin_status"`
-}
\ No newline at end of file
+}
+
+type SearchRoomResult struct {
+	model.Room
+	Nights     int     `json:"nights"`
+	TotalPrice float64 `json:"total_price"`
+}
+
+type SearchHotelResult struct {
+	model.Hotel
+	Rooms []SearchRoomResult `json:"rooms"`
+}
+
+type RatePlanRequest struct {
+	RoomID            int      `json:"room_id"`
+	Name              string   `json:"name"`
+	StartDate         *string  `json:"start_date"`
+	EndDate           *string  `json:"end_date"`
+	DaysOfWeek        []int64  `json:"days_of_week"`
+	PricePerNight     *float64 `json:"price_per_night"`
+	AdjustmentPercent *float64 `json:"adjustment_percent"`
+	Priority          int      `json:"priority"`
+}
+
+type CreateRatePlanResponse struct {
+	RatePlanID int    `json:"rate_plan_id"`
+	Message    string `json:"message"`
+}
+
+type ExpirePaymentRequest struct {
+	BookingID     int  `json:"booking_id,omitempty"`
+	ReservationID *int `json:"reservation_id,omitempty"`
+}
+
+type CancelBookingRequest struct {
+	UserID *int `json:"user_id"`
+}
+
+type CancelBookingResponse struct {
+	BookingID        int     `json:"booking_id"`
+	CancellationFee  float64 `json:"cancellation_fee"`
+	RefundableAmount float64 `json:"refundable_amount"`
+	RefundRequested  bool    `json:"refund_requested"`
+	Message          string  `json:"message"`
+}
+
+type CancellationRefundRequest struct {
+	BookingID        int     `json:"booking_id"`
+	ReservationID    *int    `json:"reservation_id,omitempty"`
+	UserID           int     `json:"user_id"`
+	RefundableAmount float64 `json:"refundable_amount"`
+}
+
+type ModifyBookingRequest struct {
+	UserID       *int    `json:"user_id"`
+	RoomID       *int    `json:"room_id"`
+	CheckinDate  *string `json:"checkin_date"`
+	CheckoutDate *string `json:"checkout_date"`
+}
+
+type ModifyBookingResponse struct {
+	BookingID       int              `json:"booking_id"`
+	ChangeID        int              `json:"change_id"`
+	RoomID          int              `json:"room_id"`
+	CheckinDate     string           `json:"checkin_date"`
+	CheckoutDate    string           `json:"checkout_date"`
+	TotalPrice      float64          `json:"total_price"`
+	NightlyRates    []NightlyRate    `json:"nightly_rates"`
+	PriceDifference float64          `json:"price_difference"`
+	Adjustment      model.Adjustment `json:"adjustment"`
+	PaymentUID      *string          `json:"payment_uid,omitempty"`
+	RefundID        *int             `json:"refund_id,omitempty"`
+	ExpiresAt       *time.Time       `json:"expires_at,omitempty"`
+	Message         string           `json:"message"`
+}
+
+// BookingChangeCallbackRequest is sent by payment-service once the extra
+// charge of a booking change is paid. Amount is what was paid.
+type BookingChangeCallbackRequest struct {
+	PaymentUID string  `json:"payment_uid"`
+	Amount     float64 `json:"amount"`
+}
+
+type PaymentAdjustmentRequest struct {
+	BookingID     int     `json:"booking_id"`
+	ReservationID *int    `json:"reservation_id,omitempty"`
+	UserID        int     `json:"user_id"`
+	Amount        float64 `json:"amount"`
+}
+
+type PaymentAdjustmentResponse struct {
+	PaymentUID *string `json:"payment_uid,omitempty"`
+	RefundID   *int    `json:"refund_id,omitempty"`
+	Message    string  `json:"message"`
+}
+
+type ReservationLineRequest struct {
+	RoomID       int            `json:"room_id"`
+	CheckinDate  string         `json:"checkin_date"`
+	CheckoutDate string         `json:"checkout_date"`
+	Adults       int            `json:"adults"`
+	Children     int            `json:"children"`
+	Guests       []GuestRequest `json:"guests"`
+}
+
+type CreateReservationRequest struct {
+	UserID     int                      `json:"user_id"`
+	Lines      []ReservationLineRequest `json:"lines"`
+	TotalPrice float64                  `json:"total_price"`
+}
+
+type ReservationLineResponse struct {
+	BookingID    int           `json:"booking_id"`
+	RoomID       int           `json:"room_id"`
+	CheckinDate  string        `json:"checkin_date"`
+	CheckoutDate string        `json:"checkout_date"`
+	TotalPrice   float64       `json:"total_price"`
+	NightlyRates []NightlyRate `json:"nightly_rates"`
+}
+
+type CreateReservationResponse struct {
+	ReservationID int                       `json:"reservation_id"`
+	TotalPrice    float64                   `json:"total_price"`
+	ExpiresAt     time.Time                 `json:"expires_at"`
+	Lines         []ReservationLineResponse `json:"lines"`
+	Message       string                    `json:"message"`
+}
+
+// UpdateReservationStatusRequest is sent by payment-service once a
+// reservation is paid. Amount is what was paid.
+type UpdateReservationStatusRequest struct {
+	ReservationID int     `json:"reservation_id"`
+	Status        string  `json:"status"`
+	Amount        float64 `json:"amount"`
+}
+
+type RoomBlockRequest struct {
+	BlockType string `json:"block_type"`
+	StartDate string `json:"start_date"`
+	EndDate   string `json:"end_date"`
+	Reason    string `json:"reason"`
+}
+
+type CreateRoomBlockResponse struct {
+	BlockID             int    `json:"block_id"`
+	ConflictingBookings []int  `json:"conflicting_bookings,omitempty"`
+	Warning             string `json:"warning,omitempty"`
+	Message             string `json:"message"`
+}
+
+type UpdateRoomStatusRequest struct {
+	Status string `json:"status"`
+}
+
+// UpdateHotelRequest is used by both PUT and PATCH. PATCH only changes the
+// fields that are present; PUT replaces the hotel and resets missing
+// policy settings to their defaults.
+type UpdateHotelRequest struct {
+	Name        *string `json:"name"`
+	Address     *string `json:"address"`
+	City        *string `json:"city"`
+	Country     *string `json:"country"`
+	PhoneNumber *string `json:"phone_number"`
+	Email       *string `json:"email"`
+
+	HoldTTLMinutes             *int     `json:"hold_ttl_minutes"`
+	FreeCancellationDays       *int     `json:"free_cancellation_days"`
+	CancellationPenaltyPercent *float64 `json:"cancellation_penalty_percent"`
+}
+
+// UpdateRoomRequest is used by both PUT and PATCH, like UpdateHotelRequest.
+type UpdateRoomRequest struct {
+	RoomNumber       *string                 `json:"room_number"`
+	RoomType         *string                 `json:"room_type"`
+	PricePerNight    *float64                `json:"price_per_night"`
+	Description      *string                 `json:"description"`
+	Status           *string                 `json:"status"`
+	MaxAdults        *int                    `json:"max_adults"`
+	MaxChildren      *int                    `json:"max_children"`
+	BedConfiguration *model.BedConfiguration `json:"bed_configuration"`
+}
+
+// UserDataExport is a guest's share of the personal data export.
+type UserDataExport struct {
+	Bookings       []model.Booking       `json:"bookings"`
+	Reservations   []model.Reservation   `json:"reservations"`
+	BookingChanges []model.BookingChange `json:"booking_changes"`
+}
+
+// PageResponse wraps one page of a list endpoint. NextCursor is null on
+// the last page. Facets, where a list offers them, count the amenities
+// across every matching row rather than just this page.
+type PageResponse struct {
+	Data       interface{}    `json:"data"`
+	NextCursor *string        `json:"next_cursor"`
+	Facets     []AmenityFacet `json:"facets,omitempty"`
+}
+
+type AmenityFacet struct {
+	Code  string `json:"code"`
+	Name  string `json:"name"`
+	Count int    `json:"count"`
+}
+
+type AmenityRequest struct {
+	Code  string `json:"code"`
+	Name  string `json:"name"`
+	Scope string `json:"scope"`
+}
+
+type UpdateAmenitiesRequest struct {
+	Amenities []string `json:"amenities"`
+}
+
+type UpdateAmenitiesResponse struct {
+	Amenities []string `json:"amenities"`
+	Message   string   `json:"message"`
+}
@@ -0,0 +1,410 @@
+package handler
+
+import (
+	"booking-service/config"
+	"booking-service/dto"
+	model "booking-service/models"
+	"database/sql"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/labstack/echo/v4"
+	"github.com/lib/pq"
+)
+
+const amenityColumns = `id, code, name, scope, created_at, updated_at`
+
+var amenityCodePattern = regexp.MustCompile(`^[a-z0-9_]{1,50}$`)
+
+func scanAmenity(row rowScanner) (model.Amenity, error) {
+	var amenity model.Amenity
+	err := row.Scan(&amenity.AmenityID, &amenity.Code, &amenity.Name, &amenity.Scope, &amenity.CreatedAt, &amenity.UpdatedAt)
+	return amenity, err
+}
+
+// amenityLink describes the table linking hotels or rooms to the catalog.
+// ref is the ID column of the owner in the listing queries.
+type amenityLink struct {
+	table  string
+	column string
+	ref    string
+	scope  model.AmenityScope
+}
+
+var (
+	hotelAmenities = amenityLink{table: "hotel_amenities", column: "hotel_id", ref: "h.id", scope: model.HotelAmenity}
+	roomAmenities  = amenityLink{table: "room_amenities", column: "room_id", ref: "r.id", scope: model.RoomAmenity}
+)
+
+// parseAmenityCodes reads a comma separated list of amenity codes, dropping
+// blanks and duplicates.
+func parseAmenityCodes(value string) []string {
+	codes := []string{}
+	seen := map[string]bool{}
+	for _, code := range strings.Split(value, ",") {
+		code = strings.ToLower(strings.TrimSpace(code))
+		if code != "" && !seen[code] {
+			seen[code] = true
+			codes = append(codes, code)
+		}
+	}
+	return codes
+}
+
+// filter adds the amenities query parameter to a listing filter. Only
+// owners having every requested amenity match.
+func (l amenityLink) filter(c echo.Context, filter string, args []interface{}) (string, []interface{}) {
+	codes := parseAmenityCodes(c.QueryParam("amenities"))
+	if len(codes) == 0 {
+		return filter, args
+	}
+
+	args = append(args, pq.Array(codes), len(codes))
+	filter += fmt.Sprintf(` AND %s IN (
+		SELECT l.%s FROM %s l JOIN amenities a ON a.id = l.amenity_id
+		WHERE a.code = ANY($%d) GROUP BY l.%s HAVING COUNT(*) = $%d)`,
+		l.ref, l.column, l.table, len(args)-1, l.column, len(args))
+	return filter, args
+}
+
+// facets counts the amenities of the owners selected by idQuery, which
+// must select a single ID column using args.
+func (l amenityLink) facets(db dbExecutor, idQuery string, args []interface{}) ([]dto.AmenityFacet, error) {
+	query := fmt.Sprintf(`
+		SELECT a.code, a.name, COUNT(*)
+		FROM %s l
+		JOIN amenities a ON a.id = l.amenity_id
+		WHERE l.%s IN (%s)
+		GROUP BY a.id, a.code, a.name
+		ORDER BY a.name
+	`, l.table, l.column, idQuery)
+	rows, err := db.Query(query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	facets := []dto.AmenityFacet{}
+	for rows.Next() {
+		var facet dto.AmenityFacet
+		if err := rows.Scan(&facet.Code, &facet.Name, &facet.Count); err != nil {
+			return nil, err
+		}
+		facets = append(facets, facet)
+	}
+	return facets, rows.Err()
+}
+
+// load returns the amenity codes of the given owners keyed by owner ID.
+func (l amenityLink) load(db dbExecutor, ids []int64) (map[int][]string, error) {
+	query := fmt.Sprintf(`
+		SELECT l.%s, a.code
+		FROM %s l
+		JOIN amenities a ON a.id = l.amenity_id
+		WHERE l.%s = ANY($1)
+		ORDER BY l.%s, a.code
+	`, l.column, l.table, l.column, l.column)
+	rows, err := db.Query(query, pq.Array(ids))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	amenities := map[int][]string{}
+	for rows.Next() {
+		var id int
+		var code string
+		if err := rows.Scan(&id, &code); err != nil {
+			return nil, err
+		}
+		amenities[id] = append(amenities[id], code)
+	}
+	return amenities, rows.Err()
+}
+
+// replace sets the amenities of an owner to codes. Codes that are unknown
+// or outside the scope of the link are reported in userErr, which is
+// suitable for sending back to the client; err is a database error.
+func (l amenityLink) replace(tx *sql.Tx, ownerID int, codes []string) (userErr error, err error) {
+	rows, err := tx.Query(`SELECT id, code, scope FROM amenities WHERE code = ANY($1)`, pq.Array(codes))
+	if err != nil {
+		return nil, err
+	}
+
+	amenityIDs := []int64{}
+	found := map[string]bool{}
+	var wrongScope []string
+	for rows.Next() {
+		var id int64
+		var code string
+		var scope model.AmenityScope
+		if err := rows.Scan(&id, &code, &scope); err != nil {
+			rows.Close()
+			return nil, err
+		}
+		found[code] = true
+		if scope != l.scope && scope != model.AnyAmenity {
+			wrongScope = append(wrongScope, code)
+		}
+		amenityIDs = append(amenityIDs, id)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	var unknown []string
+	for _, code := range codes {
+		if !found[code] {
+			unknown = append(unknown, code)
+		}
+	}
+	if len(unknown) > 0 {
+		return fmt.Errorf("Unknown amenities: %s", strings.Join(unknown, ", ")), nil
+	}
+	if len(wrongScope) > 0 {
+		sort.Strings(wrongScope)
+		return fmt.Errorf("Amenities that cannot be given to a %s: %s", l.scope, strings.Join(wrongScope, ", ")), nil
+	}
+
+	if _, err := tx.Exec(fmt.Sprintf(`DELETE FROM %s WHERE %s = $1`, l.table, l.column), ownerID); err != nil {
+		return nil, err
+	}
+	insertQuery := fmt.Sprintf(`INSERT INTO %s (%s, amenity_id) SELECT $1, UNNEST($2::int[])`, l.table, l.column)
+	if _, err := tx.Exec(insertQuery, ownerID, pq.Array(amenityIDs)); err != nil {
+		return nil, err
+	}
+	return nil, nil
+}
+
+// validateAmenity normalizes an amenity request in place. The returned
+// error is suitable for sending back to the client.
+func validateAmenity(req *dto.AmenityRequest) error {
+	req.Code = strings.ToLower(strings.TrimSpace(req.Code))
+	req.Name = strings.TrimSpace(req.Name)
+	if req.Scope == "" {
+		req.Scope = string(model.AnyAmenity)
+	}
+
+	if !amenityCodePattern.MatchString(req.Code) {
+		return errors.New("code must be 1 to 50 lowercase letters, digits or underscores")
+	}
+	if req.Name == "" {
+		return errors.New("name is required")
+	}
+	switch model.AmenityScope(req.Scope) {
+	case model.HotelAmenity, model.RoomAmenity, model.AnyAmenity:
+	default:
+		return errors.New("scope must be one of 'hotel', 'room' or 'both'")
+	}
+	return nil
+}
+
+// ListAmenities returns the amenity catalog. scope limits it to the
+// amenities that can be given to hotels or to rooms.
+func ListAmenities(c echo.Context) error {
+	query := `SELECT ` + amenityColumns + ` FROM amenities`
+	args := []interface{}{}
+
+	if scope := c.QueryParam("scope"); scope != "" {
+		if scope != string(model.HotelAmenity) && scope != string(model.RoomAmenity) {
+			return c.JSON(http.StatusBadRequest, dto.ErrorResponse{Message: "scope must be either 'hotel' or 'room'"})
+		}
+		args = append(args, scope, model.AnyAmenity)
+		query += ` WHERE scope IN ($1, $2)`
+	}
+	query += ` ORDER BY name, id`
+
+	rows, err := config.DB.Query(query, args...)
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, dto.ErrorResponse{Message: "Failed to retrieve amenities"})
+	}
+	defer rows.Close()
+
+	amenities := []model.Amenity{}
+	for rows.Next() {
+		amenity, err := scanAmenity(rows)
+		if err != nil {
+			return c.JSON(http.StatusInternalServerError, dto.ErrorResponse{Message: "Failed to scan amenity data"})
+		}
+		amenities = append(amenities, amenity)
+	}
+
+	if err := rows.Err(); err != nil {
+		return c.JSON(http.StatusInternalServerError, dto.ErrorResponse{Message: "Error occurred during amenities retrieval"})
+	}
+
+	return c.JSON(http.StatusOK, amenities)
+}
+
+func CreateAmenity(c echo.Context) error {
+	if !isGlobalStaff(c) {
+		return c.JSON(http.StatusForbidden, dto.ErrorResponse{Message: "Only administrators can manage amenities"})
+	}
+
+	var req dto.AmenityRequest
+
+	if err := c.Bind(&req); err != nil {
+		return c.JSON(http.StatusBadRequest, dto.ErrorResponse{Message: "Invalid request"})
+	}
+
+	if err := validateAmenity(&req); err != nil {
+		return c.JSON(http.StatusBadRequest, dto.ErrorResponse{Message: err.Error()})
+	}
+
+	var existingID int
+	err := config.DB.QueryRow(`SELECT id FROM amenities WHERE code = $1`, req.Code).Scan(&existingID)
+	if err == nil {
+		return c.JSON(http.StatusConflict, dto.ErrorResponse{Message: "code already exists"})
+	} else if err != sql.ErrNoRows {
+		return c.JSON(http.StatusInternalServerError, dto.ErrorResponse{Message: "Failed to check amenity code"})
+	}
+
+	query := `
+		INSERT INTO amenities (code, name, scope, created_at, updated_at)
+		VALUES ($1, $2, $3, NOW(), NOW())
+		RETURNING ` + amenityColumns
+	amenity, err := scanAmenity(config.DB.QueryRow(query, req.Code, req.Name, req.Scope))
+	if err != nil {
+		log.Println("Error creating amenity:", err)
+		return c.JSON(http.StatusInternalServerError, dto.ErrorResponse{Message: "Failed to create amenity"})
+	}
+
+	return c.JSON(http.StatusCreated, amenity)
+}
+
+// UpdateAmenity renames an amenity or changes its scope. Narrowing the
+// scope is refused while hotels or rooms it no longer applies to use it.
+func UpdateAmenity(c echo.Context) error {
+	if !isGlobalStaff(c) {
+		return c.JSON(http.StatusForbidden, dto.ErrorResponse{Message: "Only administrators can manage amenities"})
+	}
+
+	var req dto.AmenityRequest
+
+	if err := c.Bind(&req); err != nil {
+		return c.JSON(http.StatusBadRequest, dto.ErrorResponse{Message: "Invalid request"})
+	}
+
+	if err := validateAmenity(&req); err != nil {
+		return c.JSON(http.StatusBadRequest, dto.ErrorResponse{Message: err.Error()})
+	}
+
+	var existingID int
+	err := config.DB.QueryRow(`SELECT id FROM amenities WHERE code = $1 AND id <> $2`, req.Code, c.Param("id")).Scan(&existingID)
+	if err == nil {
+		return c.JSON(http.StatusConflict, dto.ErrorResponse{Message: "code already exists"})
+	} else if err != sql.ErrNoRows {
+		return c.JSON(http.StatusInternalServerError, dto.ErrorResponse{Message: "Failed to check amenity code"})
+	}
+
+	// Rooms cannot use an amenity limited to hotels and the other way round.
+	var inUse bool
+	inUseQuery := `
+		SELECT ($2 = 'room' AND EXISTS (SELECT 1 FROM hotel_amenities WHERE amenity_id = $1))
+			OR ($2 = 'hotel' AND EXISTS (SELECT 1 FROM room_amenities WHERE amenity_id = $1))
+	`
+	if err := config.DB.QueryRow(inUseQuery, c.Param("id"), req.Scope).Scan(&inUse); err != nil {
+		return c.JSON(http.StatusInternalServerError, dto.ErrorResponse{Message: "Failed to check amenity usage"})
+	}
+	if inUse {
+		return c.JSON(http.StatusConflict, dto.ErrorResponse{Message: "The amenity is still in use outside the new scope"})
+	}
+
+	query := `
+		UPDATE amenities SET code = $1, name = $2, scope = $3, updated_at = NOW()
+		WHERE id = $4
+		RETURNING ` + amenityColumns
+	amenity, err := scanAmenity(config.DB.QueryRow(query, req.Code, req.Name, req.Scope, c.Param("id")))
+	if err == sql.ErrNoRows {
+		return c.JSON(http.StatusNotFound, dto.ErrorResponse{Message: "Amenity not found"})
+	} else if err != nil {
+		log.Println("Error updating amenity:", err)
+		return c.JSON(http.StatusInternalServerError, dto.ErrorResponse{Message: "Failed to update amenity"})
+	}
+
+	return c.JSON(http.StatusOK, amenity)
+}
+
+// DeleteAmenity removes an amenity from the catalog and from every hotel
+// and room that had it.
+func DeleteAmenity(c echo.Context) error {
+	if !isGlobalStaff(c) {
+		return c.JSON(http.StatusForbidden, dto.ErrorResponse{Message: "Only administrators can manage amenities"})
+	}
+
+	res, err := config.DB.Exec(`DELETE FROM amenities WHERE id = $1`, c.Param("id"))
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, dto.ErrorResponse{Message: "Failed to delete amenity"})
+	}
+
+	if rowsAffected, err := res.RowsAffected(); err != nil || rowsAffected == 0 {
+		return c.JSON(http.StatusNotFound, dto.ErrorResponse{Message: "Amenity not found"})
+	}
+
+	return c.JSON(http.StatusOK, dto.SuccessResponse{Message: "Amenity deleted successfully"})
+}
+
+// UpdateHotelAmenities replaces the amenities of a hotel.
+func UpdateHotelAmenities(c echo.Context) error {
+	return updateAmenities(c, hotelAmenities, `SELECT id, id FROM hotels WHERE id = $1 AND deleted_at IS NULL FOR UPDATE`, "Hotel not found")
+}
+
+// UpdateRoomAmenities replaces the amenities of a room.
+func UpdateRoomAmenities(c echo.Context) error {
+	return updateAmenities(c, roomAmenities, `SELECT id, hotel_id FROM rooms WHERE id = $1 AND deleted_at IS NULL FOR UPDATE`, "Room not found")
+}
+
+// updateAmenities replaces the amenities of the owner that ownerQuery
+// locks. ownerQuery must select the owner ID and its hotel ID.
+func updateAmenities(c echo.Context, link amenityLink, ownerQuery, notFound string) error {
+	var req dto.UpdateAmenitiesRequest
+
+	if err := c.Bind(&req); err != nil {
+		return c.JSON(http.StatusBadRequest, dto.ErrorResponse{Message: "Invalid request"})
+	}
+
+	if req.Amenities == nil {
+		return c.JSON(http.StatusBadRequest, dto.ErrorResponse{Message: "amenities is required"})
+	}
+	codes := parseAmenityCodes(strings.Join(req.Amenities, ","))
+
+	tx, err := config.DB.Begin()
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, dto.ErrorResponse{Message: "Failed to start transaction"})
+	}
+	defer tx.Rollback()
+
+	var ownerID, hotelID int
+	err = tx.QueryRow(ownerQuery, c.Param("id")).Scan(&ownerID, &hotelID)
+	if err == sql.ErrNoRows {
+		return c.JSON(http.StatusNotFound, dto.ErrorResponse{Message: notFound})
+	} else if err != nil {
+		return c.JSON(http.StatusInternalServerError, dto.ErrorResponse{Message: "Failed to check hotel access"})
+	}
+
+	if !canManageHotel(c, hotelID) {
+		return forbiddenHotel(c)
+	}
+
+	userErr, err := link.replace(tx, ownerID, codes)
+	if err != nil {
+		log.Println("Error updating amenities:", err)
+		return c.JSON(http.StatusInternalServerError, dto.ErrorResponse{Message: "Failed to update amenities"})
+	}
+	if userErr != nil {
+		return c.JSON(http.StatusBadRequest, dto.ErrorResponse{Message: userErr.Error()})
+	}
+
+	if err := tx.Commit(); err != nil {
+		return c.JSON(http.StatusInternalServerError, dto.ErrorResponse{Message: "Failed to update amenities"})
+	}
+
+	sort.Strings(codes)
+	return c.JSON(http.StatusOK, dto.UpdateAmenitiesResponse{Amenities: codes, Message: "Amenities updated successfully"})
+}
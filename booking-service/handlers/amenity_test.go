@@ -0,0 +1,55 @@
+package handler
+
+import (
+	"booking-service/dto"
+	"reflect"
+	"testing"
+)
+
+func TestParseAmenityCodes(t *testing.T) {
+	tests := []struct {
+		value string
+		want  []string
+	}{
+		{"", []string{}},
+		{"wifi", []string{"wifi"}},
+		{" WiFi , pool,,wifi ", []string{"wifi", "pool"}},
+	}
+
+	for _, tt := range tests {
+		if got := parseAmenityCodes(tt.value); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("parseAmenityCodes(%q) = %v, want %v", tt.value, got, tt.want)
+		}
+	}
+}
+
+func TestValidateAmenity(t *testing.T) {
+	tests := []struct {
+		name    string
+		req     dto.AmenityRequest
+		wantErr bool
+	}{
+		{name: "default scope", req: dto.AmenityRequest{Code: " Sea_View ", Name: "Sea view"}},
+		{name: "room scope", req: dto.AmenityRequest{Code: "minibar", Name: "Minibar", Scope: "room"}},
+		{name: "invalid code", req: dto.AmenityRequest{Code: "sea view", Name: "Sea view"}, wantErr: true},
+		{name: "missing name", req: dto.AmenityRequest{Code: "spa", Name: " "}, wantErr: true},
+		{name: "unknown scope", req: dto.AmenityRequest{Code: "spa", Name: "Spa", Scope: "floor"}, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := validateAmenity(&tt.req)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("validateAmenity() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+
+	req := dto.AmenityRequest{Code: " Sea_View ", Name: " Sea view "}
+	if err := validateAmenity(&req); err != nil {
+		t.Fatal(err)
+	}
+	if req.Code != "sea_view" || req.Name != "Sea view" || req.Scope != "both" {
+		t.Errorf("validateAmenity() normalized to %+v", req)
+	}
+}
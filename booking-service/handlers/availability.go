@@ -0,0 +1,125 @@
+package handler
+
+import (
+	model "booking-service/models"
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+)
+
+const dateLayout = "2006-01-02"
+
+// maxStayNights is the longest stay that can be searched, quoted or booked.
+const maxStayNights = 30
+
+// dbExecutor is satisfied by both *sql.DB and *sql.Tx, so helpers can run
+// inside a transaction when the caller holds one.
+type dbExecutor interface {
+	Exec(query string, args ...interface{}) (sql.Result, error)
+	Query(query string, args ...interface{}) (*sql.Rows, error)
+	QueryRow(query string, args ...interface{}) *sql.Row
+}
+
+// parseStay parses the checkin and checkout dates of a stay, which must not
+// start in the past or last more than maxStayNights. The returned error is
+// suitable for sending back to the client.
+func parseStay(checkin, checkout string) (time.Time, time.Time, error) {
+	checkinDate, err := time.Parse(dateLayout, checkin)
+	if err != nil {
+		return time.Time{}, time.Time{}, errors.New("Invalid checkin_date format")
+	}
+
+	checkoutDate, err := time.Parse(dateLayout, checkout)
+	if err != nil {
+		return time.Time{}, time.Time{}, errors.New("Invalid checkout_date format")
+	}
+
+	if !checkoutDate.After(checkinDate) {
+		return time.Time{}, time.Time{}, errors.New("checkout_date must be after checkin_date")
+	}
+
+	now := time.Now().UTC()
+	today := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, time.UTC)
+	if checkinDate.Before(today) {
+		return time.Time{}, time.Time{}, errors.New("checkin_date must not be in the past")
+	}
+
+	if stayNights(checkinDate, checkoutDate) > maxStayNights {
+		return time.Time{}, time.Time{}, fmt.Errorf("a stay must not be longer than %d nights", maxStayNights)
+	}
+
+	return checkinDate, checkoutDate, nil
+}
+
+// roomBookedClause returns an SQL condition that is true when the room
+// referenced by roomRef carries a booking that is neither canceled nor
+// given up for a refund and intersects the stay bound to the checkinArg
+// and checkoutArg placeholders. Stays are half-open intervals, so a
+// checkout on the same day as another checkin is allowed. Pending bookings
+// whose hold has expired no longer block the room, even before the
+// sweeper gets to cancel them. A booking change awaiting payment of its
+// extra charge holds its new room and dates the same way until it
+// expires. When excludeArg is not zero, the booking bound to that
+// placeholder and its changes are ignored.
+func roomBookedClause(roomRef string, checkinArg, checkoutArg, excludeArg int) string {
+	exclude, excludeChange := "", ""
+	if excludeArg != 0 {
+		exclude = fmt.Sprintf("\n\t\t\tAND b.id <> $%d", excludeArg)
+		excludeChange = fmt.Sprintf("\n\t\t\tAND bc.booking_id <> $%d", excludeArg)
+	}
+
+	return fmt.Sprintf(`(EXISTS (
+			SELECT 1 FROM bookings b
+			WHERE b.room_id = %s
+			AND b.status NOT IN ('%s', '%s')
+			AND NOT (b.status = '%s' AND b.expires_at <= NOW())
+			AND b.checkin_date < $%d
+			AND b.checkout_date > $%d%s
+		) OR EXISTS (
+			SELECT 1 FROM booking_changes bc
+			WHERE bc.new_room_id = %s
+			AND bc.adjustment_status = '%s'
+			AND bc.expires_at > NOW()
+			AND bc.new_checkin_date < $%d
+			AND bc.new_checkout_date > $%d%s
+		))`, roomRef, model.Canceled, model.Refund, model.Pending, checkoutArg, checkinArg, exclude,
+		roomRef, awaitingPayment, checkoutArg, checkinArg, excludeChange)
+}
+
+// roomBlockedClause returns an SQL condition that is true when the room
+// referenced by roomRef has a maintenance or out-of-order block on any
+// night of the stay bound to the checkinArg and checkoutArg placeholders.
+// Block dates are inclusive.
+func roomBlockedClause(roomRef string, checkinArg, checkoutArg int) string {
+	return fmt.Sprintf(`EXISTS (
+			SELECT 1 FROM room_blocks rb
+			WHERE rb.room_id = %s
+			AND rb.start_date < $%d
+			AND rb.end_date >= $%d
+		)`, roomRef, checkoutArg, checkinArg)
+}
+
+// isRoomUnavailable reports whether any night of the stay is blocked or
+// already booked by a booking other than excludeBookingID. Pass zero to
+// take every booking into account.
+func isRoomUnavailable(db dbExecutor, roomID int, checkin, checkout time.Time, excludeBookingID int) (bool, error) {
+	query := `SELECT ` + roomBookedClause("$1", 2, 3, 4) + ` OR ` + roomBlockedClause("$1", 2, 3)
+
+	var exists bool
+	err := db.QueryRow(query, roomID, checkin, checkout, excludeBookingID).Scan(&exists)
+	return exists, err
+}
+
+// stayNights returns the number of nights between checkin and checkout.
+func stayNights(checkin, checkout time.Time) int {
+	return int(checkout.Sub(checkin).Hours() / 24)
+}
+
+func isValidRoomStatus(status model.RoomStatus) bool {
+	switch status {
+	case model.Available, model.Occupied, model.Maintenance:
+		return true
+	}
+	return false
+}
@@ -0,0 +1,36 @@
+package handler
+
+import (
+	"testing"
+	"time"
+)
+
+func TestParseStay(t *testing.T) {
+	today := time.Now().UTC()
+	day := func(offset int) string {
+		return today.AddDate(0, 0, offset).Format(dateLayout)
+	}
+
+	tests := []struct {
+		name              string
+		checkin, checkout string
+		wantErr           bool
+	}{
+		{name: "tonight", checkin: day(0), checkout: day(1)},
+		{name: "longest stay", checkin: day(10), checkout: day(10 + maxStayNights)},
+		{name: "invalid date", checkin: "2030-02-30", checkout: day(1), wantErr: true},
+		{name: "checkout before checkin", checkin: day(5), checkout: day(5), wantErr: true},
+		{name: "in the past", checkin: day(-1), checkout: day(1), wantErr: true},
+		{name: "too long", checkin: day(1), checkout: day(2 + maxStayNights), wantErr: true},
+		{name: "far future checkout", checkin: day(1), checkout: "9999-12-31", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, _, err := parseStay(tt.checkin, tt.checkout)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("parseStay(%q, %q) error = %v, wantErr %v", tt.checkin, tt.checkout, err, tt.wantErr)
+			}
+		})
+	}
+}
@@ -0,0 +1,139 @@
+package handler
+
+import (
+	"booking-service/config"
+	"booking-service/dto"
+	model "booking-service/models"
+	"database/sql"
+	"log"
+	"net/http"
+	"time"
+
+	"github.com/labstack/echo/v4"
+)
+
+// cancellationFee applies the hotel's cancellation policy to a paid booking
+// canceled on the given day.
+func cancellationFee(totalPrice float64, checkin, today time.Time, freeCancellationDays int, penaltyPercent float64) float64 {
+	daysBeforeCheckin := int(checkin.Sub(today).Hours() / 24)
+	if daysBeforeCheckin >= freeCancellationDays {
+		return 0
+	}
+	return roundMoney(totalPrice * penaltyPercent / 100)
+}
+
+func CancelBooking(c echo.Context) error {
+	bookingID := c.Param("booking_id")
+
+	var req dto.CancelBookingRequest
+
+	if err := c.Bind(&req); err != nil {
+		return c.JSON(http.StatusBadRequest, dto.ErrorResponse{Message: "Invalid request"})
+	}
+
+	if req.UserID == nil {
+		return c.JSON(http.StatusBadRequest, dto.ErrorResponse{Message: "User ID is required"})
+	}
+
+	tx, err := config.DB.Begin()
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, dto.ErrorResponse{Message: "Failed to start transaction"})
+	}
+	defer tx.Rollback()
+
+	var booking model.Booking
+	var checkinDate time.Time
+	var freeCancellationDays int
+	var penaltyPercent float64
+	query := `
+		SELECT b.id, b.user_id, b.status, b.checkin_status, b.checkin_date, b.total_price, b.reservation_id,
+			h.free_cancellation_days, h.cancellation_penalty_percent
+		FROM bookings b
+		JOIN rooms r ON r.id = b.room_id
+		JOIN hotels h ON h.id = r.hotel_id
+		WHERE b.id = $1
+		FOR UPDATE OF b
+	`
+	err = tx.QueryRow(query, bookingID).Scan(
+		&booking.BookingID,
+		&booking.UserID,
+		&booking.Status,
+		&booking.CheckinStatus,
+		&checkinDate,
+		&booking.TotalPrice,
+		&booking.ReservationID,
+		&freeCancellationDays,
+		&penaltyPercent,
+	)
+	if err == sql.ErrNoRows || (err == nil && booking.UserID != *req.UserID) {
+		return c.JSON(http.StatusNotFound, dto.ErrorResponse{Message: "Booking not found"})
+	} else if err != nil {
+		return c.JSON(http.StatusInternalServerError, dto.ErrorResponse{Message: "Failed to retrieve booking"})
+	}
+
+	if booking.Status != model.Pending && booking.Status != model.Confirmed {
+		return c.JSON(http.StatusBadRequest, dto.ErrorResponse{Message: "Only pending or confirmed bookings can be canceled"})
+	}
+
+	if booking.CheckinStatus != model.NotCheckedIn {
+		return c.JSON(http.StatusBadRequest, dto.ErrorResponse{Message: "Booking has already been checked in"})
+	}
+
+	// Pending bookings have not been paid yet, so there is nothing to keep
+	// or to refund.
+	fee, refundable := 0.0, 0.0
+	if booking.Status == model.Confirmed {
+		now := time.Now().UTC()
+		today := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, time.UTC)
+		fee = cancellationFee(booking.TotalPrice, checkinDate, today, freeCancellationDays, penaltyPercent)
+		refundable = roundMoney(booking.TotalPrice - fee)
+	}
+
+	updateQuery := `
+		UPDATE bookings
+		SET status = $1, cancellation_fee = $2, canceled_at = NOW(), expires_at = NULL, updated_at = NOW()
+		WHERE id = $3
+	`
+	if _, err := tx.Exec(updateQuery, model.Canceled, fee, booking.BookingID); err != nil {
+		return c.JSON(http.StatusInternalServerError, dto.ErrorResponse{Message: "Failed to cancel booking"})
+	}
+
+	if booking.ReservationID != nil {
+		if err := refreshReservation(tx, *booking.ReservationID); err != nil {
+			return c.JSON(http.StatusInternalServerError, dto.ErrorResponse{Message: "Failed to update reservation"})
+		}
+	}
+
+	// A change still awaiting payment must not keep holding its new room.
+	// Its charge is expired by payment-service along with the booking's
+	// other pending payments.
+	expireChangesQuery := `UPDATE booking_changes SET adjustment_status = 'expired' WHERE booking_id = $1 AND adjustment_status = $2`
+	if _, err := tx.Exec(expireChangesQuery, booking.BookingID, awaitingPayment); err != nil {
+		return c.JSON(http.StatusInternalServerError, dto.ErrorResponse{Message: "Failed to cancel booking"})
+	}
+
+	if err := tx.Commit(); err != nil {
+		return c.JSON(http.StatusInternalServerError, dto.ErrorResponse{Message: "Failed to cancel booking"})
+	}
+
+	response := dto.CancelBookingResponse{
+		BookingID:        booking.BookingID,
+		CancellationFee:  fee,
+		RefundableAmount: refundable,
+		Message:          "Booking canceled successfully",
+	}
+
+	// payment-service is told even when nothing is refundable, so that it
+	// expires the payments still open for the booking.
+	err = requestCancellationRefund(booking.BookingID, booking.UserID, booking.ReservationID, refundable)
+	if err != nil {
+		log.Printf("Failed to notify payment service about canceled booking %d: %v\n", booking.BookingID, err)
+		if refundable > 0 {
+			response.Message = "Booking canceled, but the refund request could not be sent to the payment service"
+		}
+	} else {
+		response.RefundRequested = refundable > 0
+	}
+
+	return c.JSON(http.StatusOK, response)
+}
@@ -0,0 +1,173 @@
+package handler
+
+import (
+	"booking-service/config"
+	"database/sql"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/labstack/echo/v4"
+)
+
+// openTestDB connects to the database in TEST_DATABASE_URL, applies the
+// migrations to a fresh schema and points config.DB at it. Tests that need
+// it are skipped when the variable is not set.
+func openTestDB(t *testing.T) *sql.DB {
+	t.Helper()
+
+	dsn := os.Getenv("TEST_DATABASE_URL")
+	if dsn == "" {
+		t.Skip("TEST_DATABASE_URL is not set")
+	}
+
+	admin, err := sql.Open("postgres", dsn)
+	if err != nil {
+		t.Fatalf("open database: %v", err)
+	}
+	t.Cleanup(func() { admin.Close() })
+
+	schema := fmt.Sprintf("booking_test_%d", time.Now().UnixNano())
+	if _, err := admin.Exec("CREATE SCHEMA " + schema); err != nil {
+		t.Fatalf("create schema: %v", err)
+	}
+	t.Cleanup(func() { admin.Exec("DROP SCHEMA " + schema + " CASCADE") })
+
+	separator := "?"
+	if strings.Contains(dsn, "?") {
+		separator = "&"
+	}
+	db, err := sql.Open("postgres", dsn+separator+"search_path="+schema)
+	if err != nil {
+		t.Fatalf("open schema: %v", err)
+	}
+	t.Cleanup(func() { db.Close() })
+
+	migrations, err := filepath.Glob("../migrations/*.up.sql")
+	if err != nil {
+		t.Fatalf("list migrations: %v", err)
+	}
+	sort.Strings(migrations)
+
+	for _, migration := range migrations {
+		statements, err := os.ReadFile(migration)
+		if err != nil {
+			t.Fatalf("read %s: %v", migration, err)
+		}
+		if _, err := db.Exec(string(statements)); err != nil {
+			t.Fatalf("apply %s: %v", migration, err)
+		}
+	}
+
+	config.DB = db
+	return db
+}
+
+func createTestRoom(t *testing.T, db *sql.DB) int {
+	t.Helper()
+
+	var hotelID, roomID int
+	err := db.QueryRow(`INSERT INTO hotels (name, address, city, country) VALUES ('Test Hotel', 'Street 1', 'Jakarta', 'Indonesia') RETURNING id`).Scan(&hotelID)
+	if err != nil {
+		t.Fatalf("create hotel: %v", err)
+	}
+
+	err = db.QueryRow(`INSERT INTO rooms (hotel_id, room_number, room_type, price_per_night) VALUES ($1, '101', 'double', 100) RETURNING id`, hotelID).Scan(&roomID)
+	if err != nil {
+		t.Fatalf("create room: %v", err)
+	}
+
+	return roomID
+}
+
+// bookConcurrently fires one CreateBooking request per stay at the same time
+// and returns the response status codes.
+func bookConcurrently(t *testing.T, roomID int, stays [][2]string) []int {
+	t.Helper()
+
+	e := echo.New()
+	codes := make([]int, len(stays))
+	start := make(chan struct{})
+
+	var wg sync.WaitGroup
+	for i, stay := range stays {
+		wg.Add(1)
+		go func(i int, stay [2]string) {
+			defer wg.Done()
+
+			body := fmt.Sprintf(`{"user_id": %d, "room_id": %d, "checkin_date": %q, "checkout_date": %q}`, i+1, roomID, stay[0], stay[1])
+			req := httptest.NewRequest(http.MethodPost, "/booking", strings.NewReader(body))
+			req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+			rec := httptest.NewRecorder()
+
+			<-start
+			if err := CreateBooking(e.NewContext(req, rec)); err != nil {
+				t.Errorf("CreateBooking returned error: %v", err)
+			}
+			codes[i] = rec.Code
+		}(i, stay)
+	}
+
+	close(start)
+	wg.Wait()
+	return codes
+}
+
+func TestCreateBookingParallelOverlappingStays(t *testing.T) {
+	db := openTestDB(t)
+	roomID := createTestRoom(t, db)
+
+	const attempts = 20
+	stays := make([][2]string, attempts)
+	for i := range stays {
+		stays[i] = [2]string{"2030-03-01", "2030-03-04"}
+	}
+
+	created := 0
+	for _, code := range bookConcurrently(t, roomID, stays) {
+		switch code {
+		case http.StatusCreated:
+			created++
+		case http.StatusBadRequest:
+		default:
+			t.Errorf("unexpected status %d", code)
+		}
+	}
+
+	if created != 1 {
+		t.Fatalf("expected exactly one booking to succeed, got %d", created)
+	}
+
+	var count int
+	if err := db.QueryRow(`SELECT COUNT(*) FROM bookings WHERE room_id = $1`, roomID).Scan(&count); err != nil {
+		t.Fatalf("count bookings: %v", err)
+	}
+	if count != 1 {
+		t.Fatalf("expected one booking row, got %d", count)
+	}
+}
+
+func TestCreateBookingParallelAdjacentStays(t *testing.T) {
+	db := openTestDB(t)
+	roomID := createTestRoom(t, db)
+
+	stays := [][2]string{
+		{"2030-03-01", "2030-03-03"},
+		{"2030-03-03", "2030-03-05"},
+		{"2030-03-05", "2030-03-06"},
+		{"2030-03-06", "2030-03-09"},
+	}
+
+	for i, code := range bookConcurrently(t, roomID, stays) {
+		if code != http.StatusCreated {
+			t.Errorf("stay %v: expected status %d, got %d", stays[i], http.StatusCreated, code)
+		}
+	}
+}
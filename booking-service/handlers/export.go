@@ -0,0 +1,131 @@
+package handler
+
+import (
+	"booking-service/config"
+	"booking-service/dto"
+	model "booking-service/models"
+	"net/http"
+
+	"github.com/labstack/echo/v4"
+)
+
+// ExportUserData returns everything booking-service stores about a guest:
+// their bookings with the guests registered on them, the reservations
+// grouping them and every change made to them. It backs the gateway's
+// personal data export.
+func ExportUserData(c echo.Context) error {
+	userID := c.Param("user_id")
+
+	query := `
+		SELECT b.id, b.user_id, b.room_id, r.hotel_id, b.checkin_date, b.checkout_date, b.total_price, b.status,
+			b.checkin_status, b.expires_at, b.cancellation_fee, b.canceled_at, b.reservation_id, b.adults, b.children,
+			b.created_at, b.updated_at
+		FROM bookings b
+		JOIN rooms r ON r.id = b.room_id
+		WHERE b.user_id = $1 ORDER BY b.id
+	`
+	rows, err := config.DB.Query(query, userID)
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, dto.ErrorResponse{Message: "Failed to retrieve bookings"})
+	}
+	defer rows.Close()
+
+	export := dto.UserDataExport{
+		Bookings:       []model.Booking{},
+		Reservations:   []model.Reservation{},
+		BookingChanges: []model.BookingChange{},
+	}
+	for rows.Next() {
+		var booking model.Booking
+		if err := rows.Scan(
+			&booking.BookingID,
+			&booking.UserID,
+			&booking.RoomID,
+			&booking.HotelID,
+			&booking.CheckinDate,
+			&booking.CheckoutDate,
+			&booking.TotalPrice,
+			&booking.Status,
+			&booking.CheckinStatus,
+			&booking.ExpiresAt,
+			&booking.CancellationFee,
+			&booking.CanceledAt,
+			&booking.ReservationID,
+			&booking.Adults,
+			&booking.Children,
+			&booking.CreatedAt,
+			&booking.UpdatedAt,
+		); err != nil {
+			return c.JSON(http.StatusInternalServerError, dto.ErrorResponse{Message: "Failed to scan booking data"})
+		}
+		export.Bookings = append(export.Bookings, booking)
+	}
+	if err := rows.Err(); err != nil {
+		return c.JSON(http.StatusInternalServerError, dto.ErrorResponse{Message: "Error occurred during bookings retrieval"})
+	}
+
+	bookingIDs := make([]int64, len(export.Bookings))
+	for i, booking := range export.Bookings {
+		bookingIDs[i] = int64(booking.BookingID)
+	}
+	guests, err := loadGuests(config.DB, bookingIDs)
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, dto.ErrorResponse{Message: "Failed to retrieve guests"})
+	}
+	for i := range export.Bookings {
+		export.Bookings[i].Guests = guests[export.Bookings[i].BookingID]
+	}
+
+	query = `SELECT id, user_id, total_price, status, expires_at, created_at, updated_at FROM reservations WHERE user_id = $1 ORDER BY id`
+	rows, err = config.DB.Query(query, userID)
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, dto.ErrorResponse{Message: "Failed to retrieve reservations"})
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var reservation model.Reservation
+		if err := rows.Scan(
+			&reservation.ReservationID,
+			&reservation.UserID,
+			&reservation.TotalPrice,
+			&reservation.Status,
+			&reservation.ExpiresAt,
+			&reservation.CreatedAt,
+			&reservation.UpdatedAt,
+		); err != nil {
+			return c.JSON(http.StatusInternalServerError, dto.ErrorResponse{Message: "Failed to scan reservation data"})
+		}
+
+		reservation.Bookings = []model.Booking{}
+		for _, booking := range export.Bookings {
+			if booking.ReservationID != nil && *booking.ReservationID == reservation.ReservationID {
+				reservation.Bookings = append(reservation.Bookings, booking)
+			}
+		}
+		export.Reservations = append(export.Reservations, reservation)
+	}
+	if err := rows.Err(); err != nil {
+		return c.JSON(http.StatusInternalServerError, dto.ErrorResponse{Message: "Error occurred during reservations retrieval"})
+	}
+
+	query = `SELECT ` + bookingChangeColumns + ` FROM booking_changes WHERE user_id = $1 ORDER BY id`
+	rows, err = config.DB.Query(query, userID)
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, dto.ErrorResponse{Message: "Failed to retrieve booking changes"})
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		change, err := scanBookingChange(rows)
+		if err != nil {
+			return c.JSON(http.StatusInternalServerError, dto.ErrorResponse{Message: "Failed to scan booking change data"})
+		}
+		export.BookingChanges = append(export.BookingChanges, change)
+	}
+	if err := rows.Err(); err != nil {
+		return c.JSON(http.StatusInternalServerError, dto.ErrorResponse{Message: "Error occurred during booking changes retrieval"})
+	}
+
+	return c.JSON(http.StatusOK, export)
+}
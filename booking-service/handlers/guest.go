@@ -0,0 +1,459 @@
+package handler
+
+import (
+	"booking-service/config"
+	"booking-service/dto"
+	model "booking-service/models"
+	"database/sql"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"net/mail"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/labstack/echo/v4"
+	"github.com/lib/pq"
+)
+
+const guestColumns = `id, booking_id, is_primary, first_name, last_name, email, phone, nationality,
+	to_char(date_of_birth, 'YYYY-MM-DD'), document_type, document_number, created_at, updated_at`
+
+func scanGuest(row rowScanner) (model.Guest, error) {
+	var guest model.Guest
+	err := row.Scan(
+		&guest.GuestID,
+		&guest.BookingID,
+		&guest.IsPrimary,
+		&guest.FirstName,
+		&guest.LastName,
+		&guest.Email,
+		&guest.Phone,
+		&guest.Nationality,
+		&guest.DateOfBirth,
+		&guest.DocumentType,
+		&guest.DocumentNumber,
+		&guest.CreatedAt,
+		&guest.UpdatedAt,
+	)
+	return guest, err
+}
+
+// validateGuest normalizes a guest in place. The returned error is suitable
+// for sending back to the client.
+func validateGuest(req *dto.GuestRequest) error {
+	req.FirstName = strings.TrimSpace(req.FirstName)
+	req.LastName = strings.TrimSpace(req.LastName)
+	req.Email = strings.TrimSpace(req.Email)
+	req.Phone = strings.TrimSpace(req.Phone)
+	req.Nationality = strings.ToUpper(strings.TrimSpace(req.Nationality))
+	req.DocumentNumber = strings.TrimSpace(req.DocumentNumber)
+
+	if req.FirstName == "" || req.LastName == "" {
+		return errors.New("first_name and last_name are required")
+	}
+
+	// The limits match the columns of the guests table.
+	if utf8.RuneCountInString(req.FirstName) > 100 || utf8.RuneCountInString(req.LastName) > 100 {
+		return errors.New("first_name and last_name must not be longer than 100 characters")
+	}
+	if utf8.RuneCountInString(req.Email) > 255 {
+		return errors.New("email must not be longer than 255 characters")
+	}
+	if utf8.RuneCountInString(req.Phone) > 50 {
+		return errors.New("phone must not be longer than 50 characters")
+	}
+	if utf8.RuneCountInString(req.DocumentNumber) > 50 {
+		return errors.New("document_number must not be longer than 50 characters")
+	}
+
+	if req.Email != "" {
+		if _, err := mail.ParseAddress(req.Email); err != nil {
+			return errors.New("Invalid email")
+		}
+	}
+
+	if req.Nationality != "" {
+		if len(req.Nationality) != 2 || strings.Trim(req.Nationality, "ABCDEFGHIJKLMNOPQRSTUVWXYZ") != "" {
+			return errors.New("nationality must be a two-letter ISO 3166 country code")
+		}
+	}
+
+	if req.DateOfBirth != "" {
+		dateOfBirth, err := time.Parse(dateLayout, req.DateOfBirth)
+		if err != nil {
+			return errors.New("Invalid date_of_birth format")
+		}
+		if dateOfBirth.After(time.Now()) {
+			return errors.New("date_of_birth must not be in the future")
+		}
+	}
+
+	switch model.DocumentType(req.DocumentType) {
+	case "":
+		if req.DocumentNumber != "" {
+			return errors.New("document_type is required with document_number")
+		}
+	case model.Passport, model.NationalID, model.DrivingLicense:
+		if req.DocumentNumber == "" {
+			return errors.New("document_number is required with document_type")
+		}
+	default:
+		return errors.New("document_type must be one of 'passport', 'national_id' or 'driving_license'")
+	}
+
+	return nil
+}
+
+// validateParty checks the party size and guests given with a new booking.
+// Adults defaults to one; every guest has to be part of the party.
+func validateParty(adults *int, children int, guests []dto.GuestRequest) error {
+	if *adults == 0 {
+		*adults = 1
+	}
+	if *adults < 0 || children < 0 {
+		return errors.New("adults and children must not be negative")
+	}
+
+	if len(guests) > *adults+children {
+		return errors.New("There are more guests than adults and children")
+	}
+
+	primary := 0
+	for i := range guests {
+		if err := validateGuest(&guests[i]); err != nil {
+			return fmt.Errorf("Guest %d: %s", i+1, err.Error())
+		}
+		if guests[i].IsPrimary {
+			primary++
+		}
+	}
+	if primary > 1 {
+		return errors.New("Only one guest can be the primary guest")
+	}
+
+	return nil
+}
+
+// nullString stores an empty optional field as NULL.
+func nullString(value string) interface{} {
+	if value == "" {
+		return nil
+	}
+	return value
+}
+
+func insertGuest(db dbExecutor, bookingID int, req dto.GuestRequest) (int, error) {
+	query := `
+		INSERT INTO guests (booking_id, is_primary, first_name, last_name, email, phone, nationality,
+			date_of_birth, document_type, document_number, created_at, updated_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, NOW(), NOW())
+		RETURNING id
+	`
+	var guestID int
+	err := db.QueryRow(query, bookingID, req.IsPrimary, req.FirstName, req.LastName, nullString(req.Email), nullString(req.Phone),
+		nullString(req.Nationality), nullString(req.DateOfBirth), nullString(req.DocumentType), nullString(req.DocumentNumber)).Scan(&guestID)
+	return guestID, err
+}
+
+// insertGuests registers the guests given with a new booking. The first
+// guest is the primary guest unless another one is marked as such.
+func insertGuests(db dbExecutor, bookingID int, guests []dto.GuestRequest) error {
+	hasPrimary := false
+	for _, guest := range guests {
+		hasPrimary = hasPrimary || guest.IsPrimary
+	}
+
+	for i, guest := range guests {
+		if !hasPrimary && i == 0 {
+			guest.IsPrimary = true
+		}
+		if _, err := insertGuest(db, bookingID, guest); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// loadGuests returns the guests of the given bookings keyed by booking ID,
+// primary guest first.
+func loadGuests(db dbExecutor, bookingIDs []int64) (map[int][]model.Guest, error) {
+	query := `SELECT ` + guestColumns + ` FROM guests WHERE booking_id = ANY($1) ORDER BY booking_id, is_primary DESC, id`
+	rows, err := db.Query(query, pq.Array(bookingIDs))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	guests := map[int][]model.Guest{}
+	for rows.Next() {
+		guest, err := scanGuest(rows)
+		if err != nil {
+			return nil, err
+		}
+		guests[guest.BookingID] = append(guests[guest.BookingID], guest)
+	}
+	return guests, rows.Err()
+}
+
+type guestBooking struct {
+	bookingID     int
+	status        string
+	checkinStatus string
+	adults        int
+	children      int
+	maxAdults     int
+	maxChildren   int
+	guests        int
+}
+
+// lockGuestBooking locks a booking whose guests or party are about to
+// change and writes the error response when the caller may not change
+// them. Guests can be changed by staff of the hotel until check-in. The
+// caller should return the error unchanged when ok is false.
+func lockGuestBooking(c echo.Context, tx *sql.Tx, bookingID string) (booking guestBooking, ok bool, err error) {
+	var hotelID int
+	query := `
+		SELECT b.id, b.status, b.checkin_status, b.adults, b.children, r.max_adults, r.max_children, r.hotel_id
+		FROM bookings b
+		JOIN rooms r ON r.id = b.room_id
+		WHERE b.id = $1
+		FOR UPDATE OF b
+	`
+	err = tx.QueryRow(query, bookingID).Scan(&booking.bookingID, &booking.status, &booking.checkinStatus, &booking.adults, &booking.children,
+		&booking.maxAdults, &booking.maxChildren, &hotelID)
+	if err == sql.ErrNoRows {
+		return booking, false, c.JSON(http.StatusNotFound, dto.ErrorResponse{Message: "Booking not found"})
+	} else if err != nil {
+		return booking, false, c.JSON(http.StatusInternalServerError, dto.ErrorResponse{Message: "Failed to retrieve booking"})
+	}
+
+	if !canManageHotel(c, hotelID) {
+		return booking, false, forbiddenHotel(c)
+	}
+
+	if booking.status != string(model.Pending) && booking.status != string(model.Confirmed) {
+		return booking, false, c.JSON(http.StatusConflict, dto.ErrorResponse{Message: "Guests cannot be changed on a canceled booking"})
+	}
+	if booking.checkinStatus != string(model.NotCheckedIn) {
+		return booking, false, c.JSON(http.StatusConflict, dto.ErrorResponse{Message: "Guests can only be changed before check-in"})
+	}
+
+	err = tx.QueryRow(`SELECT COUNT(*) FROM guests WHERE booking_id = $1`, booking.bookingID).Scan(&booking.guests)
+	if err != nil {
+		return booking, false, c.JSON(http.StatusInternalServerError, dto.ErrorResponse{Message: "Failed to retrieve guests"})
+	}
+
+	return booking, true, nil
+}
+
+// makePrimaryGuest clears the primary flag of every other guest of the
+// booking so that guestID can take it. guestID is zero for a guest that is
+// about to be added.
+func makePrimaryGuest(tx *sql.Tx, bookingID, guestID int) error {
+	_, err := tx.Exec(`UPDATE guests SET is_primary = FALSE, updated_at = NOW() WHERE booking_id = $1 AND id <> $2 AND is_primary`, bookingID, guestID)
+	return err
+}
+
+// AddGuest registers another guest on a booking. The first guest of a
+// booking becomes its primary guest.
+func AddGuest(c echo.Context) error {
+	var req dto.GuestRequest
+
+	if err := c.Bind(&req); err != nil {
+		return c.JSON(http.StatusBadRequest, dto.ErrorResponse{Message: "Invalid request"})
+	}
+
+	if err := validateGuest(&req); err != nil {
+		return c.JSON(http.StatusBadRequest, dto.ErrorResponse{Message: err.Error()})
+	}
+
+	tx, err := config.DB.Begin()
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, dto.ErrorResponse{Message: "Failed to start transaction"})
+	}
+	defer tx.Rollback()
+
+	booking, ok, err := lockGuestBooking(c, tx, c.Param("booking_id"))
+	if !ok {
+		return err
+	}
+
+	if booking.guests >= booking.adults+booking.children {
+		return c.JSON(http.StatusConflict, dto.ErrorResponse{Message: "Every member of the party is already registered; update adults or children first"})
+	}
+
+	if booking.guests == 0 {
+		req.IsPrimary = true
+	}
+	if req.IsPrimary {
+		if err := makePrimaryGuest(tx, booking.bookingID, 0); err != nil {
+			return c.JSON(http.StatusInternalServerError, dto.ErrorResponse{Message: "Failed to add guest"})
+		}
+	}
+
+	guestID, err := insertGuest(tx, booking.bookingID, req)
+	if err != nil {
+		log.Println("Error adding guest:", err)
+		return c.JSON(http.StatusInternalServerError, dto.ErrorResponse{Message: "Failed to add guest"})
+	}
+
+	guest, err := scanGuest(tx.QueryRow(`SELECT `+guestColumns+` FROM guests WHERE id = $1`, guestID))
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, dto.ErrorResponse{Message: "Failed to add guest"})
+	}
+
+	if err := tx.Commit(); err != nil {
+		return c.JSON(http.StatusInternalServerError, dto.ErrorResponse{Message: "Failed to add guest"})
+	}
+
+	return c.JSON(http.StatusCreated, guest)
+}
+
+// UpdateGuest replaces the details of a guest.
+func UpdateGuest(c echo.Context) error {
+	var req dto.GuestRequest
+
+	if err := c.Bind(&req); err != nil {
+		return c.JSON(http.StatusBadRequest, dto.ErrorResponse{Message: "Invalid request"})
+	}
+
+	if err := validateGuest(&req); err != nil {
+		return c.JSON(http.StatusBadRequest, dto.ErrorResponse{Message: err.Error()})
+	}
+
+	tx, err := config.DB.Begin()
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, dto.ErrorResponse{Message: "Failed to start transaction"})
+	}
+	defer tx.Rollback()
+
+	booking, ok, err := lockGuestBooking(c, tx, c.Param("booking_id"))
+	if !ok {
+		return err
+	}
+
+	var guestID int
+	var isPrimary bool
+	query := `SELECT id, is_primary FROM guests WHERE id = $1 AND booking_id = $2`
+	err = tx.QueryRow(query, c.Param("guest_id"), booking.bookingID).Scan(&guestID, &isPrimary)
+	if err == sql.ErrNoRows {
+		return c.JSON(http.StatusNotFound, dto.ErrorResponse{Message: "Guest not found"})
+	} else if err != nil {
+		return c.JSON(http.StatusInternalServerError, dto.ErrorResponse{Message: "Failed to retrieve guest"})
+	}
+
+	// The primary guest stays primary until another guest takes over.
+	if isPrimary {
+		req.IsPrimary = true
+	} else if req.IsPrimary {
+		if err := makePrimaryGuest(tx, booking.bookingID, guestID); err != nil {
+			return c.JSON(http.StatusInternalServerError, dto.ErrorResponse{Message: "Failed to update guest"})
+		}
+	}
+
+	updateQuery := `
+		UPDATE guests SET is_primary = $2, first_name = $3, last_name = $4, email = $5, phone = $6, nationality = $7,
+			date_of_birth = $8, document_type = $9, document_number = $10, updated_at = NOW()
+		WHERE id = $1
+		RETURNING ` + guestColumns
+	guest, err := scanGuest(tx.QueryRow(updateQuery, guestID, req.IsPrimary, req.FirstName, req.LastName, nullString(req.Email), nullString(req.Phone),
+		nullString(req.Nationality), nullString(req.DateOfBirth), nullString(req.DocumentType), nullString(req.DocumentNumber)))
+	if err != nil {
+		log.Println("Error updating guest:", err)
+		return c.JSON(http.StatusInternalServerError, dto.ErrorResponse{Message: "Failed to update guest"})
+	}
+
+	if err := tx.Commit(); err != nil {
+		return c.JSON(http.StatusInternalServerError, dto.ErrorResponse{Message: "Failed to update guest"})
+	}
+
+	return c.JSON(http.StatusOK, guest)
+}
+
+// DeleteGuest removes a guest from a booking. When the primary guest is
+// removed the longest registered remaining guest takes over.
+func DeleteGuest(c echo.Context) error {
+	tx, err := config.DB.Begin()
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, dto.ErrorResponse{Message: "Failed to start transaction"})
+	}
+	defer tx.Rollback()
+
+	booking, ok, err := lockGuestBooking(c, tx, c.Param("booking_id"))
+	if !ok {
+		return err
+	}
+
+	var isPrimary bool
+	query := `DELETE FROM guests WHERE id = $1 AND booking_id = $2 RETURNING is_primary`
+	err = tx.QueryRow(query, c.Param("guest_id"), booking.bookingID).Scan(&isPrimary)
+	if err == sql.ErrNoRows {
+		return c.JSON(http.StatusNotFound, dto.ErrorResponse{Message: "Guest not found"})
+	} else if err != nil {
+		return c.JSON(http.StatusInternalServerError, dto.ErrorResponse{Message: "Failed to delete guest"})
+	}
+
+	if isPrimary {
+		promoteQuery := `
+			UPDATE guests SET is_primary = TRUE, updated_at = NOW()
+			WHERE id = (SELECT id FROM guests WHERE booking_id = $1 ORDER BY id LIMIT 1)
+		`
+		if _, err := tx.Exec(promoteQuery, booking.bookingID); err != nil {
+			return c.JSON(http.StatusInternalServerError, dto.ErrorResponse{Message: "Failed to delete guest"})
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return c.JSON(http.StatusInternalServerError, dto.ErrorResponse{Message: "Failed to delete guest"})
+	}
+
+	return c.JSON(http.StatusOK, dto.SuccessResponse{Message: "Guest deleted successfully"})
+}
+
+// UpdateParty changes the number of adults and children staying under a
+// booking. The party has to fit the room and cannot shrink below the
+// guests already registered.
+func UpdateParty(c echo.Context) error {
+	var req dto.UpdatePartyRequest
+
+	if err := c.Bind(&req); err != nil {
+		return c.JSON(http.StatusBadRequest, dto.ErrorResponse{Message: "Invalid request"})
+	}
+
+	if req.Adults < 1 || req.Children < 0 {
+		return c.JSON(http.StatusBadRequest, dto.ErrorResponse{Message: "adults must be at least 1 and children must not be negative"})
+	}
+
+	tx, err := config.DB.Begin()
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, dto.ErrorResponse{Message: "Failed to start transaction"})
+	}
+	defer tx.Rollback()
+
+	booking, ok, err := lockGuestBooking(c, tx, c.Param("booking_id"))
+	if !ok {
+		return err
+	}
+
+	if !fitsRoom(req.Adults, req.Children, booking.maxAdults, booking.maxChildren) {
+		return c.JSON(http.StatusBadRequest, dto.ErrorResponse{Message: partyTooLarge(booking.maxAdults, booking.maxChildren)})
+	}
+
+	if req.Adults+req.Children < booking.guests {
+		return c.JSON(http.StatusConflict, dto.ErrorResponse{Message: fmt.Sprintf("The booking has %d registered guests; remove guests first", booking.guests)})
+	}
+
+	_, err = tx.Exec(`UPDATE bookings SET adults = $2, children = $3, updated_at = NOW() WHERE id = $1`, booking.bookingID, req.Adults, req.Children)
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, dto.ErrorResponse{Message: "Failed to update party"})
+	}
+
+	if err := tx.Commit(); err != nil {
+		return c.JSON(http.StatusInternalServerError, dto.ErrorResponse{Message: "Failed to update party"})
+	}
+
+	return c.JSON(http.StatusOK, dto.SuccessResponse{Message: "Party updated successfully"})
+}
@@ -0,0 +1,81 @@
+package handler
+
+import (
+	"booking-service/dto"
+	"strings"
+	"testing"
+)
+
+func TestValidateParty(t *testing.T) {
+	guest := func(first, last string) dto.GuestRequest {
+		return dto.GuestRequest{FirstName: first, LastName: last}
+	}
+
+	tests := []struct {
+		name     string
+		adults   int
+		children int
+		guests   []dto.GuestRequest
+		wantErr  bool
+	}{
+		{name: "defaults to one adult", adults: 0},
+		{name: "guests fit the party", adults: 2, children: 1, guests: []dto.GuestRequest{guest("Ana", "Lima"), guest("Ben", "Lima")}},
+		{name: "more guests than party", adults: 1, guests: []dto.GuestRequest{guest("Ana", "Lima"), guest("Ben", "Lima")}, wantErr: true},
+		{name: "negative children", adults: 1, children: -1, wantErr: true},
+		{name: "missing last name", adults: 1, guests: []dto.GuestRequest{guest("Ana", " ")}, wantErr: true},
+		{name: "two primary guests", adults: 2, guests: []dto.GuestRequest{
+			{IsPrimary: true, FirstName: "Ana", LastName: "Lima"},
+			{IsPrimary: true, FirstName: "Ben", LastName: "Lima"},
+		}, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			adults := tt.adults
+			err := validateParty(&adults, tt.children, tt.guests)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("validateParty() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if err == nil && adults < 1 {
+				t.Fatalf("adults = %d, want at least 1", adults)
+			}
+		})
+	}
+}
+
+func TestValidateGuest(t *testing.T) {
+	tests := []struct {
+		name    string
+		guest   dto.GuestRequest
+		wantErr bool
+	}{
+		{name: "name only", guest: dto.GuestRequest{FirstName: "Ana", LastName: "Lima"}},
+		{name: "full details", guest: dto.GuestRequest{FirstName: "Ana", LastName: "Lima", Email: "ana@example.com", Nationality: "br",
+			DateOfBirth: "1990-04-01", DocumentType: "passport", DocumentNumber: "X123"}},
+		{name: "bad email", guest: dto.GuestRequest{FirstName: "Ana", LastName: "Lima", Email: "ana"}, wantErr: true},
+		{name: "bad nationality", guest: dto.GuestRequest{FirstName: "Ana", LastName: "Lima", Nationality: "BRA"}, wantErr: true},
+		{name: "bad date of birth", guest: dto.GuestRequest{FirstName: "Ana", LastName: "Lima", DateOfBirth: "01/04/1990"}, wantErr: true},
+		{name: "document number without type", guest: dto.GuestRequest{FirstName: "Ana", LastName: "Lima", DocumentNumber: "X123"}, wantErr: true},
+		{name: "document type without number", guest: dto.GuestRequest{FirstName: "Ana", LastName: "Lima", DocumentType: "passport"}, wantErr: true},
+		{name: "long last name", guest: dto.GuestRequest{FirstName: "Ana", LastName: strings.Repeat("é", 101)}, wantErr: true},
+		{name: "long phone", guest: dto.GuestRequest{FirstName: "Ana", LastName: "Lima", Phone: strings.Repeat("1", 51)}, wantErr: true},
+		{name: "long document number", guest: dto.GuestRequest{FirstName: "Ana", LastName: "Lima", DocumentType: "passport",
+			DocumentNumber: strings.Repeat("X", 51)}, wantErr: true},
+		{name: "unknown document type", guest: dto.GuestRequest{FirstName: "Ana", LastName: "Lima", DocumentType: "library_card", DocumentNumber: "1"}, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			guest := tt.guest
+			err := validateGuest(&guest)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("validateGuest() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+
+	guest := dto.GuestRequest{FirstName: "Ana", LastName: "Lima", Nationality: " br "}
+	if err := validateGuest(&guest); err != nil || guest.Nationality != "BR" {
+		t.Fatalf("nationality = %q, err = %v, want BR", guest.Nationality, err)
+	}
+}
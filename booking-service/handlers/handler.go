@@ -5,29 +5,122 @@ import (
 	"booking-service/dto"
 	model "booking-service/models"
 	"database/sql"
+	"errors"
+	"fmt"
 	"log"
 	"net/http"
+	"strconv"
 	"time"
 
 	"github.com/labstack/echo/v4"
 )
 
+const (
+	defaultHoldTTLMinutes             = 30
+	defaultFreeCancellationDays       = 1
+	defaultCancellationPenaltyPercent = 100.0
+)
+
+const hotelColumns = `h.id, h.name, h.address, h.city, h.country, h.phone_number, h.email, h.hold_ttl_minutes,
+	h.free_cancellation_days, h.cancellation_penalty_percent, h.created_at, h.updated_at, h.deleted_at`
+
+const roomColumns = `r.id, r.hotel_id, r.room_number, r.room_type, r.price_per_night, r.description, r.status,
+	r.max_adults, r.max_children, r.bed_configuration, r.created_at, r.updated_at, r.deleted_at`
+
+// hotelFields returns the scan destinations matching hotelColumns.
+func hotelFields(hotel *model.Hotel) []interface{} {
+	return []interface{}{
+		&hotel.HotelID,
+		&hotel.Name,
+		&hotel.Address,
+		&hotel.City,
+		&hotel.Country,
+		&hotel.PhoneNumber,
+		&hotel.Email,
+		&hotel.HoldTTLMinutes,
+		&hotel.FreeCancellationDays,
+		&hotel.CancellationPenaltyPercent,
+		&hotel.CreatedAt,
+		&hotel.UpdatedAt,
+		&hotel.DeletedAt,
+	}
+}
+
+// roomFields returns the scan destinations matching roomColumns.
+func roomFields(room *model.Room) []interface{} {
+	return []interface{}{
+		&room.RoomID,
+		&room.HotelID,
+		&room.RoomNumber,
+		&room.RoomType,
+		&room.PricePerNight,
+		&room.Description,
+		&room.Status,
+		&room.MaxAdults,
+		&room.MaxChildren,
+		&room.BedConfiguration,
+		&room.CreatedAt,
+		&room.UpdatedAt,
+		&room.DeletedAt,
+	}
+}
+
+// validateHotelPolicy checks the optional booking policy settings of a
+// hotel request.
+func validateHotelPolicy(holdTTLMinutes, freeCancellationDays *int, cancellationPenaltyPercent *float64) error {
+	if holdTTLMinutes != nil && *holdTTLMinutes <= 0 {
+		return errors.New("hold_ttl_minutes must be greater than zero")
+	}
+	if freeCancellationDays != nil && *freeCancellationDays < 0 {
+		return errors.New("free_cancellation_days must not be negative")
+	}
+	if cancellationPenaltyPercent != nil && (*cancellationPenaltyPercent < 0 || *cancellationPenaltyPercent > 100) {
+		return errors.New("cancellation_penalty_percent must be between 0 and 100")
+	}
+	return nil
+}
+
 func CreateHotel(c echo.Context) error {
+	if !isGlobalStaff(c) {
+		return c.JSON(http.StatusForbidden, dto.ErrorResponse{Message: "Only administrators can create hotels"})
+	}
+
 	var req dto.CreateHotelRequest
 
 	if err := c.Bind(&req); err != nil {
 		return c.JSON(http.StatusBadRequest, dto.ErrorResponse{Message: "Invalid request"})
 	}
 
+	if err := validateHotelPolicy(req.HoldTTLMinutes, req.FreeCancellationDays, req.CancellationPenaltyPercent); err != nil {
+		return c.JSON(http.StatusBadRequest, dto.ErrorResponse{Message: err.Error()})
+	}
+
+	holdTTLMinutes := defaultHoldTTLMinutes
+	if req.HoldTTLMinutes != nil {
+		holdTTLMinutes = *req.HoldTTLMinutes
+	}
+
+	freeCancellationDays := defaultFreeCancellationDays
+	if req.FreeCancellationDays != nil {
+		freeCancellationDays = *req.FreeCancellationDays
+	}
+
+	cancellationPenaltyPercent := defaultCancellationPenaltyPercent
+	if req.CancellationPenaltyPercent != nil {
+		cancellationPenaltyPercent = *req.CancellationPenaltyPercent
+	}
+
 	query := `
-		INSERT INTO hotels (name, address, city, country, phone_number, email, created_at, updated_at)
-		VALUES ($1, $2, $3, $4, $5, $6, NOW(), NOW())
+		INSERT INTO hotels (name, address, city, country, phone_number, email, hold_ttl_minutes,
+			free_cancellation_days, cancellation_penalty_percent, created_at, updated_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, NOW(), NOW())
 		RETURNING id
 	`
 
 	var hotelID int
 
-	err := config.DB.QueryRow(query, req.Name, req.Address, req.City, req.Country, req.PhoneNumber, req.Email).Scan(&hotelID)
+	err := config.DB.QueryRow(query, req.Name, req.Address, req.City, req.Country, req.PhoneNumber, req.Email,
+		holdTTLMinutes, freeCancellationDays, cancellationPenaltyPercent).Scan(&hotelID)
 	if err != nil {
 		log.Println("Error executing query:", err)
 		return c.JSON(http.StatusInternalServerError, dto.ErrorResponse{Message: "Failed to create hotel"})
@@ -46,8 +139,20 @@ func CreateRoom(c echo.Context) error {
 		return c.JSON(http.StatusBadRequest, dto.ErrorResponse{Message: "Invalid request"})
 	}
 
+	if req.Status == "" {
+		req.Status = string(model.Available)
+	}
+	if !isValidRoomStatus(model.RoomStatus(req.Status)) {
+		return c.JSON(http.StatusBadRequest, dto.ErrorResponse{Message: "status must be one of 'available', 'occupied' or 'maintenance'"})
+	}
+
+	occupancy := occupancyFromRequest(defaultOccupancy(model.RoomType(req.RoomType)), req.MaxAdults, req.MaxChildren, req.BedConfiguration)
+	if err := validateOccupancy(occupancy); err != nil {
+		return c.JSON(http.StatusBadRequest, dto.ErrorResponse{Message: err.Error()})
+	}
+
 	hotelCheckQuery := `
-		SELECT id FROM hotels WHERE id = $1
+		SELECT id FROM hotels WHERE id = $1 AND deleted_at IS NULL
 	`
 	var existingHotelID int
 
@@ -56,8 +161,12 @@ func CreateRoom(c echo.Context) error {
 		return c.JSON(http.StatusBadRequest, dto.ErrorResponse{Message: "hotel not found"})
 	}
 
+	if !canManageHotel(c, existingHotelID) {
+		return forbiddenHotel(c)
+	}
+
 	roomCheckQuery := `
-		SELECT id FROM rooms WHERE hotel_id = $1 AND room_number = $2
+		SELECT id FROM rooms WHERE hotel_id = $1 AND room_number = $2 AND deleted_at IS NULL
 	`
 	var existingRoomID int
 	err = config.DB.QueryRow(roomCheckQuery, req.HotelID, req.RoomNumber).Scan(&existingRoomID)
@@ -66,14 +175,16 @@ func CreateRoom(c echo.Context) error {
 	}
 
 	insertRoomQuery := `
-		INSERT INTO rooms (hotel_id, room_number, room_type, price_per_night, description, status, created_at, updated_at)
-		VALUES ($1, $2, $3, $4, $5, $6, NOW(), NOW())
+		INSERT INTO rooms (hotel_id, room_number, room_type, price_per_night, description, status,
+			max_adults, max_children, bed_configuration, created_at, updated_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, NOW(), NOW())
 		RETURNING id
 	`
 
 	var roomID int
 
-	err = config.DB.QueryRow(insertRoomQuery, req.HotelID, req.RoomNumber, req.RoomType, req.PricePerNight, req.Description, req.Status).Scan(&roomID)
+	err = config.DB.QueryRow(insertRoomQuery, req.HotelID, req.RoomNumber, req.RoomType, req.PricePerNight, req.Description, req.Status,
+		occupancy.maxAdults, occupancy.maxChildren, occupancy.beds).Scan(&roomID)
 	if err != nil {
 		return c.JSON(http.StatusInternalServerError, dto.ErrorResponse{Message: "Failed to create room"})
 	}
@@ -92,21 +203,35 @@ func CreateBooking(c echo.Context) error {
 		return c.JSON(http.StatusBadRequest, dto.ErrorResponse{Message: "Invalid request"})
 	}
 
-	checkinDate, err := time.Parse("2006-01-02", req.CheckinDate)
+	checkinDate, checkoutDate, err := parseStay(req.CheckinDate, req.CheckoutDate)
 	if err != nil {
-		return c.JSON(http.StatusBadRequest, dto.ErrorResponse{Message: "Invalid checkin_date format"})
+		return c.JSON(http.StatusBadRequest, dto.ErrorResponse{Message: err.Error()})
+	}
+
+	if err := validateParty(&req.Adults, req.Children, req.Guests); err != nil {
+		return c.JSON(http.StatusBadRequest, dto.ErrorResponse{Message: err.Error()})
 	}
 
-	checkoutDate, err := time.Parse("2006-01-02", req.CheckoutDate)
+	tx, err := config.DB.Begin()
 	if err != nil {
-		return c.JSON(http.StatusBadRequest, dto.ErrorResponse{Message: "Invalid checkout_date format"})
+		return c.JSON(http.StatusInternalServerError, dto.ErrorResponse{Message: "Failed to start transaction"})
 	}
+	defer tx.Rollback()
 
+	// Locking the room row serializes concurrent bookings of the same room,
+	// so no other request can insert an overlapping booking between the
+	// availability check and our insert.
 	var roomStatus string
+	var pricePerNight float64
+	var holdTTLMinutes, maxAdults, maxChildren int
 	checkRoomQuery := `
-		SELECT status FROM rooms WHERE id = $1
+		SELECT r.status, r.price_per_night, h.hold_ttl_minutes, r.max_adults, r.max_children
+		FROM rooms r
+		JOIN hotels h ON h.id = r.hotel_id
+		WHERE r.id = $1 AND r.deleted_at IS NULL
+		FOR UPDATE OF r
 	`
-	err = config.DB.QueryRow(checkRoomQuery, req.RoomID).Scan(&roomStatus)
+	err = tx.QueryRow(checkRoomQuery, req.RoomID).Scan(&roomStatus, &pricePerNight, &holdTTLMinutes, &maxAdults, &maxChildren)
 
 	if err == sql.ErrNoRows {
 		return c.JSON(http.StatusBadRequest, dto.ErrorResponse{Message: "Room not found"})
@@ -114,83 +239,209 @@ func CreateBooking(c echo.Context) error {
 		return c.JSON(http.StatusInternalServerError, dto.ErrorResponse{Message: "Failed to check room availability"})
 	}
 
-	if roomStatus != "available" {
-		return c.JSON(http.StatusBadRequest, dto.ErrorResponse{Message: "Room is not available"})
+	if roomStatus == string(model.Maintenance) {
+		return c.JSON(http.StatusBadRequest, dto.ErrorResponse{Message: "Room is under maintenance"})
+	}
+
+	if !fitsRoom(req.Adults, req.Children, maxAdults, maxChildren) {
+		return c.JSON(http.StatusBadRequest, dto.ErrorResponse{Message: partyTooLarge(maxAdults, maxChildren)})
+	}
+
+	unavailable, err := isRoomUnavailable(tx, req.RoomID, checkinDate, checkoutDate, 0)
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, dto.ErrorResponse{Message: "Failed to check room availability"})
+	}
+	if unavailable {
+		return c.JSON(http.StatusBadRequest, dto.ErrorResponse{Message: "Room is not available for the selected dates"})
+	}
+
+	nightlyRates, totalPrice, err := calculateStayPrice(tx, req.RoomID, pricePerNight, checkinDate, checkoutDate)
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, dto.ErrorResponse{Message: "Failed to calculate booking price"})
+	}
+
+	if req.TotalPrice != 0 && !sameAmount(req.TotalPrice, totalPrice) {
+		return c.JSON(http.StatusConflict, dto.ErrorResponse{Message: "total_price does not match the current price of the stay"})
 	}
 
 	insertBookingQuery := `
-		INSERT INTO bookings (user_id, room_id, checkin_date, checkout_date, total_price, status, created_at, updated_at)
-		VALUES ($1, $2, $3, $4, $5, $6, NOW(), NOW())
-		RETURNING id
+		INSERT INTO bookings (user_id, room_id, checkin_date, checkout_date, total_price, status, expires_at, adults, children, created_at, updated_at)
+		VALUES ($1, $2, $3, $4, $5, $6, NOW() + make_interval(mins => $7), $8, $9, NOW(), NOW())
+		RETURNING id, expires_at
 	`
 
 	var bookingID int
-	err = config.DB.QueryRow(insertBookingQuery, req.UserID, req.RoomID, checkinDate, checkoutDate, req.TotalPrice, "pending").Scan(&bookingID)
+	var expiresAt time.Time
+	err = tx.QueryRow(insertBookingQuery, req.UserID, req.RoomID, checkinDate, checkoutDate, totalPrice, model.Pending, holdTTLMinutes,
+		req.Adults, req.Children).Scan(&bookingID, &expiresAt)
 	if err != nil {
 		return c.JSON(http.StatusInternalServerError, dto.ErrorResponse{Message: "Failed to create booking"})
 	}
 
-	updateRoomStatusQuery := `
-		UPDATE rooms SET status = 'booked', updated_at = NOW() WHERE id = $1
-	`
-	_, err = config.DB.Exec(updateRoomStatusQuery, req.RoomID)
-	if err != nil {
-		return c.JSON(http.StatusInternalServerError, dto.ErrorResponse{Message: "Failed to update room status"})
+	if err := insertGuests(tx, bookingID, req.Guests); err != nil {
+		log.Println("Error registering guests:", err)
+		return c.JSON(http.StatusInternalServerError, dto.ErrorResponse{Message: "Failed to create booking"})
+	}
+
+	if err := tx.Commit(); err != nil {
+		return c.JSON(http.StatusInternalServerError, dto.ErrorResponse{Message: "Failed to create booking"})
 	}
 
 	return c.JSON(http.StatusCreated, dto.CreateBookingResponse{
-		BookingId: bookingID,
-		Message:   "Booking created successfully",
+		BookingId:    bookingID,
+		TotalPrice:   totalPrice,
+		NightlyRates: nightlyRates,
+		ExpiresAt:    expiresAt,
+		Message:      "Booking created successfully",
 	})
 }
 
 
+func QuoteBooking(c echo.Context) error {
+	var req dto.QuoteBookingRequest
+
+	if err := c.Bind(&req); err != nil {
+		return c.JSON(http.StatusBadRequest, dto.ErrorResponse{Message: "Invalid request"})
+	}
+
+	checkinDate, checkoutDate, err := parseStay(req.CheckinDate, req.CheckoutDate)
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, dto.ErrorResponse{Message: err.Error()})
+	}
+
+	if err := validateParty(&req.Adults, req.Children, nil); err != nil {
+		return c.JSON(http.StatusBadRequest, dto.ErrorResponse{Message: err.Error()})
+	}
+
+	var roomStatus string
+	var pricePerNight float64
+	var maxAdults, maxChildren int
+	query := `SELECT status, price_per_night, max_adults, max_children FROM rooms WHERE id = $1 AND deleted_at IS NULL`
+	err = config.DB.QueryRow(query, req.RoomID).Scan(&roomStatus, &pricePerNight, &maxAdults, &maxChildren)
+	if err == sql.ErrNoRows {
+		return c.JSON(http.StatusNotFound, dto.ErrorResponse{Message: "Room not found"})
+	} else if err != nil {
+		return c.JSON(http.StatusInternalServerError, dto.ErrorResponse{Message: "Failed to retrieve room"})
+	}
+
+	unavailable, err := isRoomUnavailable(config.DB, req.RoomID, checkinDate, checkoutDate, 0)
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, dto.ErrorResponse{Message: "Failed to check room availability"})
+	}
+
+	nightlyRates, totalPrice, err := calculateStayPrice(config.DB, req.RoomID, pricePerNight, checkinDate, checkoutDate)
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, dto.ErrorResponse{Message: "Failed to calculate booking price"})
+	}
+
+	return c.JSON(http.StatusOK, dto.QuoteBookingResponse{
+		RoomID:       req.RoomID,
+		CheckinDate:  req.CheckinDate,
+		CheckoutDate: req.CheckoutDate,
+		Nights:       len(nightlyRates),
+		NightlyRates: nightlyRates,
+		TotalPrice:   totalPrice,
+		Available:    roomStatus != string(model.Maintenance) && !unavailable && fitsRoom(req.Adults, req.Children, maxAdults, maxChildren),
+	})
+}
+
+var hotelSortOptions = map[string]sortOption{
+	"id":         {expr: "h.id", cast: "integer"},
+	"name":       {expr: "h.name", cast: "text"},
+	"city":       {expr: "h.city", cast: "text"},
+	"created_at": {expr: "h.created_at", cast: "timestamp"},
+}
+
+// GetAllHotels lists hotels a page at a time. It can be filtered by city,
+// country, a partial name and the amenities a hotel must all have. The
+// response counts the amenities of every matching hotel as facets.
 func GetAllHotels(c echo.Context) error {
-	query := `SELECT id, name, address, city, country, phone_number, email, created_at, updated_at FROM hotels`
+	page, err := parsePageQuery(c, hotelSortOptions, "id")
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, dto.ErrorResponse{Message: err.Error()})
+	}
 
-	var hotels []model.Hotel
+	filter := ` FROM hotels h WHERE h.deleted_at IS NULL`
+	args := []interface{}{}
 
-	rows, err := config.DB.Query(query)
+	if city := c.QueryParam("city"); city != "" {
+		args = append(args, city)
+		filter += fmt.Sprintf(" AND h.city ILIKE $%d", len(args))
+	}
+
+	if country := c.QueryParam("country"); country != "" {
+		args = append(args, country)
+		filter += fmt.Sprintf(" AND h.country ILIKE $%d", len(args))
+	}
+
+	if name := c.QueryParam("name"); name != "" {
+		args = append(args, "%"+name+"%")
+		filter += fmt.Sprintf(" AND h.name ILIKE $%d", len(args))
+	}
+
+	filter, args = hotelAmenities.filter(c, filter, args)
+
+	facets, err := hotelAmenities.facets(config.DB, `SELECT h.id`+filter, args)
+	if err != nil {
+		log.Println("Error counting hotel amenities:", err)
+		return c.JSON(http.StatusInternalServerError, dto.ErrorResponse{Message: "Failed to retrieve hotels"})
+	}
+
+	query, args := page.apply(`SELECT `+hotelColumns+`, `+page.sortKey()+filter, args, "h.id")
+
+	hotels := []model.Hotel{}
+	var keys []string
+
+	rows, err := config.DB.Query(query, args...)
 	if err != nil {
-		log.Print(err, "ERRORNYA")
+		log.Println("Error retrieving hotels:", err)
 		return c.JSON(http.StatusInternalServerError, dto.ErrorResponse{Message: "Failed to retrieve hotels"})
 	}
 	defer rows.Close()
 
 	for rows.Next() {
 		var hotel model.Hotel
-		if err := rows.Scan(&hotel.HotelID, &hotel.Name, &hotel.Address, &hotel.City, &hotel.Country, &hotel.PhoneNumber, &hotel.Email, &hotel.CreatedAt, &hotel.UpdatedAt); 
-		err != nil {
+		var key string
+		if err := rows.Scan(append(hotelFields(&hotel), &key)...); err != nil {
 			return c.JSON(http.StatusInternalServerError, dto.ErrorResponse{Message: "Failed to scan hotel data"})
 		}
 		hotels = append(hotels, hotel)
+		keys = append(keys, key)
 	}
 
 	if err := rows.Err(); err != nil {
 		return c.JSON(http.StatusInternalServerError, dto.ErrorResponse{Message: "Error occurred during hotels retrieval"})
 	}
 
-	return c.JSON(http.StatusOK, hotels)
+	var nextCursor *string
+	if len(hotels) > page.limit {
+		hotels = hotels[:page.limit]
+		nextCursor = page.cursorAfter(keys[page.limit-1], hotels[page.limit-1].HotelID)
+	}
+
+	hotelIDs := make([]int64, len(hotels))
+	for i, hotel := range hotels {
+		hotelIDs[i] = int64(hotel.HotelID)
+	}
+	amenities, err := hotelAmenities.load(config.DB, hotelIDs)
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, dto.ErrorResponse{Message: "Failed to retrieve hotel amenities"})
+	}
+	for i := range hotels {
+		hotels[i].Amenities = amenities[hotels[i].HotelID]
+	}
+
+	return c.JSON(http.StatusOK, dto.PageResponse{Data: hotels, NextCursor: nextCursor, Facets: facets})
 }
 
 func GetHotelByID(c echo.Context) error {
 	id := c.Param("id")
 
-	query := `SELECT id, name, address, city, country, phone_number, email, created_at, updated_at FROM hotels WHERE id = $1`
+	query := `SELECT ` + hotelColumns + ` FROM hotels h WHERE h.id = $1`
 
 	var hotel model.Hotel
 
-	err := config.DB.QueryRow(query, id).Scan(
-		&hotel.HotelID,
-		&hotel.Name,
-		&hotel.Address,
-		&hotel.City,
-		&hotel.Country,
-		&hotel.PhoneNumber,
-		&hotel.Email,
-		&hotel.CreatedAt,
-		&hotel.UpdatedAt,
-	)
+	err := config.DB.QueryRow(query, id).Scan(hotelFields(&hotel)...)
 
 	if err == sql.ErrNoRows {
 		return c.JSON(http.StatusNotFound, dto.ErrorResponse{Message: "Hotel not found"})
@@ -198,27 +449,23 @@ func GetHotelByID(c echo.Context) error {
 		return c.JSON(http.StatusInternalServerError, dto.ErrorResponse{Message: "Failed to retrieve hotel"})
 	}
 
+	amenities, err := hotelAmenities.load(config.DB, []int64{int64(hotel.HotelID)})
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, dto.ErrorResponse{Message: "Failed to retrieve hotel amenities"})
+	}
+	hotel.Amenities = amenities[hotel.HotelID]
+
 	return c.JSON(http.StatusOK, hotel)
 }
 
 func GetRoomByID(c echo.Context) error {
 	id := c.Param("id")
 
-	query := `SELECT id, hotel_id, room_number, room_type, price_per_night, description, status, created_at, updated_at FROM rooms WHERE id = $1`
+	query := `SELECT ` + roomColumns + ` FROM rooms r WHERE r.id = $1`
 
 	var room model.Room
 
-	err := config.DB.QueryRow(query, id).Scan(
-		&room.RoomID,
-		&room.HotelID,
-		&room.RoomNumber,
-		&room.RoomType,
-		&room.PricePerNight,
-		&room.Description,
-		&room.Status,
-		&room.CreatedAt,
-		&room.UpdatedAt,
-	)
+	err := config.DB.QueryRow(query, id).Scan(roomFields(&room)...)
 
 	if err == sql.ErrNoRows {
 		return c.JSON(http.StatusNotFound, dto.ErrorResponse{Message: "Room not found"})
@@ -227,9 +474,26 @@ func GetRoomByID(c echo.Context) error {
 		return c.JSON(http.StatusInternalServerError, dto.ErrorResponse{Message: "Failed to retrieve room"})
 	}
 
+	amenities, err := roomAmenities.load(config.DB, []int64{int64(room.RoomID)})
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, dto.ErrorResponse{Message: "Failed to retrieve room amenities"})
+	}
+	room.Amenities = amenities[room.RoomID]
+
 	return c.JSON(http.StatusOK, room)
 }
 
+var roomSortOptions = map[string]sortOption{
+	"id":              {expr: "r.id", cast: "integer"},
+	"room_number":     {expr: "r.room_number", cast: "text"},
+	"price_per_night": {expr: "r.price_per_night", cast: "numeric"},
+	"created_at":      {expr: "r.created_at", cast: "timestamp"},
+}
+
+// ListRoomsByHotelId lists the rooms of a hotel a page at a time. It can be
+// filtered by status, room_type, a min_price/max_price range, the adults
+// and children a room has to fit and the amenities it must all have. The
+// response counts the amenities of every matching room as facets.
 func ListRoomsByHotelId(c echo.Context) error {
 	hotelID := c.QueryParam("hotel_id")
 
@@ -237,41 +501,119 @@ func ListRoomsByHotelId(c echo.Context) error {
 		return c.JSON(http.StatusBadRequest, dto.ErrorResponse{Message: "hotel_id is required"})
 	}
 
-	query := `SELECT id, hotel_id, room_number, room_type, price_per_night, description, status, created_at, updated_at FROM rooms WHERE hotel_id = $1`
+	page, err := parsePageQuery(c, roomSortOptions, "id")
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, dto.ErrorResponse{Message: err.Error()})
+	}
+
+	filter := ` FROM rooms r WHERE r.hotel_id = $1 AND r.deleted_at IS NULL`
+	args := []interface{}{hotelID}
 
-	var rooms []model.Room
+	if status := c.QueryParam("status"); status != "" {
+		if !isValidRoomStatus(model.RoomStatus(status)) {
+			return c.JSON(http.StatusBadRequest, dto.ErrorResponse{Message: "status must be one of 'available', 'occupied' or 'maintenance'"})
+		}
+		args = append(args, status)
+		filter += fmt.Sprintf(" AND r.status = $%d", len(args))
+	}
 
-	rows, err := config.DB.Query(query, hotelID)
+	if roomType := c.QueryParam("room_type"); roomType != "" {
+		args = append(args, roomType)
+		filter += fmt.Sprintf(" AND r.room_type = $%d", len(args))
+	}
+
+	if value := c.QueryParam("min_price"); value != "" {
+		minPrice, err := strconv.ParseFloat(value, 64)
+		if err != nil {
+			return c.JSON(http.StatusBadRequest, dto.ErrorResponse{Message: "Invalid min_price"})
+		}
+		args = append(args, minPrice)
+		filter += fmt.Sprintf(" AND r.price_per_night >= $%d", len(args))
+	}
+
+	if value := c.QueryParam("max_price"); value != "" {
+		maxPrice, err := strconv.ParseFloat(value, 64)
+		if err != nil {
+			return c.JSON(http.StatusBadRequest, dto.ErrorResponse{Message: "Invalid max_price"})
+		}
+		args = append(args, maxPrice)
+		filter += fmt.Sprintf(" AND r.price_per_night <= $%d", len(args))
+	}
+
+	adults, children, hasParty, err := parsePartyQuery(c)
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, dto.ErrorResponse{Message: err.Error()})
+	}
+	if hasParty {
+		args = append(args, adults, children)
+		filter += " AND " + roomFitsClause(len(args)-1, len(args))
+	}
+
+	filter, args = roomAmenities.filter(c, filter, args)
+
+	facets, err := roomAmenities.facets(config.DB, `SELECT r.id`+filter, args)
+	if err != nil {
+		log.Println("Error counting room amenities:", err)
+		return c.JSON(http.StatusInternalServerError, dto.ErrorResponse{Message: "Failed to retrieve rooms"})
+	}
+
+	query, args := page.apply(`SELECT `+roomColumns+`, `+page.sortKey()+filter, args, "r.id")
+
+	rooms := []model.Room{}
+	var keys []string
+
+	rows, err := config.DB.Query(query, args...)
 	if err != nil {
+		log.Println("Error retrieving rooms:", err)
 		return c.JSON(http.StatusInternalServerError, dto.ErrorResponse{Message: "Failed to retrieve rooms"})
 	}
 	defer rows.Close()
 
 	for rows.Next() {
 		var room model.Room
-		if err := rows.Scan(
-			&room.RoomID,
-			&room.HotelID,
-			&room.RoomNumber,
-			&room.RoomType,
-			&room.PricePerNight,
-			&room.Description,
-			&room.Status,
-			&room.CreatedAt,
-			&room.UpdatedAt,
-		); err != nil {
+		var key string
+		if err := rows.Scan(append(roomFields(&room), &key)...); err != nil {
 			return c.JSON(http.StatusInternalServerError, dto.ErrorResponse{Message: "Failed to scan room data"})
 		}
 		rooms = append(rooms, room)
+		keys = append(keys, key)
 	}
 
 	if err := rows.Err(); err != nil {
 		return c.JSON(http.StatusInternalServerError, dto.ErrorResponse{Message: "Error occurred during rooms retrieval"})
 	}
 
-	return c.JSON(http.StatusOK, rooms)
+	var nextCursor *string
+	if len(rooms) > page.limit {
+		rooms = rooms[:page.limit]
+		nextCursor = page.cursorAfter(keys[page.limit-1], rooms[page.limit-1].RoomID)
+	}
+
+	roomIDs := make([]int64, len(rooms))
+	for i, room := range rooms {
+		roomIDs[i] = int64(room.RoomID)
+	}
+	amenities, err := roomAmenities.load(config.DB, roomIDs)
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, dto.ErrorResponse{Message: "Failed to retrieve room amenities"})
+	}
+	for i := range rooms {
+		rooms[i].Amenities = amenities[rooms[i].RoomID]
+	}
+
+	return c.JSON(http.StatusOK, dto.PageResponse{Data: rooms, NextCursor: nextCursor, Facets: facets})
 }
 
+var bookingSortOptions = map[string]sortOption{
+	"id":           {expr: "id", cast: "integer"},
+	"checkin_date": {expr: "checkin_date", cast: "date"},
+	"total_price":  {expr: "total_price", cast: "numeric"},
+	"created_at":   {expr: "created_at", cast: "timestamp"},
+}
+
+// GetBookingsByUserID lists a user's bookings a page at a time, newest
+// first by default. It can be filtered by status and by a
+// checkin_from/checkin_to range of check-in dates.
 func GetBookingsByUserID(c echo.Context) error {
 	userID := c.Param("user_id")
 
@@ -279,14 +621,46 @@ func GetBookingsByUserID(c echo.Context) error {
 		return c.JSON(http.StatusBadRequest, dto.ErrorResponse{Message: "user_id is required"})
 	}
 
+	page, err := parsePageQuery(c, bookingSortOptions, "-created_at")
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, dto.ErrorResponse{Message: err.Error()})
+	}
+
 	query := `
-		SELECT id, user_id, room_id, checkin_date, checkout_date, total_price, status, created_at, updated_at 
-		FROM bookings WHERE user_id = $1
-	`
+		SELECT id, user_id, room_id, checkin_date, checkout_date, total_price, status, expires_at,
+			cancellation_fee, canceled_at, reservation_id, created_at, updated_at, ` + page.sortKey() + `
+		FROM bookings WHERE user_id = $1`
+	args := []interface{}{userID}
+
+	if status := c.QueryParam("status"); status != "" {
+		args = append(args, status)
+		query += fmt.Sprintf(" AND status = $%d", len(args))
+	}
+
+	if value := c.QueryParam("checkin_from"); value != "" {
+		checkinFrom, err := time.Parse(dateLayout, value)
+		if err != nil {
+			return c.JSON(http.StatusBadRequest, dto.ErrorResponse{Message: "Invalid checkin_from format"})
+		}
+		args = append(args, checkinFrom)
+		query += fmt.Sprintf(" AND checkin_date >= $%d", len(args))
+	}
+
+	if value := c.QueryParam("checkin_to"); value != "" {
+		checkinTo, err := time.Parse(dateLayout, value)
+		if err != nil {
+			return c.JSON(http.StatusBadRequest, dto.ErrorResponse{Message: "Invalid checkin_to format"})
+		}
+		args = append(args, checkinTo)
+		query += fmt.Sprintf(" AND checkin_date <= $%d", len(args))
+	}
+
+	query, args = page.apply(query, args, "id")
 
 	bookings := []model.Booking{}
+	var keys []string
 
-	rows, err := config.DB.Query(query, userID)
+	rows, err := config.DB.Query(query, args...)
 	if err != nil {
 		return c.JSON(http.StatusInternalServerError, dto.ErrorResponse{Message: "Failed to retrieve bookings"})
 	}
@@ -294,6 +668,7 @@ func GetBookingsByUserID(c echo.Context) error {
 
 	for rows.Next() {
 		var booking model.Booking
+		var key string
 		if err := rows.Scan(
 			&booking.BookingID,
 			&booking.UserID,
@@ -302,15 +677,31 @@ func GetBookingsByUserID(c echo.Context) error {
 			&booking.CheckoutDate,
 			&booking.TotalPrice,
 			&booking.Status,
+			&booking.ExpiresAt,
+			&booking.CancellationFee,
+			&booking.CanceledAt,
+			&booking.ReservationID,
 			&booking.CreatedAt,
 			&booking.UpdatedAt,
+			&key,
 		); err != nil {
 			return c.JSON(http.StatusInternalServerError, dto.ErrorResponse{Message: "Failed to scan booking data"})
 		}
 		bookings = append(bookings, booking)
+		keys = append(keys, key)
 	}
 
-	return c.JSON(http.StatusOK, bookings)
+	if err := rows.Err(); err != nil {
+		return c.JSON(http.StatusInternalServerError, dto.ErrorResponse{Message: "Error occurred during bookings retrieval"})
+	}
+
+	var nextCursor *string
+	if len(bookings) > page.limit {
+		bookings = bookings[:page.limit]
+		nextCursor = page.cursorAfter(keys[page.limit-1], bookings[page.limit-1].BookingID)
+	}
+
+	return c.JSON(http.StatusOK, dto.PageResponse{Data: bookings, NextCursor: nextCursor})
 }
 
 func GetBookingByID(c echo.Context) error {
@@ -321,8 +712,11 @@ func GetBookingByID(c echo.Context) error {
 	}
 
 	query := `
-		SELECT id, user_id, room_id, checkin_date, checkout_date, total_price, status, created_at, updated_at 
-		FROM bookings WHERE id = $1
+		SELECT b.id, b.user_id, b.room_id, r.hotel_id, b.checkin_date, b.checkout_date, b.total_price, b.status,
+			b.expires_at, b.cancellation_fee, b.canceled_at, b.reservation_id, b.adults, b.children, b.created_at, b.updated_at
+		FROM bookings b
+		JOIN rooms r ON r.id = b.room_id
+		WHERE b.id = $1
 	`
 
 	var booking model.Booking
@@ -331,10 +725,17 @@ func GetBookingByID(c echo.Context) error {
 		&booking.BookingID,
 		&booking.UserID,
 		&booking.RoomID,
+		&booking.HotelID,
 		&booking.CheckinDate,
 		&booking.CheckoutDate,
 		&booking.TotalPrice,
 		&booking.Status,
+		&booking.ExpiresAt,
+		&booking.CancellationFee,
+		&booking.CanceledAt,
+		&booking.ReservationID,
+		&booking.Adults,
+		&booking.Children,
 		&booking.CreatedAt,
 		&booking.UpdatedAt,
 	)
@@ -345,49 +746,40 @@ func GetBookingByID(c echo.Context) error {
 		return c.JSON(http.StatusInternalServerError, dto.ErrorResponse{Message: "Failed to retrieve booking"})
 	}
 
-	return c.JSON(http.StatusOK, booking)
-}
-
-func UpdateBookingStatusHandler(c echo.Context) error {
-	var req dto.UpdateBookingStatusRequest
-
-	if err := c.Bind(&req); err != nil {
-		return c.JSON(http.StatusBadRequest, dto.ErrorResponse{Message: "Invalid request"})
-	}
-
-	query := `UPDATE bookings SET status = $1 WHERE id = $2`
-	_, err := config.DB.Exec(query, req.Status, req.BookingID)
+	guests, err := loadGuests(config.DB, []int64{int64(booking.BookingID)})
 	if err != nil {
-		return c.JSON(http.StatusInternalServerError, dto.ErrorResponse{Message: "Failed to update booking status"})
+		return c.JSON(http.StatusInternalServerError, dto.ErrorResponse{Message: "Failed to retrieve guests"})
 	}
+	booking.Guests = guests[booking.BookingID]
 
-	return c.JSON(http.StatusOK, dto.SuccessResponse{Message: "Booking status updated successfully"})
+	return c.JSON(http.StatusOK, booking)
 }
 
-func UpdateBookingStatus(c echo.Context) error {
-	var req dto.UpdateBookingRefundStatusRequest
+func UpdateBookingStatusHandler(c echo.Context) error {
+	var req dto.UpdateBookingStatusRequest
 
 	if err := c.Bind(&req); err != nil {
 		return c.JSON(http.StatusBadRequest, dto.ErrorResponse{Message: "Invalid request"})
 	}
 
-	if req.BookingID == nil || req.UserID == nil || req.Status == "" {
-		return c.JSON(http.StatusBadRequest, dto.ErrorResponse{Message: "Booking ID, User ID, and Status are required"})
-	}
-
+	// A payment can only confirm a booking whose hold is still active;
+	// once the hold expires the room may already belong to someone else.
+	// It also has to cover the current price, which may have changed since
+	// the payment was opened. Lines of a reservation are only confirmed
+	// with their reservation.
 	query := `
-		UPDATE bookings 
-		SET status = $1, updated_at = NOW()
-		WHERE id = $2 AND user_id = $3 AND checkin_status NOT IN ('checked_in', 'checked_out')
+		UPDATE bookings SET status = $1, expires_at = NULL, updated_at = NOW()
+		WHERE id = $2 AND status = $3 AND (expires_at IS NULL OR expires_at > NOW())
+		AND reservation_id IS NULL AND total_price = ROUND($4::numeric, 2)
 	`
-	res, err := config.DB.Exec(query, req.Status, req.BookingID, req.UserID)
+	res, err := config.DB.Exec(query, req.Status, req.BookingID, model.Pending, req.Amount)
 	if err != nil {
 		return c.JSON(http.StatusInternalServerError, dto.ErrorResponse{Message: "Failed to update booking status"})
 	}
 
 	rowsAffected, err := res.RowsAffected()
 	if err != nil || rowsAffected == 0 {
-		return c.JSON(http.StatusNotFound, dto.ErrorResponse{Message: "Booking not found or cannot refund"})
+		return c.JSON(http.StatusConflict, dto.ErrorResponse{Message: "Booking is no longer pending, its hold has expired or the amount paid does not match its price"})
 	}
 
 	return c.JSON(http.StatusOK, dto.SuccessResponse{Message: "Booking status updated successfully"})
@@ -409,18 +801,39 @@ func UpdateCheckinStatus(c echo.Context) error {
 	}
 
 	var currentStatus, currentCheckinStatus string
-	query := `SELECT status, checkin_status FROM bookings WHERE id = $1`
-	err := config.DB.QueryRow(query, req.BookingID).Scan(&currentStatus, &currentCheckinStatus)
+	var roomID, hotelID int
+	query := `
+		SELECT b.status, b.checkin_status, b.room_id, r.hotel_id
+		FROM bookings b
+		JOIN rooms r ON r.id = b.room_id
+		WHERE b.id = $1
+	`
+	err := config.DB.QueryRow(query, req.BookingID).Scan(&currentStatus, &currentCheckinStatus, &roomID, &hotelID)
 	if err == sql.ErrNoRows {
 		return c.JSON(http.StatusNotFound, dto.ErrorResponse{Message: "Booking not found"})
 	} else if err != nil {
 		return c.JSON(http.StatusInternalServerError, dto.ErrorResponse{Message: "Failed to retrieve booking status"})
 	}
 
+	if !canManageHotel(c, hotelID) {
+		return forbiddenHotel(c)
+	}
+
 	if req.CheckinStatus == "checked_in" {
 		if currentStatus != "confirmed" || currentCheckinStatus != "not_checked_in" {
 			return c.JSON(http.StatusBadRequest, dto.ErrorResponse{Message: "Booking must be confirmed and not checked in to proceed with check-in"})
 		}
+
+		// Guests have to be registered with an identity document before
+		// they can check in.
+		var documented bool
+		documentedQuery := `SELECT EXISTS (SELECT 1 FROM guests WHERE booking_id = $1 AND document_number IS NOT NULL)`
+		if err := config.DB.QueryRow(documentedQuery, req.BookingID).Scan(&documented); err != nil {
+			return c.JSON(http.StatusInternalServerError, dto.ErrorResponse{Message: "Failed to retrieve guests"})
+		}
+		if !documented {
+			return c.JSON(http.StatusBadRequest, dto.ErrorResponse{Message: "Register at least one guest with an identity document before check-in"})
+		}
 	} else if req.CheckinStatus == "checked_out" {
 		if currentCheckinStatus != "checked_in" {
 			return c.JSON(http.StatusBadRequest, dto.ErrorResponse{Message: "Booking must be checked in to proceed with check-out"})
@@ -442,6 +855,17 @@ func UpdateCheckinStatus(c echo.Context) error {
 		return c.JSON(http.StatusNotFound, dto.ErrorResponse{Message: "Booking not found or no change in status"})
 	}
 
+	roomStatus := model.Occupied
+	if req.CheckinStatus == "checked_out" {
+		roomStatus = model.Available
+	}
+
+	updateRoomQuery := `UPDATE rooms SET status = $1, updated_at = NOW() WHERE id = $2 AND status <> $3`
+	_, err = config.DB.Exec(updateRoomQuery, roomStatus, roomID, model.Maintenance)
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, dto.ErrorResponse{Message: "Failed to update room status"})
+	}
+
 	return c.JSON(http.StatusOK, dto.SuccessResponse{Message: "Check-in status updated successfully"})
 }
 
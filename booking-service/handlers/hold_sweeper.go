@@ -0,0 +1,153 @@
+package handler
+
+import (
+	"booking-service/config"
+	model "booking-service/models"
+	"log"
+	"time"
+)
+
+// StartHoldSweeper periodically cancels pending bookings and reservations
+// whose hold expired before payment arrived, which frees their rooms, and
+// expires the matching pending payments in payment-service. Booking changes
+// whose extra charge was not paid in time are expired the same way.
+func StartHoldSweeper(interval time.Duration) {
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+
+		for range ticker.C {
+			sweepExpiredHolds()
+			sweepExpiredReservations()
+			sweepExpiredChanges()
+		}
+	}()
+}
+
+func sweepExpiredHolds() {
+	query := `
+		UPDATE bookings SET status = $1, updated_at = NOW()
+		WHERE status = $2 AND expires_at <= NOW()
+		RETURNING id
+	`
+
+	rows, err := config.DB.Query(query, model.Canceled, model.Pending)
+	if err != nil {
+		log.Println("Error canceling expired booking holds:", err)
+		return
+	}
+	defer rows.Close()
+
+	var bookingIDs []int
+	for rows.Next() {
+		var bookingID int
+		if err := rows.Scan(&bookingID); err != nil {
+			log.Println("Error scanning expired booking:", err)
+			return
+		}
+		bookingIDs = append(bookingIDs, bookingID)
+	}
+
+	if err := rows.Err(); err != nil {
+		log.Println("Error canceling expired booking holds:", err)
+		return
+	}
+
+	// A payment that misses this notification is still refused when its
+	// callback arrives, since the booking can no longer be confirmed.
+	for _, bookingID := range bookingIDs {
+		if err := expirePendingPayments(bookingID); err != nil {
+			log.Printf("Failed to expire payments of booking %d: %v\n", bookingID, err)
+		}
+	}
+
+	if len(bookingIDs) > 0 {
+		log.Printf("Released %d expired booking holds\n", len(bookingIDs))
+	}
+}
+
+// sweepExpiredReservations cancels expired pending reservations. Their
+// lines carry the same expiry and are released by sweepExpiredHolds.
+func sweepExpiredReservations() {
+	query := `
+		UPDATE reservations SET status = $1, updated_at = NOW()
+		WHERE status = $2 AND expires_at <= NOW()
+		RETURNING id
+	`
+
+	rows, err := config.DB.Query(query, model.Canceled, model.Pending)
+	if err != nil {
+		log.Println("Error canceling expired reservations:", err)
+		return
+	}
+	defer rows.Close()
+
+	var reservationIDs []int
+	for rows.Next() {
+		var reservationID int
+		if err := rows.Scan(&reservationID); err != nil {
+			log.Println("Error scanning expired reservation:", err)
+			return
+		}
+		reservationIDs = append(reservationIDs, reservationID)
+	}
+
+	if err := rows.Err(); err != nil {
+		log.Println("Error canceling expired reservations:", err)
+		return
+	}
+
+	for _, reservationID := range reservationIDs {
+		if err := expireReservationPayments(reservationID); err != nil {
+			log.Printf("Failed to expire payments of reservation %d: %v\n", reservationID, err)
+		}
+	}
+
+	if len(reservationIDs) > 0 {
+		log.Printf("Released %d expired reservations\n", len(reservationIDs))
+	}
+}
+
+// sweepExpiredChanges expires booking changes whose extra charge was not
+// paid before their hold ran out. The bookings keep their previous stay.
+func sweepExpiredChanges() {
+	query := `
+		UPDATE booking_changes SET adjustment_status = 'expired'
+		WHERE adjustment_status = $1 AND expires_at <= NOW()
+		RETURNING booking_id
+	`
+
+	rows, err := config.DB.Query(query, awaitingPayment)
+	if err != nil {
+		log.Println("Error expiring unpaid booking changes:", err)
+		return
+	}
+	defer rows.Close()
+
+	var bookingIDs []int
+	for rows.Next() {
+		var bookingID int
+		if err := rows.Scan(&bookingID); err != nil {
+			log.Println("Error scanning expired booking change:", err)
+			return
+		}
+		bookingIDs = append(bookingIDs, bookingID)
+	}
+
+	if err := rows.Err(); err != nil {
+		log.Println("Error expiring unpaid booking changes:", err)
+		return
+	}
+
+	// The bookings are paid, so their only pending payments are the
+	// charges of these changes.
+	for _, bookingID := range bookingIDs {
+		if err := expirePendingPayments(bookingID); err != nil {
+			log.Printf("Failed to expire the change payment of booking %d: %v\n", bookingID, err)
+		}
+	}
+
+	if len(bookingIDs) > 0 {
+		log.Printf("Expired %d unpaid booking changes\n", len(bookingIDs))
+	}
+}
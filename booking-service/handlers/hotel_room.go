@@ -0,0 +1,316 @@
+package handler
+
+import (
+	"booking-service/config"
+	"booking-service/dto"
+	model "booking-service/models"
+	"database/sql"
+	"fmt"
+	"log"
+	"net/http"
+
+	"github.com/labstack/echo/v4"
+)
+
+// hasUpcomingBookings reports whether a room matched by roomFilter, an SQL
+// condition on rooms r bound to $1, still has a booking whose stay is not
+// over. Expired holds and bookings that are canceled or given up for a
+// refund do not count, the same as in roomBookedClause.
+func hasUpcomingBookings(db dbExecutor, roomFilter string, id interface{}) (bool, error) {
+	query := fmt.Sprintf(`
+		SELECT EXISTS (
+			SELECT 1 FROM bookings b
+			JOIN rooms r ON r.id = b.room_id
+			WHERE %s
+			AND b.checkout_date > CURRENT_DATE
+			AND b.status NOT IN ('%s', '%s')
+			AND b.checkin_status <> '%s'
+			AND NOT (b.status = '%s' AND b.expires_at <= NOW())
+		)`, roomFilter, model.Canceled, model.Refund, model.CheckedOut, model.Pending)
+
+	var exists bool
+	err := db.QueryRow(query, id).Scan(&exists)
+	return exists, err
+}
+
+func UpdateHotel(c echo.Context) error {
+	return updateHotel(c, false)
+}
+
+func PatchHotel(c echo.Context) error {
+	return updateHotel(c, true)
+}
+
+func updateHotel(c echo.Context, partial bool) error {
+	hotelID := c.Param("id")
+
+	var req dto.UpdateHotelRequest
+
+	if err := c.Bind(&req); err != nil {
+		return c.JSON(http.StatusBadRequest, dto.ErrorResponse{Message: "Invalid request"})
+	}
+
+	if !partial && (req.Name == nil || req.Address == nil) {
+		return c.JSON(http.StatusBadRequest, dto.ErrorResponse{Message: "name and address are required"})
+	}
+	if (req.Name != nil && *req.Name == "") || (req.Address != nil && *req.Address == "") {
+		return c.JSON(http.StatusBadRequest, dto.ErrorResponse{Message: "name and address must not be empty"})
+	}
+
+	if err := validateHotelPolicy(req.HoldTTLMinutes, req.FreeCancellationDays, req.CancellationPenaltyPercent); err != nil {
+		return c.JSON(http.StatusBadRequest, dto.ErrorResponse{Message: err.Error()})
+	}
+
+	var hotel model.Hotel
+	query := `SELECT ` + hotelColumns + ` FROM hotels h WHERE h.id = $1 AND h.deleted_at IS NULL`
+	err := config.DB.QueryRow(query, hotelID).Scan(hotelFields(&hotel)...)
+	if err == sql.ErrNoRows {
+		return c.JSON(http.StatusNotFound, dto.ErrorResponse{Message: "Hotel not found"})
+	} else if err != nil {
+		return c.JSON(http.StatusInternalServerError, dto.ErrorResponse{Message: "Failed to retrieve hotel"})
+	}
+
+	if !canManageHotel(c, hotel.HotelID) {
+		return forbiddenHotel(c)
+	}
+
+	if !partial {
+		hotel = model.Hotel{
+			HotelID:                    hotel.HotelID,
+			HoldTTLMinutes:             defaultHoldTTLMinutes,
+			FreeCancellationDays:       defaultFreeCancellationDays,
+			CancellationPenaltyPercent: defaultCancellationPenaltyPercent,
+		}
+	}
+
+	setString := func(dst *string, src *string) {
+		if src != nil {
+			*dst = *src
+		}
+	}
+	setString(&hotel.Name, req.Name)
+	setString(&hotel.Address, req.Address)
+	setString(&hotel.City, req.City)
+	setString(&hotel.Country, req.Country)
+	setString(&hotel.PhoneNumber, req.PhoneNumber)
+	setString(&hotel.Email, req.Email)
+	if req.HoldTTLMinutes != nil {
+		hotel.HoldTTLMinutes = *req.HoldTTLMinutes
+	}
+	if req.FreeCancellationDays != nil {
+		hotel.FreeCancellationDays = *req.FreeCancellationDays
+	}
+	if req.CancellationPenaltyPercent != nil {
+		hotel.CancellationPenaltyPercent = *req.CancellationPenaltyPercent
+	}
+
+	updateQuery := `
+		UPDATE hotels h
+		SET name = $1, address = $2, city = $3, country = $4, phone_number = $5, email = $6, hold_ttl_minutes = $7,
+			free_cancellation_days = $8, cancellation_penalty_percent = $9, updated_at = NOW()
+		WHERE h.id = $10 AND h.deleted_at IS NULL
+		RETURNING ` + hotelColumns
+	err = config.DB.QueryRow(updateQuery, hotel.Name, hotel.Address, hotel.City, hotel.Country, hotel.PhoneNumber, hotel.Email,
+		hotel.HoldTTLMinutes, hotel.FreeCancellationDays, hotel.CancellationPenaltyPercent, hotel.HotelID).Scan(hotelFields(&hotel)...)
+	if err == sql.ErrNoRows {
+		return c.JSON(http.StatusNotFound, dto.ErrorResponse{Message: "Hotel not found"})
+	} else if err != nil {
+		log.Println("Error updating hotel:", err)
+		return c.JSON(http.StatusInternalServerError, dto.ErrorResponse{Message: "Failed to update hotel"})
+	}
+
+	return c.JSON(http.StatusOK, hotel)
+}
+
+// DeleteHotel retires a hotel together with its rooms. Bookings keep
+// pointing at them, so the rows are only marked as deleted.
+func DeleteHotel(c echo.Context) error {
+	hotelID := c.Param("id")
+
+	if !isGlobalStaff(c) {
+		return c.JSON(http.StatusForbidden, dto.ErrorResponse{Message: "Only administrators can delete hotels"})
+	}
+
+	tx, err := config.DB.Begin()
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, dto.ErrorResponse{Message: "Failed to start transaction"})
+	}
+	defer tx.Rollback()
+
+	var lockedHotelID int
+	err = tx.QueryRow(`SELECT id FROM hotels WHERE id = $1 AND deleted_at IS NULL FOR UPDATE`, hotelID).Scan(&lockedHotelID)
+	if err == sql.ErrNoRows {
+		return c.JSON(http.StatusNotFound, dto.ErrorResponse{Message: "Hotel not found"})
+	} else if err != nil {
+		return c.JSON(http.StatusInternalServerError, dto.ErrorResponse{Message: "Failed to retrieve hotel"})
+	}
+
+	// Lock the rooms as well so no booking is created while we check.
+	if _, err := tx.Exec(`SELECT id FROM rooms WHERE hotel_id = $1 ORDER BY id FOR UPDATE`, lockedHotelID); err != nil {
+		return c.JSON(http.StatusInternalServerError, dto.ErrorResponse{Message: "Failed to check hotel bookings"})
+	}
+
+	upcoming, err := hasUpcomingBookings(tx, "r.hotel_id = $1", lockedHotelID)
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, dto.ErrorResponse{Message: "Failed to check hotel bookings"})
+	}
+	if upcoming {
+		return c.JSON(http.StatusConflict, dto.ErrorResponse{Message: "Hotel has upcoming bookings and cannot be deleted"})
+	}
+
+	if _, err := tx.Exec(`UPDATE rooms SET deleted_at = NOW(), updated_at = NOW() WHERE hotel_id = $1 AND deleted_at IS NULL`, lockedHotelID); err != nil {
+		return c.JSON(http.StatusInternalServerError, dto.ErrorResponse{Message: "Failed to delete hotel"})
+	}
+	if _, err := tx.Exec(`UPDATE hotels SET deleted_at = NOW(), updated_at = NOW() WHERE id = $1`, lockedHotelID); err != nil {
+		return c.JSON(http.StatusInternalServerError, dto.ErrorResponse{Message: "Failed to delete hotel"})
+	}
+
+	if err := tx.Commit(); err != nil {
+		return c.JSON(http.StatusInternalServerError, dto.ErrorResponse{Message: "Failed to delete hotel"})
+	}
+
+	return c.JSON(http.StatusOK, dto.SuccessResponse{Message: "Hotel deleted successfully"})
+}
+
+func UpdateRoom(c echo.Context) error {
+	return updateRoom(c, false)
+}
+
+func PatchRoom(c echo.Context) error {
+	return updateRoom(c, true)
+}
+
+func updateRoom(c echo.Context, partial bool) error {
+	roomID := c.Param("id")
+
+	var req dto.UpdateRoomRequest
+
+	if err := c.Bind(&req); err != nil {
+		return c.JSON(http.StatusBadRequest, dto.ErrorResponse{Message: "Invalid request"})
+	}
+
+	if !partial && (req.RoomNumber == nil || req.RoomType == nil || req.PricePerNight == nil) {
+		return c.JSON(http.StatusBadRequest, dto.ErrorResponse{Message: "room_number, room_type and price_per_night are required"})
+	}
+	if req.RoomNumber != nil && *req.RoomNumber == "" {
+		return c.JSON(http.StatusBadRequest, dto.ErrorResponse{Message: "room_number must not be empty"})
+	}
+	if req.PricePerNight != nil && *req.PricePerNight <= 0 {
+		return c.JSON(http.StatusBadRequest, dto.ErrorResponse{Message: "price_per_night must be greater than zero"})
+	}
+	if req.Status != nil && !isValidRoomStatus(model.RoomStatus(*req.Status)) {
+		return c.JSON(http.StatusBadRequest, dto.ErrorResponse{Message: "status must be one of 'available', 'occupied' or 'maintenance'"})
+	}
+
+	var room model.Room
+	query := `SELECT ` + roomColumns + ` FROM rooms r WHERE r.id = $1 AND r.deleted_at IS NULL`
+	err := config.DB.QueryRow(query, roomID).Scan(roomFields(&room)...)
+	if err == sql.ErrNoRows {
+		return c.JSON(http.StatusNotFound, dto.ErrorResponse{Message: "Room not found"})
+	} else if err != nil {
+		return c.JSON(http.StatusInternalServerError, dto.ErrorResponse{Message: "Failed to retrieve room"})
+	}
+
+	if !canManageHotel(c, room.HotelID) {
+		return forbiddenHotel(c)
+	}
+
+	// A full update without capacity falls back to the defaults of the
+	// room type, like a new room.
+	current := roomOccupancy{maxAdults: room.MaxAdults, maxChildren: room.MaxChildren, beds: room.BedConfiguration}
+	if !partial {
+		room = model.Room{RoomID: room.RoomID, HotelID: room.HotelID, Status: model.Available}
+	}
+
+	if req.RoomNumber != nil {
+		room.RoomNumber = *req.RoomNumber
+	}
+	if req.RoomType != nil {
+		room.RoomType = model.RoomType(*req.RoomType)
+	}
+	if req.PricePerNight != nil {
+		room.PricePerNight = *req.PricePerNight
+	}
+	if req.Description != nil {
+		room.Description = *req.Description
+	}
+	if req.Status != nil {
+		room.Status = model.RoomStatus(*req.Status)
+	}
+
+	if !partial {
+		current = defaultOccupancy(room.RoomType)
+	}
+	occupancy := occupancyFromRequest(current, req.MaxAdults, req.MaxChildren, req.BedConfiguration)
+	if err := validateOccupancy(occupancy); err != nil {
+		return c.JSON(http.StatusBadRequest, dto.ErrorResponse{Message: err.Error()})
+	}
+
+	var existingRoomID int
+	roomCheckQuery := `SELECT id FROM rooms WHERE hotel_id = $1 AND room_number = $2 AND id <> $3 AND deleted_at IS NULL`
+	err = config.DB.QueryRow(roomCheckQuery, room.HotelID, room.RoomNumber, room.RoomID).Scan(&existingRoomID)
+	if err == nil {
+		return c.JSON(http.StatusBadRequest, dto.ErrorResponse{Message: "room_number already exists"})
+	} else if err != sql.ErrNoRows {
+		return c.JSON(http.StatusInternalServerError, dto.ErrorResponse{Message: "Failed to check room number"})
+	}
+
+	updateQuery := `
+		UPDATE rooms r
+		SET room_number = $1, room_type = $2, price_per_night = $3, description = $4, status = $5,
+			max_adults = $6, max_children = $7, bed_configuration = $8, updated_at = NOW()
+		WHERE r.id = $9 AND r.deleted_at IS NULL
+		RETURNING ` + roomColumns
+	err = config.DB.QueryRow(updateQuery, room.RoomNumber, room.RoomType, room.PricePerNight, room.Description, room.Status,
+		occupancy.maxAdults, occupancy.maxChildren, occupancy.beds, room.RoomID).Scan(roomFields(&room)...)
+	if err == sql.ErrNoRows {
+		return c.JSON(http.StatusNotFound, dto.ErrorResponse{Message: "Room not found"})
+	} else if err != nil {
+		log.Println("Error updating room:", err)
+		return c.JSON(http.StatusInternalServerError, dto.ErrorResponse{Message: "Failed to update room"})
+	}
+
+	return c.JSON(http.StatusOK, room)
+}
+
+// DeleteRoom retires a room. It is refused while the room still has
+// upcoming bookings; past bookings keep resolving the retired room.
+func DeleteRoom(c echo.Context) error {
+	roomID := c.Param("id")
+
+	tx, err := config.DB.Begin()
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, dto.ErrorResponse{Message: "Failed to start transaction"})
+	}
+	defer tx.Rollback()
+
+	var lockedRoomID, hotelID int
+	err = tx.QueryRow(`SELECT id, hotel_id FROM rooms WHERE id = $1 AND deleted_at IS NULL FOR UPDATE`, roomID).Scan(&lockedRoomID, &hotelID)
+	if err == sql.ErrNoRows {
+		return c.JSON(http.StatusNotFound, dto.ErrorResponse{Message: "Room not found"})
+	} else if err != nil {
+		return c.JSON(http.StatusInternalServerError, dto.ErrorResponse{Message: "Failed to retrieve room"})
+	}
+
+	if !canManageHotel(c, hotelID) {
+		return forbiddenHotel(c)
+	}
+
+	upcoming, err := hasUpcomingBookings(tx, "r.id = $1", lockedRoomID)
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, dto.ErrorResponse{Message: "Failed to check room bookings"})
+	}
+	if upcoming {
+		return c.JSON(http.StatusConflict, dto.ErrorResponse{Message: "Room has upcoming bookings and cannot be deleted"})
+	}
+
+	if _, err := tx.Exec(`UPDATE rooms SET deleted_at = NOW(), updated_at = NOW() WHERE id = $1`, lockedRoomID); err != nil {
+		return c.JSON(http.StatusInternalServerError, dto.ErrorResponse{Message: "Failed to delete room"})
+	}
+
+	if err := tx.Commit(); err != nil {
+		return c.JSON(http.StatusInternalServerError, dto.ErrorResponse{Message: "Failed to delete room"})
+	}
+
+	return c.JSON(http.StatusOK, dto.SuccessResponse{Message: "Room deleted successfully"})
+}
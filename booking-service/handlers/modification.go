@@ -0,0 +1,425 @@
+package handler
+
+import (
+	"booking-service/config"
+	"booking-service/dto"
+	model "booking-service/models"
+	"database/sql"
+	"log"
+	"net/http"
+	"time"
+
+	"github.com/labstack/echo/v4"
+)
+
+const bookingChangeColumns = `id, booking_id, user_id, previous_room_id, to_char(previous_checkin_date, 'YYYY-MM-DD'),
+	to_char(previous_checkout_date, 'YYYY-MM-DD'), previous_total_price, new_room_id, to_char(new_checkin_date, 'YYYY-MM-DD'),
+	to_char(new_checkout_date, 'YYYY-MM-DD'), new_total_price, price_difference, adjustment, adjustment_status,
+	payment_uid, refund_id, expires_at, created_at`
+
+// awaitingPayment is the adjustment status of a change that is only
+// applied once its extra charge is paid.
+const awaitingPayment = "awaiting_payment"
+
+func scanBookingChange(row rowScanner) (model.BookingChange, error) {
+	var change model.BookingChange
+	err := row.Scan(
+		&change.ChangeID,
+		&change.BookingID,
+		&change.UserID,
+		&change.PreviousRoomID,
+		&change.PreviousCheckinDate,
+		&change.PreviousCheckoutDate,
+		&change.PreviousTotalPrice,
+		&change.NewRoomID,
+		&change.NewCheckinDate,
+		&change.NewCheckoutDate,
+		&change.NewTotalPrice,
+		&change.PriceDifference,
+		&change.Adjustment,
+		&change.AdjustmentStatus,
+		&change.PaymentUID,
+		&change.RefundID,
+		&change.ExpiresAt,
+		&change.CreatedAt,
+	)
+	return change, err
+}
+
+// ModifyBooking moves a booking to other dates or another room of the same
+// hotel. The stay is repriced and, for bookings that are already paid, the
+// difference is sent to payment-service as an additional charge or a
+// partial refund. A change that costs more is only applied once the charge
+// is paid; until then it holds the new room and dates for the hotel's hold
+// time. Every change is kept in booking_changes.
+func ModifyBooking(c echo.Context) error {
+	bookingID := c.Param("booking_id")
+
+	var req dto.ModifyBookingRequest
+
+	if err := c.Bind(&req); err != nil {
+		return c.JSON(http.StatusBadRequest, dto.ErrorResponse{Message: "Invalid request"})
+	}
+
+	if req.UserID == nil {
+		return c.JSON(http.StatusBadRequest, dto.ErrorResponse{Message: "User ID is required"})
+	}
+
+	if req.RoomID == nil && req.CheckinDate == nil && req.CheckoutDate == nil {
+		return c.JSON(http.StatusBadRequest, dto.ErrorResponse{Message: "At least one of room_id, checkin_date or checkout_date is required"})
+	}
+
+	tx, err := config.DB.Begin()
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, dto.ErrorResponse{Message: "Failed to start transaction"})
+	}
+	defer tx.Rollback()
+
+	var booking model.Booking
+	var previousCheckin, previousCheckout time.Time
+	var hotelID, holdTTLMinutes int
+	var holdExpired bool
+	query := `
+		SELECT b.id, b.user_id, b.room_id, b.checkin_date, b.checkout_date, b.total_price, b.status, b.checkin_status,
+			b.reservation_id, b.adults, b.children, COALESCE(b.expires_at <= NOW(), FALSE), r.hotel_id, h.hold_ttl_minutes
+		FROM bookings b
+		JOIN rooms r ON r.id = b.room_id
+		JOIN hotels h ON h.id = r.hotel_id
+		WHERE b.id = $1
+		FOR UPDATE OF b
+	`
+	err = tx.QueryRow(query, bookingID).Scan(
+		&booking.BookingID,
+		&booking.UserID,
+		&booking.RoomID,
+		&previousCheckin,
+		&previousCheckout,
+		&booking.TotalPrice,
+		&booking.Status,
+		&booking.CheckinStatus,
+		&booking.ReservationID,
+		&booking.Adults,
+		&booking.Children,
+		&holdExpired,
+		&hotelID,
+		&holdTTLMinutes,
+	)
+	if err == sql.ErrNoRows || (err == nil && booking.UserID != *req.UserID) {
+		return c.JSON(http.StatusNotFound, dto.ErrorResponse{Message: "Booking not found"})
+	} else if err != nil {
+		return c.JSON(http.StatusInternalServerError, dto.ErrorResponse{Message: "Failed to retrieve booking"})
+	}
+
+	if booking.Status != model.Pending && booking.Status != model.Confirmed {
+		return c.JSON(http.StatusBadRequest, dto.ErrorResponse{Message: "Only pending or confirmed bookings can be modified"})
+	}
+
+	if booking.Status == model.Pending && holdExpired {
+		return c.JSON(http.StatusConflict, dto.ErrorResponse{Message: "Booking hold has expired"})
+	}
+
+	if booking.CheckinStatus != model.NotCheckedIn {
+		return c.JSON(http.StatusBadRequest, dto.ErrorResponse{Message: "Booking has already been checked in"})
+	}
+
+	var awaiting bool
+	awaitingQuery := `
+		SELECT EXISTS (
+			SELECT 1 FROM booking_changes WHERE booking_id = $1 AND adjustment_status = $2 AND expires_at > NOW()
+		)
+	`
+	if err := tx.QueryRow(awaitingQuery, booking.BookingID, awaitingPayment).Scan(&awaiting); err != nil {
+		return c.JSON(http.StatusInternalServerError, dto.ErrorResponse{Message: "Failed to retrieve booking changes"})
+	}
+	if awaiting {
+		return c.JSON(http.StatusConflict, dto.ErrorResponse{Message: "A previous change of this booking is still awaiting payment"})
+	}
+
+	roomID := booking.RoomID
+	if req.RoomID != nil {
+		roomID = *req.RoomID
+	}
+	checkin := previousCheckin.Format(dateLayout)
+	if req.CheckinDate != nil {
+		checkin = *req.CheckinDate
+	}
+	checkout := previousCheckout.Format(dateLayout)
+	if req.CheckoutDate != nil {
+		checkout = *req.CheckoutDate
+	}
+
+	checkinDate, checkoutDate, err := parseStay(checkin, checkout)
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, dto.ErrorResponse{Message: err.Error()})
+	}
+
+	if roomID == booking.RoomID && checkinDate.Equal(previousCheckin) && checkoutDate.Equal(previousCheckout) {
+		return c.JSON(http.StatusBadRequest, dto.ErrorResponse{Message: "The booking already has the requested room and dates"})
+	}
+
+	// Same room lock as CreateBooking, so a modification and a new booking
+	// cannot both claim the same nights.
+	var roomStatus string
+	var pricePerNight float64
+	var roomHotelID, maxAdults, maxChildren int
+	checkRoomQuery := `SELECT status, price_per_night, hotel_id, max_adults, max_children FROM rooms WHERE id = $1 AND deleted_at IS NULL FOR UPDATE`
+	err = tx.QueryRow(checkRoomQuery, roomID).Scan(&roomStatus, &pricePerNight, &roomHotelID, &maxAdults, &maxChildren)
+	if err == sql.ErrNoRows {
+		return c.JSON(http.StatusBadRequest, dto.ErrorResponse{Message: "Room not found"})
+	} else if err != nil {
+		return c.JSON(http.StatusInternalServerError, dto.ErrorResponse{Message: "Failed to check room availability"})
+	}
+
+	if roomHotelID != hotelID {
+		return c.JSON(http.StatusBadRequest, dto.ErrorResponse{Message: "The new room must belong to the same hotel"})
+	}
+
+	if roomStatus == string(model.Maintenance) {
+		return c.JSON(http.StatusBadRequest, dto.ErrorResponse{Message: "Room is under maintenance"})
+	}
+
+	if !fitsRoom(booking.Adults, booking.Children, maxAdults, maxChildren) {
+		return c.JSON(http.StatusBadRequest, dto.ErrorResponse{Message: partyTooLarge(maxAdults, maxChildren)})
+	}
+
+	unavailable, err := isRoomUnavailable(tx, roomID, checkinDate, checkoutDate, booking.BookingID)
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, dto.ErrorResponse{Message: "Failed to check room availability"})
+	}
+	if unavailable {
+		return c.JSON(http.StatusBadRequest, dto.ErrorResponse{Message: "Room is not available for the selected dates"})
+	}
+
+	nightlyRates, totalPrice, err := calculateStayPrice(tx, roomID, pricePerNight, checkinDate, checkoutDate)
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, dto.ErrorResponse{Message: "Failed to calculate booking price"})
+	}
+
+	difference := roundMoney(totalPrice - booking.TotalPrice)
+
+	// Only paid bookings need money to move; a pending booking is simply
+	// paid at its new price. A paid booking that gets more expensive keeps
+	// its current stay until the difference is paid.
+	adjustment := model.NoAdjustment
+	adjustmentStatus := "not_required"
+	if booking.Status == model.Confirmed && difference > 0 {
+		adjustment, adjustmentStatus = model.ChargeAdjustment, awaitingPayment
+	} else if booking.Status == model.Confirmed && difference < 0 {
+		adjustment, adjustmentStatus = model.RefundAdjustment, "requested"
+	}
+
+	if adjustment != model.ChargeAdjustment {
+		if err := applyBookingChange(tx, booking.BookingID, booking.ReservationID, roomID, checkinDate, checkoutDate, totalPrice); err != nil {
+			log.Println("Error modifying booking:", err)
+			return c.JSON(http.StatusInternalServerError, dto.ErrorResponse{Message: "Failed to modify booking"})
+		}
+	}
+
+	// Only changes awaiting payment expire, so the others get no expiry.
+	var holdMinutes *int
+	if adjustment == model.ChargeAdjustment {
+		holdMinutes = &holdTTLMinutes
+	}
+
+	insertChangeQuery := `
+		INSERT INTO booking_changes (booking_id, user_id, previous_room_id, previous_checkin_date, previous_checkout_date,
+			previous_total_price, new_room_id, new_checkin_date, new_checkout_date, new_total_price, price_difference,
+			adjustment, adjustment_status, expires_at, created_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, NOW() + make_interval(mins => $14), NOW())
+		RETURNING id, expires_at
+	`
+	var changeID int
+	var expiresAt *time.Time
+	err = tx.QueryRow(insertChangeQuery, booking.BookingID, booking.UserID, booking.RoomID, previousCheckin, previousCheckout,
+		booking.TotalPrice, roomID, checkinDate, checkoutDate, totalPrice, difference, adjustment, adjustmentStatus,
+		holdMinutes).Scan(&changeID, &expiresAt)
+	if err != nil {
+		log.Println("Error recording booking change:", err)
+		return c.JSON(http.StatusInternalServerError, dto.ErrorResponse{Message: "Failed to modify booking"})
+	}
+
+	if err := tx.Commit(); err != nil {
+		return c.JSON(http.StatusInternalServerError, dto.ErrorResponse{Message: "Failed to modify booking"})
+	}
+
+	response := dto.ModifyBookingResponse{
+		BookingID:       booking.BookingID,
+		ChangeID:        changeID,
+		RoomID:          roomID,
+		CheckinDate:     checkin,
+		CheckoutDate:    checkout,
+		TotalPrice:      totalPrice,
+		NightlyRates:    nightlyRates,
+		PriceDifference: difference,
+		Adjustment:      adjustment,
+		Message:         "Booking modified successfully",
+	}
+
+	if booking.Status == model.Pending && difference != 0 {
+		// Payments opened for the old price must not confirm the booking.
+		if booking.ReservationID != nil {
+			err = expireReservationPayments(*booking.ReservationID)
+		} else {
+			err = expirePendingPayments(booking.BookingID)
+		}
+		if err != nil {
+			log.Printf("Failed to expire pending payments of modified booking %d: %v\n", booking.BookingID, err)
+		}
+	}
+
+	if adjustment == model.NoAdjustment {
+		return c.JSON(http.StatusOK, response)
+	}
+
+	result, err := requestPaymentAdjustment(booking.BookingID, booking.UserID, booking.ReservationID, difference)
+	if err != nil {
+		log.Printf("Failed to request payment adjustment for booking %d: %v\n", booking.BookingID, err)
+		adjustmentStatus = "failed"
+	}
+
+	updateChangeQuery := `UPDATE booking_changes SET adjustment_status = $1, payment_uid = $2, refund_id = $3 WHERE id = $4`
+	if _, err := config.DB.Exec(updateChangeQuery, adjustmentStatus, result.PaymentUID, result.RefundID, changeID); err != nil {
+		log.Printf("Failed to record payment adjustment of booking change %d: %v\n", changeID, err)
+	}
+
+	// Without a charge to pay, a change that costs more can never be
+	// applied, so it is dropped and the booking stays as it was.
+	if adjustment == model.ChargeAdjustment && adjustmentStatus == "failed" {
+		return c.JSON(http.StatusBadGateway, dto.ErrorResponse{Message: "The booking was not modified because the extra charge could not be created"})
+	}
+
+	response.PaymentUID = result.PaymentUID
+	response.RefundID = result.RefundID
+	if adjustment == model.ChargeAdjustment {
+		response.ExpiresAt = expiresAt
+		response.Message = "The booking will be modified once the price difference is paid"
+		return c.JSON(http.StatusAccepted, response)
+	}
+	if adjustmentStatus == "failed" {
+		response.Message = "Booking modified, but the payment adjustment could not be sent to the payment service"
+	}
+
+	return c.JSON(http.StatusOK, response)
+}
+
+// applyBookingChange moves a booking to its new room, dates and price.
+func applyBookingChange(tx *sql.Tx, bookingID int, reservationID *int, roomID int, checkin, checkout time.Time, totalPrice float64) error {
+	updateQuery := `
+		UPDATE bookings
+		SET room_id = $1, checkin_date = $2, checkout_date = $3, total_price = $4, updated_at = NOW()
+		WHERE id = $5
+	`
+	if _, err := tx.Exec(updateQuery, roomID, checkin, checkout, totalPrice, bookingID); err != nil {
+		return err
+	}
+
+	if reservationID != nil {
+		return refreshReservation(tx, *reservationID)
+	}
+	return nil
+}
+
+// ApplyBookingChangeHandler is called by payment-service once the extra
+// charge of a booking change is paid, and applies the change. It answers
+// 409 Conflict when the change can no longer be applied, for instance
+// because it expired or the booking was canceled in the meantime.
+func ApplyBookingChangeHandler(c echo.Context) error {
+	var req dto.BookingChangeCallbackRequest
+
+	if err := c.Bind(&req); err != nil {
+		return c.JSON(http.StatusBadRequest, dto.ErrorResponse{Message: "Invalid request"})
+	}
+
+	tx, err := config.DB.Begin()
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, dto.ErrorResponse{Message: "Failed to start transaction"})
+	}
+	defer tx.Rollback()
+
+	var changeID, bookingID, roomID int
+	var checkinDate, checkoutDate time.Time
+	var totalPrice, difference float64
+	changeQuery := `
+		SELECT id, booking_id, new_room_id, new_checkin_date, new_checkout_date, new_total_price, price_difference
+		FROM booking_changes
+		WHERE payment_uid = $1 AND adjustment_status = $2 AND expires_at > NOW()
+		FOR UPDATE
+	`
+	err = tx.QueryRow(changeQuery, req.PaymentUID, awaitingPayment).Scan(
+		&changeID, &bookingID, &roomID, &checkinDate, &checkoutDate, &totalPrice, &difference)
+	if err == sql.ErrNoRows {
+		return c.JSON(http.StatusConflict, dto.ErrorResponse{Message: "No booking change is awaiting this payment"})
+	} else if err != nil {
+		return c.JSON(http.StatusInternalServerError, dto.ErrorResponse{Message: "Failed to retrieve booking change"})
+	}
+
+	if !sameAmount(req.Amount, difference) {
+		return c.JSON(http.StatusConflict, dto.ErrorResponse{Message: "The amount paid does not match the price difference"})
+	}
+
+	var status model.BookingStatus
+	var checkinStatus model.CheckinStatus
+	var reservationID *int
+	bookingQuery := `SELECT status, checkin_status, reservation_id FROM bookings WHERE id = $1 FOR UPDATE`
+	if err := tx.QueryRow(bookingQuery, bookingID).Scan(&status, &checkinStatus, &reservationID); err != nil {
+		return c.JSON(http.StatusInternalServerError, dto.ErrorResponse{Message: "Failed to retrieve booking"})
+	}
+	if status != model.Confirmed || checkinStatus != model.NotCheckedIn {
+		return c.JSON(http.StatusConflict, dto.ErrorResponse{Message: "Booking can no longer be modified"})
+	}
+
+	// The change held the room, but a room block may have been added on
+	// top of it since.
+	if _, err := tx.Exec(`SELECT id FROM rooms WHERE id = $1 FOR UPDATE`, roomID); err != nil {
+		return c.JSON(http.StatusInternalServerError, dto.ErrorResponse{Message: "Failed to check room availability"})
+	}
+	unavailable, err := isRoomUnavailable(tx, roomID, checkinDate, checkoutDate, bookingID)
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, dto.ErrorResponse{Message: "Failed to check room availability"})
+	}
+	if unavailable {
+		return c.JSON(http.StatusConflict, dto.ErrorResponse{Message: "Room is no longer available for the new dates"})
+	}
+
+	if err := applyBookingChange(tx, bookingID, reservationID, roomID, checkinDate, checkoutDate, totalPrice); err != nil {
+		log.Println("Error applying booking change:", err)
+		return c.JSON(http.StatusInternalServerError, dto.ErrorResponse{Message: "Failed to modify booking"})
+	}
+
+	if _, err := tx.Exec(`UPDATE booking_changes SET adjustment_status = 'paid' WHERE id = $1`, changeID); err != nil {
+		return c.JSON(http.StatusInternalServerError, dto.ErrorResponse{Message: "Failed to modify booking"})
+	}
+
+	if err := tx.Commit(); err != nil {
+		return c.JSON(http.StatusInternalServerError, dto.ErrorResponse{Message: "Failed to modify booking"})
+	}
+
+	return c.JSON(http.StatusOK, dto.SuccessResponse{Message: "Booking modified successfully"})
+}
+
+func GetBookingChanges(c echo.Context) error {
+	bookingID := c.Param("booking_id")
+
+	query := `SELECT ` + bookingChangeColumns + ` FROM booking_changes WHERE booking_id = $1 ORDER BY id`
+
+	rows, err := config.DB.Query(query, bookingID)
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, dto.ErrorResponse{Message: "Failed to retrieve booking changes"})
+	}
+	defer rows.Close()
+
+	changes := []model.BookingChange{}
+	for rows.Next() {
+		change, err := scanBookingChange(rows)
+		if err != nil {
+			return c.JSON(http.StatusInternalServerError, dto.ErrorResponse{Message: "Failed to scan booking change data"})
+		}
+		changes = append(changes, change)
+	}
+
+	if err := rows.Err(); err != nil {
+		return c.JSON(http.StatusInternalServerError, dto.ErrorResponse{Message: "Error occurred during booking changes retrieval"})
+	}
+
+	return c.JSON(http.StatusOK, changes)
+}
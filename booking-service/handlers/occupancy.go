@@ -0,0 +1,127 @@
+package handler
+
+import (
+	model "booking-service/models"
+	"errors"
+	"fmt"
+	"strconv"
+
+	"github.com/labstack/echo/v4"
+)
+
+const maxBedsOfType = 10
+
+type roomOccupancy struct {
+	maxAdults   int
+	maxChildren int
+	beds        model.BedConfiguration
+}
+
+// roomTypeOccupancy is what a room takes when it is created without an
+// explicit capacity. Rooms of other types default to two adults.
+var roomTypeOccupancy = map[model.RoomType]roomOccupancy{
+	model.Single: {1, 0, model.BedConfiguration{{Type: model.SingleBed, Count: 1}}},
+	model.Double: {2, 1, model.BedConfiguration{{Type: model.DoubleBed, Count: 1}}},
+	model.Suite:  {2, 2, model.BedConfiguration{{Type: model.KingBed, Count: 1}, {Type: model.SofaBed, Count: 1}}},
+	model.Deluxe: {2, 1, model.BedConfiguration{{Type: model.KingBed, Count: 1}}},
+}
+
+func defaultOccupancy(roomType model.RoomType) roomOccupancy {
+	if occupancy, ok := roomTypeOccupancy[roomType]; ok {
+		return occupancy
+	}
+	return roomOccupancy{maxAdults: 2, beds: model.BedConfiguration{}}
+}
+
+func isValidBedType(bedType model.BedType) bool {
+	switch bedType {
+	case model.SingleBed, model.DoubleBed, model.QueenBed, model.KingBed, model.SofaBed, model.BunkBed, model.Crib:
+		return true
+	}
+	return false
+}
+
+// validateOccupancy checks the capacity of a room. The returned error is
+// suitable for sending back to the client.
+func validateOccupancy(occupancy roomOccupancy) error {
+	if occupancy.maxAdults < 1 {
+		return errors.New("max_adults must be at least 1")
+	}
+	if occupancy.maxChildren < 0 {
+		return errors.New("max_children must not be negative")
+	}
+
+	seen := map[model.BedType]bool{}
+	for _, bed := range occupancy.beds {
+		if !isValidBedType(bed.Type) {
+			return errors.New("bed type must be one of 'single', 'double', 'queen', 'king', 'sofa_bed', 'bunk_bed' or 'crib'")
+		}
+		if bed.Count < 1 || bed.Count > maxBedsOfType {
+			return fmt.Errorf("bed count must be between 1 and %d", maxBedsOfType)
+		}
+		if seen[bed.Type] {
+			return fmt.Errorf("bed type '%s' is listed more than once", bed.Type)
+		}
+		seen[bed.Type] = true
+	}
+
+	return nil
+}
+
+// fitsRoom reports whether a party fits a room. Children may take places
+// that adults leave free, but not the other way around.
+func fitsRoom(adults, children, maxAdults, maxChildren int) bool {
+	return adults <= maxAdults && adults+children <= maxAdults+maxChildren
+}
+
+func partyTooLarge(maxAdults, maxChildren int) string {
+	return fmt.Sprintf("The room fits at most %d adults and %d children", maxAdults, maxChildren)
+}
+
+// roomFitsClause returns an SQL condition that is true when the room
+// aliased r fits the party bound to the adultsArg and childrenArg
+// placeholders. It matches fitsRoom.
+func roomFitsClause(adultsArg, childrenArg int) string {
+	return fmt.Sprintf("(r.max_adults >= $%d AND r.max_adults + r.max_children >= $%d + $%d)", adultsArg, adultsArg, childrenArg)
+}
+
+// parsePartyQuery reads the adults and children query parameters used to
+// filter rooms. ok is false when neither is given.
+func parsePartyQuery(c echo.Context) (adults, children int, ok bool, err error) {
+	adultsParam, childrenParam := c.QueryParam("adults"), c.QueryParam("children")
+	if adultsParam == "" && childrenParam == "" {
+		return 0, 0, false, nil
+	}
+
+	adults = 1
+	if adultsParam != "" {
+		if adults, err = strconv.Atoi(adultsParam); err != nil || adults < 1 {
+			return 0, 0, false, errors.New("adults must be a positive integer")
+		}
+	}
+	if childrenParam != "" {
+		if children, err = strconv.Atoi(childrenParam); err != nil || children < 0 {
+			return 0, 0, false, errors.New("children must not be negative")
+		}
+	}
+
+	return adults, children, true, nil
+}
+
+// occupancyFromRequest applies the capacity fields of a room request on
+// top of current.
+func occupancyFromRequest(current roomOccupancy, maxAdults, maxChildren *int, beds *model.BedConfiguration) roomOccupancy {
+	if maxAdults != nil {
+		current.maxAdults = *maxAdults
+	}
+	if maxChildren != nil {
+		current.maxChildren = *maxChildren
+	}
+	if beds != nil {
+		current.beds = *beds
+	}
+	if current.beds == nil {
+		current.beds = model.BedConfiguration{}
+	}
+	return current
+}
@@ -0,0 +1,73 @@
+package handler
+
+import (
+	model "booking-service/models"
+	"reflect"
+	"testing"
+)
+
+func TestFitsRoom(t *testing.T) {
+	tests := []struct {
+		adults, children int
+		want             bool
+	}{
+		{2, 0, true},
+		{2, 1, true},
+		{1, 2, true}, // a child takes the free adult place
+		{3, 0, false},
+		{2, 2, false},
+	}
+
+	for _, tt := range tests {
+		if got := fitsRoom(tt.adults, tt.children, 2, 1); got != tt.want {
+			t.Errorf("fitsRoom(%d, %d, 2, 1) = %v, want %v", tt.adults, tt.children, got, tt.want)
+		}
+	}
+}
+
+func TestValidateOccupancy(t *testing.T) {
+	king := model.Bed{Type: model.KingBed, Count: 1}
+
+	tests := []struct {
+		name      string
+		occupancy roomOccupancy
+		wantErr   bool
+	}{
+		{name: "room type default", occupancy: defaultOccupancy(model.Suite)},
+		{name: "unknown room type default", occupancy: defaultOccupancy("loft")},
+		{name: "no adults", occupancy: roomOccupancy{maxAdults: 0}, wantErr: true},
+		{name: "negative children", occupancy: roomOccupancy{maxAdults: 2, maxChildren: -1}, wantErr: true},
+		{name: "unknown bed", occupancy: roomOccupancy{maxAdults: 2, beds: model.BedConfiguration{{Type: "hammock", Count: 1}}}, wantErr: true},
+		{name: "no beds of a type", occupancy: roomOccupancy{maxAdults: 2, beds: model.BedConfiguration{{Type: model.KingBed}}}, wantErr: true},
+		{name: "bed type twice", occupancy: roomOccupancy{maxAdults: 2, beds: model.BedConfiguration{king, king}}, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := validateOccupancy(tt.occupancy); (err != nil) != tt.wantErr {
+				t.Fatalf("validateOccupancy() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestBedConfigurationRoundTrip(t *testing.T) {
+	beds := model.BedConfiguration{{Type: model.QueenBed, Count: 2}, {Type: model.Crib, Count: 1}}
+
+	value, err := beds.Value()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var scanned model.BedConfiguration
+	if err := scanned.Scan(value); err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(scanned, beds) {
+		t.Fatalf("scanned %v, want %v", scanned, beds)
+	}
+
+	if value, _ := model.BedConfiguration(nil).Value(); string(value.([]byte)) != "[]" {
+		t.Fatalf("nil configuration stored as %s, want []", value)
+	}
+}
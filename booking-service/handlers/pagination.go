@@ -0,0 +1,129 @@
+package handler
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/labstack/echo/v4"
+)
+
+const (
+	defaultPageLimit = 20
+	maxPageLimit     = 100
+)
+
+// sortOption is a column a list endpoint can be sorted by. cast is the SQL
+// type the cursor value is converted back to when comparing.
+type sortOption struct {
+	expr string
+	cast string
+}
+
+// pageCursor marks the last row of a page. It is handed to clients as an
+// opaque base64 string.
+type pageCursor struct {
+	Sort  string `json:"s"`
+	Value string `json:"v"`
+	ID    int    `json:"id"`
+}
+
+// pageQuery holds the limit, sort and cursor of a list request. Results are
+// always ordered by the sort column and then by ID, which makes the order
+// total and lets the next page continue right after the cursor.
+type pageQuery struct {
+	limit  int
+	sort   string
+	option sortOption
+	desc   bool
+	cursor *pageCursor
+}
+
+// parsePageQuery reads the limit, sort and cursor query parameters. sort
+// names one of the given options and is prefixed with '-' for descending
+// order. The returned error is suitable for sending back to the client.
+func parsePageQuery(c echo.Context, options map[string]sortOption, defaultSort string) (pageQuery, error) {
+	page := pageQuery{limit: defaultPageLimit, sort: defaultSort}
+
+	if value := c.QueryParam("limit"); value != "" {
+		limit, err := strconv.Atoi(value)
+		if err != nil || limit <= 0 {
+			return page, errors.New("limit must be a positive integer")
+		}
+		if limit > maxPageLimit {
+			limit = maxPageLimit
+		}
+		page.limit = limit
+	}
+
+	if value := c.QueryParam("sort"); value != "" {
+		page.sort = value
+	}
+
+	name := strings.TrimPrefix(page.sort, "-")
+	option, ok := options[name]
+	if !ok {
+		names := make([]string, 0, len(options))
+		for name := range options {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		return page, fmt.Errorf("sort must be one of: %s", strings.Join(names, ", "))
+	}
+	page.option = option
+	page.desc = strings.HasPrefix(page.sort, "-")
+
+	if value := c.QueryParam("cursor"); value != "" {
+		raw, err := base64.RawURLEncoding.DecodeString(value)
+		if err != nil {
+			return page, errors.New("Invalid cursor")
+		}
+		var cursor pageCursor
+		if err := json.Unmarshal(raw, &cursor); err != nil {
+			return page, errors.New("Invalid cursor")
+		}
+		if cursor.Sort != page.sort {
+			return page, errors.New("cursor was issued for a different sort")
+		}
+		page.cursor = &cursor
+	}
+
+	return page, nil
+}
+
+// sortKey is the select expression that yields the cursor value of a row.
+// It must be the last column of the query.
+func (p pageQuery) sortKey() string {
+	return p.option.expr + "::text"
+}
+
+// apply adds the cursor condition, ordering and limit to a query that
+// already has a WHERE clause. One extra row is fetched to tell whether a
+// next page exists.
+func (p pageQuery) apply(query string, args []interface{}, idColumn string) (string, []interface{}) {
+	direction, comparison := "ASC", ">"
+	if p.desc {
+		direction, comparison = "DESC", "<"
+	}
+
+	if p.cursor != nil {
+		args = append(args, p.cursor.Value, p.cursor.ID)
+		query += fmt.Sprintf(" AND (%s, %s) %s ($%d::%s, $%d)",
+			p.option.expr, idColumn, comparison, len(args)-1, p.option.cast, len(args))
+	}
+
+	query += fmt.Sprintf(" ORDER BY %s %s, %s %s LIMIT %d", p.option.expr, direction, idColumn, direction, p.limit+1)
+	return query, args
+}
+
+// cursorAfter returns the cursor for the page that starts after the row
+// with the given sort key and ID.
+func (p pageQuery) cursorAfter(key string, id int) *string {
+	raw, _ := json.Marshal(pageCursor{Sort: p.sort, Value: key, ID: id})
+	cursor := base64.RawURLEncoding.EncodeToString(raw)
+	return &cursor
+}
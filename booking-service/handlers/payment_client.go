@@ -0,0 +1,79 @@
+package handler
+
+import (
+	"booking-service/dto"
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+)
+
+// postToPaymentService sends a JSON request to payment-service and fails
+// unless it answers with 200 OK. When result is not nil the response body
+// is decoded into it.
+func postToPaymentService(path string, body, result interface{}) error {
+	paymentServiceURL := os.Getenv("PAYMENT_SERVICE_URL")
+	if paymentServiceURL == "" {
+		return errors.New("payment service URL is not configured")
+	}
+
+	jsonBody, err := json.Marshal(body)
+	if err != nil {
+		return err
+	}
+
+	resp, err := http.Post(paymentServiceURL+path, "application/json", bytes.NewBuffer(jsonBody))
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("payment service responded with status %d", resp.StatusCode)
+	}
+
+	if result != nil {
+		return json.NewDecoder(resp.Body).Decode(result)
+	}
+
+	return nil
+}
+
+// expirePendingPayments marks the pending payments of a booking as expired.
+func expirePendingPayments(bookingID int) error {
+	return postToPaymentService("/payment/expire", dto.ExpirePaymentRequest{BookingID: bookingID}, nil)
+}
+
+// expireReservationPayments marks the pending payments of a reservation as
+// expired.
+func expireReservationPayments(reservationID int) error {
+	return postToPaymentService("/payment/expire", dto.ExpirePaymentRequest{ReservationID: &reservationID}, nil)
+}
+
+// requestCancellationRefund tells payment-service that a booking was
+// canceled and how much of what was paid for it is refundable. Bookings
+// that belong to a reservation were paid through it.
+func requestCancellationRefund(bookingID, userID int, reservationID *int, refundableAmount float64) error {
+	return postToPaymentService("/refund/cancellation", dto.CancellationRefundRequest{
+		BookingID:        bookingID,
+		ReservationID:    reservationID,
+		UserID:           userID,
+		RefundableAmount: refundableAmount,
+	}, nil)
+}
+
+// requestPaymentAdjustment asks payment-service to settle the price
+// difference of a modified booking: a positive amount opens an additional
+// charge, a negative one requests a partial refund.
+func requestPaymentAdjustment(bookingID, userID int, reservationID *int, amount float64) (dto.PaymentAdjustmentResponse, error) {
+	var response dto.PaymentAdjustmentResponse
+	err := postToPaymentService("/payment/adjustment", dto.PaymentAdjustmentRequest{
+		BookingID:     bookingID,
+		ReservationID: reservationID,
+		UserID:        userID,
+		Amount:        amount,
+	}, &response)
+	return response, err
+}
@@ -0,0 +1,157 @@
+package handler
+
+import (
+	"booking-service/dto"
+	model "booking-service/models"
+	"math"
+	"time"
+
+	"github.com/lib/pq"
+)
+
+const ratePlanColumns = `id, room_id, name, to_char(start_date, 'YYYY-MM-DD'), to_char(end_date, 'YYYY-MM-DD'),
+	days_of_week, price_per_night, adjustment_percent, priority, created_at, updated_at`
+
+type rowScanner interface {
+	Scan(dest ...interface{}) error
+}
+
+func scanRatePlan(row rowScanner) (model.RatePlan, error) {
+	var plan model.RatePlan
+	err := row.Scan(
+		&plan.RatePlanID,
+		&plan.RoomID,
+		&plan.Name,
+		&plan.StartDate,
+		&plan.EndDate,
+		pq.Array(&plan.DaysOfWeek),
+		&plan.PricePerNight,
+		&plan.AdjustmentPercent,
+		&plan.Priority,
+		&plan.CreatedAt,
+		&plan.UpdatedAt,
+	)
+	return plan, err
+}
+
+// roundMoney rounds an amount to whole cents.
+func roundMoney(amount float64) float64 {
+	return math.Round(amount*100) / 100
+}
+
+// sameAmount reports whether two amounts are equal to the cent.
+func sameAmount(a, b float64) bool {
+	return math.Abs(roundMoney(a)-roundMoney(b)) < 0.005
+}
+
+// loadRatePlans returns the rate plans of the given rooms that may apply to
+// at least one night of the stay, keyed by room ID.
+func loadRatePlans(db dbExecutor, roomIDs []int, checkin, checkout time.Time) (map[int][]model.RatePlan, error) {
+	ids := make([]int64, len(roomIDs))
+	for i, id := range roomIDs {
+		ids[i] = int64(id)
+	}
+
+	query := `
+		SELECT ` + ratePlanColumns + `
+		FROM rate_plans
+		WHERE room_id = ANY($1)
+		AND (start_date IS NULL OR start_date < $3)
+		AND (end_date IS NULL OR end_date >= $2)
+	`
+
+	rows, err := db.Query(query, pq.Array(ids), checkin, checkout)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	plans := map[int][]model.RatePlan{}
+	for rows.Next() {
+		plan, err := scanRatePlan(rows)
+		if err != nil {
+			return nil, err
+		}
+		plans[plan.RoomID] = append(plans[plan.RoomID], plan)
+	}
+
+	return plans, rows.Err()
+}
+
+// matchRatePlan returns the plan that prices the given night, or nil when
+// the room's base price applies.
+func matchRatePlan(plans []model.RatePlan, night time.Time) *model.RatePlan {
+	date := night.Format(dateLayout)
+	weekday := int64(night.Weekday())
+
+	var match *model.RatePlan
+	for i := range plans {
+		plan := &plans[i]
+
+		if plan.StartDate != nil && date < *plan.StartDate {
+			continue
+		}
+		if plan.EndDate != nil && date > *plan.EndDate {
+			continue
+		}
+		if len(plan.DaysOfWeek) > 0 {
+			found := false
+			for _, day := range plan.DaysOfWeek {
+				if day == weekday {
+					found = true
+					break
+				}
+			}
+			if !found {
+				continue
+			}
+		}
+
+		if match == nil || plan.Priority > match.Priority ||
+			(plan.Priority == match.Priority && plan.RatePlanID > match.RatePlanID) {
+			match = plan
+		}
+	}
+
+	return match
+}
+
+// priceStay prices every night of the stay against the room's rate plans,
+// falling back to the base price, and returns the breakdown with the total.
+func priceStay(basePrice float64, plans []model.RatePlan, checkin, checkout time.Time) ([]dto.NightlyRate, float64) {
+	nightlyRates := []dto.NightlyRate{}
+	total := 0.0
+
+	for night := checkin; night.Before(checkout); night = night.AddDate(0, 0, 1) {
+		rate := dto.NightlyRate{
+			Date:  night.Format(dateLayout),
+			Price: roundMoney(basePrice),
+		}
+
+		if plan := matchRatePlan(plans, night); plan != nil {
+			if plan.PricePerNight != nil {
+				rate.Price = roundMoney(*plan.PricePerNight)
+			} else {
+				rate.Price = roundMoney(basePrice * (1 + *plan.AdjustmentPercent/100))
+			}
+			rate.RatePlanID = &plan.RatePlanID
+			rate.RatePlan = plan.Name
+		}
+
+		nightlyRates = append(nightlyRates, rate)
+		total += rate.Price
+	}
+
+	return nightlyRates, roundMoney(total)
+}
+
+// calculateStayPrice prices a stay in a single room.
+func calculateStayPrice(db dbExecutor, roomID int, basePrice float64, checkin, checkout time.Time) ([]dto.NightlyRate, float64, error) {
+	plans, err := loadRatePlans(db, []int{roomID}, checkin, checkout)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	nightlyRates, total := priceStay(basePrice, plans[roomID], checkin, checkout)
+	return nightlyRates, total, nil
+}
@@ -0,0 +1,190 @@
+package handler
+
+import (
+	"booking-service/config"
+	"booking-service/dto"
+	model "booking-service/models"
+	"database/sql"
+	"errors"
+	"log"
+	"net/http"
+	"time"
+
+	"github.com/labstack/echo/v4"
+	"github.com/lib/pq"
+)
+
+func validateRatePlan(req dto.RatePlanRequest) error {
+	if req.Name == "" {
+		return errors.New("name is required")
+	}
+
+	if (req.PricePerNight == nil) == (req.AdjustmentPercent == nil) {
+		return errors.New("Exactly one of price_per_night or adjustment_percent is required")
+	}
+	if req.PricePerNight != nil && *req.PricePerNight <= 0 {
+		return errors.New("price_per_night must be greater than zero")
+	}
+	if req.AdjustmentPercent != nil && *req.AdjustmentPercent <= -100 {
+		return errors.New("adjustment_percent must be greater than -100")
+	}
+
+	var startDate, endDate time.Time
+	var err error
+	if req.StartDate != nil {
+		if startDate, err = time.Parse(dateLayout, *req.StartDate); err != nil {
+			return errors.New("Invalid start_date format")
+		}
+	}
+	if req.EndDate != nil {
+		if endDate, err = time.Parse(dateLayout, *req.EndDate); err != nil {
+			return errors.New("Invalid end_date format")
+		}
+	}
+	if req.StartDate != nil && req.EndDate != nil && endDate.Before(startDate) {
+		return errors.New("end_date must not be before start_date")
+	}
+
+	for _, day := range req.DaysOfWeek {
+		if day < 0 || day > 6 {
+			return errors.New("days_of_week must contain values from 0 (Sunday) to 6 (Saturday)")
+		}
+	}
+
+	return nil
+}
+
+// ratePlanHotelQuery selects the hotel a rate plan belongs to.
+const ratePlanHotelQuery = `SELECT r.hotel_id FROM rate_plans p JOIN rooms r ON r.id = p.room_id WHERE p.id = $1`
+
+func ListRatePlansByRoomID(c echo.Context) error {
+	roomID := c.Param("id")
+
+	if ok, err := checkRoomAccess(c, config.DB, roomID); !ok {
+		return err
+	}
+
+	query := `SELECT ` + ratePlanColumns + ` FROM rate_plans WHERE room_id = $1 ORDER BY priority DESC, id`
+
+	rows, err := config.DB.Query(query, roomID)
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, dto.ErrorResponse{Message: "Failed to retrieve rate plans"})
+	}
+	defer rows.Close()
+
+	ratePlans := []model.RatePlan{}
+	for rows.Next() {
+		plan, err := scanRatePlan(rows)
+		if err != nil {
+			return c.JSON(http.StatusInternalServerError, dto.ErrorResponse{Message: "Failed to scan rate plan data"})
+		}
+		ratePlans = append(ratePlans, plan)
+	}
+
+	if err := rows.Err(); err != nil {
+		return c.JSON(http.StatusInternalServerError, dto.ErrorResponse{Message: "Error occurred during rate plans retrieval"})
+	}
+
+	return c.JSON(http.StatusOK, ratePlans)
+}
+
+func CreateRatePlan(c echo.Context) error {
+	var req dto.RatePlanRequest
+
+	if err := c.Bind(&req); err != nil {
+		return c.JSON(http.StatusBadRequest, dto.ErrorResponse{Message: "Invalid request"})
+	}
+
+	if err := validateRatePlan(req); err != nil {
+		return c.JSON(http.StatusBadRequest, dto.ErrorResponse{Message: err.Error()})
+	}
+
+	var existingRoomID, hotelID int
+	err := config.DB.QueryRow(`SELECT id, hotel_id FROM rooms WHERE id = $1 AND deleted_at IS NULL`, req.RoomID).Scan(&existingRoomID, &hotelID)
+	if err == sql.ErrNoRows {
+		return c.JSON(http.StatusBadRequest, dto.ErrorResponse{Message: "Room not found"})
+	} else if err != nil {
+		return c.JSON(http.StatusInternalServerError, dto.ErrorResponse{Message: "Failed to check room"})
+	}
+
+	if !canManageHotel(c, hotelID) {
+		return forbiddenHotel(c)
+	}
+
+	query := `
+		INSERT INTO rate_plans (room_id, name, start_date, end_date, days_of_week, price_per_night, adjustment_percent, priority, created_at, updated_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, NOW(), NOW())
+		RETURNING id
+	`
+
+	var ratePlanID int
+	err = config.DB.QueryRow(query, req.RoomID, req.Name, req.StartDate, req.EndDate, pq.Array(req.DaysOfWeek),
+		req.PricePerNight, req.AdjustmentPercent, req.Priority).Scan(&ratePlanID)
+	if err != nil {
+		log.Println("Error creating rate plan:", err)
+		return c.JSON(http.StatusInternalServerError, dto.ErrorResponse{Message: "Failed to create rate plan"})
+	}
+
+	return c.JSON(http.StatusCreated, dto.CreateRatePlanResponse{
+		RatePlanID: ratePlanID,
+		Message:    "Rate plan created successfully",
+	})
+}
+
+func UpdateRatePlan(c echo.Context) error {
+	ratePlanID := c.Param("id")
+
+	var req dto.RatePlanRequest
+
+	if err := c.Bind(&req); err != nil {
+		return c.JSON(http.StatusBadRequest, dto.ErrorResponse{Message: "Invalid request"})
+	}
+
+	if err := validateRatePlan(req); err != nil {
+		return c.JSON(http.StatusBadRequest, dto.ErrorResponse{Message: err.Error()})
+	}
+
+	if ok, err := checkHotelAccess(c, config.DB, ratePlanHotelQuery, ratePlanID, "Rate plan not found"); !ok {
+		return err
+	}
+
+	query := `
+		UPDATE rate_plans
+		SET name = $1, start_date = $2, end_date = $3, days_of_week = $4, price_per_night = $5,
+			adjustment_percent = $6, priority = $7, updated_at = NOW()
+		WHERE id = $8
+	`
+	res, err := config.DB.Exec(query, req.Name, req.StartDate, req.EndDate, pq.Array(req.DaysOfWeek),
+		req.PricePerNight, req.AdjustmentPercent, req.Priority, ratePlanID)
+	if err != nil {
+		log.Println("Error updating rate plan:", err)
+		return c.JSON(http.StatusInternalServerError, dto.ErrorResponse{Message: "Failed to update rate plan"})
+	}
+
+	rowsAffected, err := res.RowsAffected()
+	if err != nil || rowsAffected == 0 {
+		return c.JSON(http.StatusNotFound, dto.ErrorResponse{Message: "Rate plan not found"})
+	}
+
+	return c.JSON(http.StatusOK, dto.SuccessResponse{Message: "Rate plan updated successfully"})
+}
+
+func DeleteRatePlan(c echo.Context) error {
+	ratePlanID := c.Param("id")
+
+	if ok, err := checkHotelAccess(c, config.DB, ratePlanHotelQuery, ratePlanID, "Rate plan not found"); !ok {
+		return err
+	}
+
+	res, err := config.DB.Exec(`DELETE FROM rate_plans WHERE id = $1`, ratePlanID)
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, dto.ErrorResponse{Message: "Failed to delete rate plan"})
+	}
+
+	rowsAffected, err := res.RowsAffected()
+	if err != nil || rowsAffected == 0 {
+		return c.JSON(http.StatusNotFound, dto.ErrorResponse{Message: "Rate plan not found"})
+	}
+
+	return c.JSON(http.StatusOK, dto.SuccessResponse{Message: "Rate plan deleted successfully"})
+}
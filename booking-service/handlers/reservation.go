@@ -0,0 +1,335 @@
+package handler
+
+import (
+	"booking-service/config"
+	"booking-service/dto"
+	model "booking-service/models"
+	"database/sql"
+	"fmt"
+	"log"
+	"net/http"
+	"time"
+
+	"github.com/labstack/echo/v4"
+	"github.com/lib/pq"
+)
+
+const maxReservationLines = 20
+
+type reservationLine struct {
+	roomID       int
+	checkinDate  time.Time
+	checkoutDate time.Time
+}
+
+type lockedRoom struct {
+	status         string
+	pricePerNight  float64
+	holdTTLMinutes int
+	maxAdults      int
+	maxChildren    int
+}
+
+// refreshReservation recomputes the total of a reservation from its
+// remaining lines and cancels it once every line is canceled.
+func refreshReservation(db dbExecutor, reservationID int) error {
+	query := `
+		UPDATE reservations SET
+			total_price = COALESCE((SELECT SUM(total_price) FROM bookings WHERE reservation_id = $1 AND status <> $2), 0),
+			status = CASE WHEN EXISTS (SELECT 1 FROM bookings WHERE reservation_id = $1 AND status <> $2) THEN status ELSE $2 END,
+			updated_at = NOW()
+		WHERE id = $1
+	`
+	_, err := db.Exec(query, reservationID, model.Canceled)
+	return err
+}
+
+// CreateReservation holds several rooms, possibly for different dates, as
+// one reservation that is paid with a single payment. Each line becomes a
+// pending booking; the reservation and its lines share one hold.
+func CreateReservation(c echo.Context) error {
+	var req dto.CreateReservationRequest
+
+	if err := c.Bind(&req); err != nil {
+		return c.JSON(http.StatusBadRequest, dto.ErrorResponse{Message: "Invalid request"})
+	}
+
+	if len(req.Lines) == 0 {
+		return c.JSON(http.StatusBadRequest, dto.ErrorResponse{Message: "At least one line is required"})
+	}
+	if len(req.Lines) > maxReservationLines {
+		return c.JSON(http.StatusBadRequest, dto.ErrorResponse{Message: fmt.Sprintf("A reservation can contain at most %d lines", maxReservationLines)})
+	}
+
+	lines := make([]reservationLine, len(req.Lines))
+	roomIDs := []int64{}
+	seenRooms := map[int]bool{}
+	for i, lineReq := range req.Lines {
+		checkinDate, checkoutDate, err := parseStay(lineReq.CheckinDate, lineReq.CheckoutDate)
+		if err != nil {
+			return c.JSON(http.StatusBadRequest, dto.ErrorResponse{Message: fmt.Sprintf("Line %d: %s", i+1, err.Error())})
+		}
+
+		if err := validateParty(&req.Lines[i].Adults, lineReq.Children, lineReq.Guests); err != nil {
+			return c.JSON(http.StatusBadRequest, dto.ErrorResponse{Message: fmt.Sprintf("Line %d: %s", i+1, err.Error())})
+		}
+
+		for j := 0; j < i; j++ {
+			if lines[j].roomID == lineReq.RoomID && lines[j].checkinDate.Before(checkoutDate) && lines[j].checkoutDate.After(checkinDate) {
+				return c.JSON(http.StatusBadRequest, dto.ErrorResponse{Message: fmt.Sprintf("Lines %d and %d book the same room for overlapping dates", j+1, i+1)})
+			}
+		}
+
+		lines[i] = reservationLine{roomID: lineReq.RoomID, checkinDate: checkinDate, checkoutDate: checkoutDate}
+		if !seenRooms[lineReq.RoomID] {
+			seenRooms[lineReq.RoomID] = true
+			roomIDs = append(roomIDs, int64(lineReq.RoomID))
+		}
+	}
+
+	tx, err := config.DB.Begin()
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, dto.ErrorResponse{Message: "Failed to start transaction"})
+	}
+	defer tx.Rollback()
+
+	// Rooms are locked in ID order so that two reservations sharing rooms
+	// cannot deadlock, and CreateBooking waits on the same row locks.
+	lockRoomsQuery := `
+		SELECT r.id, r.status, r.price_per_night, h.hold_ttl_minutes, r.max_adults, r.max_children
+		FROM rooms r
+		JOIN hotels h ON h.id = r.hotel_id
+		WHERE r.id = ANY($1) AND r.deleted_at IS NULL
+		ORDER BY r.id
+		FOR UPDATE OF r
+	`
+	rows, err := tx.Query(lockRoomsQuery, pq.Array(roomIDs))
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, dto.ErrorResponse{Message: "Failed to check room availability"})
+	}
+
+	rooms := map[int]lockedRoom{}
+	for rows.Next() {
+		var roomID int
+		var room lockedRoom
+		if err := rows.Scan(&roomID, &room.status, &room.pricePerNight, &room.holdTTLMinutes, &room.maxAdults, &room.maxChildren); err != nil {
+			rows.Close()
+			return c.JSON(http.StatusInternalServerError, dto.ErrorResponse{Message: "Failed to check room availability"})
+		}
+		rooms[roomID] = room
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return c.JSON(http.StatusInternalServerError, dto.ErrorResponse{Message: "Failed to check room availability"})
+	}
+
+	holdTTLMinutes := 0
+	totalPrice := 0.0
+	responseLines := make([]dto.ReservationLineResponse, len(lines))
+
+	for i, line := range lines {
+		room, ok := rooms[line.roomID]
+		if !ok {
+			return c.JSON(http.StatusBadRequest, dto.ErrorResponse{Message: fmt.Sprintf("Line %d: Room not found", i+1)})
+		}
+
+		if room.status == string(model.Maintenance) {
+			return c.JSON(http.StatusBadRequest, dto.ErrorResponse{Message: fmt.Sprintf("Line %d: Room is under maintenance", i+1)})
+		}
+
+		if !fitsRoom(req.Lines[i].Adults, req.Lines[i].Children, room.maxAdults, room.maxChildren) {
+			return c.JSON(http.StatusBadRequest, dto.ErrorResponse{Message: fmt.Sprintf("Line %d: %s", i+1, partyTooLarge(room.maxAdults, room.maxChildren))})
+		}
+
+		unavailable, err := isRoomUnavailable(tx, line.roomID, line.checkinDate, line.checkoutDate, 0)
+		if err != nil {
+			return c.JSON(http.StatusInternalServerError, dto.ErrorResponse{Message: "Failed to check room availability"})
+		}
+		if unavailable {
+			return c.JSON(http.StatusBadRequest, dto.ErrorResponse{Message: fmt.Sprintf("Line %d: Room is not available for the selected dates", i+1)})
+		}
+
+		nightlyRates, linePrice, err := calculateStayPrice(tx, line.roomID, room.pricePerNight, line.checkinDate, line.checkoutDate)
+		if err != nil {
+			return c.JSON(http.StatusInternalServerError, dto.ErrorResponse{Message: "Failed to calculate booking price"})
+		}
+
+		// The whole reservation is released as soon as any of its hotels
+		// would release a single booking.
+		if holdTTLMinutes == 0 || room.holdTTLMinutes < holdTTLMinutes {
+			holdTTLMinutes = room.holdTTLMinutes
+		}
+		totalPrice += linePrice
+
+		responseLines[i] = dto.ReservationLineResponse{
+			RoomID:       line.roomID,
+			CheckinDate:  req.Lines[i].CheckinDate,
+			CheckoutDate: req.Lines[i].CheckoutDate,
+			TotalPrice:   linePrice,
+			NightlyRates: nightlyRates,
+		}
+	}
+	totalPrice = roundMoney(totalPrice)
+
+	if req.TotalPrice != 0 && !sameAmount(req.TotalPrice, totalPrice) {
+		return c.JSON(http.StatusConflict, dto.ErrorResponse{Message: "total_price does not match the current price of the reservation"})
+	}
+
+	insertReservationQuery := `
+		INSERT INTO reservations (user_id, total_price, status, expires_at, created_at, updated_at)
+		VALUES ($1, $2, $3, NOW() + make_interval(mins => $4), NOW(), NOW())
+		RETURNING id, expires_at
+	`
+
+	var reservationID int
+	var expiresAt time.Time
+	err = tx.QueryRow(insertReservationQuery, req.UserID, totalPrice, model.Pending, holdTTLMinutes).Scan(&reservationID, &expiresAt)
+	if err != nil {
+		log.Println("Error creating reservation:", err)
+		return c.JSON(http.StatusInternalServerError, dto.ErrorResponse{Message: "Failed to create reservation"})
+	}
+
+	insertBookingQuery := `
+		INSERT INTO bookings (user_id, room_id, checkin_date, checkout_date, total_price, status, expires_at, reservation_id,
+			adults, children, created_at, updated_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, NOW(), NOW())
+		RETURNING id
+	`
+	for i, line := range lines {
+		err = tx.QueryRow(insertBookingQuery, req.UserID, line.roomID, line.checkinDate, line.checkoutDate,
+			responseLines[i].TotalPrice, model.Pending, expiresAt, reservationID,
+			req.Lines[i].Adults, req.Lines[i].Children).Scan(&responseLines[i].BookingID)
+		if err != nil {
+			log.Println("Error creating reservation line:", err)
+			return c.JSON(http.StatusInternalServerError, dto.ErrorResponse{Message: "Failed to create reservation"})
+		}
+
+		if err := insertGuests(tx, responseLines[i].BookingID, req.Lines[i].Guests); err != nil {
+			log.Println("Error registering reservation guests:", err)
+			return c.JSON(http.StatusInternalServerError, dto.ErrorResponse{Message: "Failed to create reservation"})
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return c.JSON(http.StatusInternalServerError, dto.ErrorResponse{Message: "Failed to create reservation"})
+	}
+
+	return c.JSON(http.StatusCreated, dto.CreateReservationResponse{
+		ReservationID: reservationID,
+		TotalPrice:    totalPrice,
+		ExpiresAt:     expiresAt,
+		Lines:         responseLines,
+		Message:       "Reservation created successfully",
+	})
+}
+
+func GetReservationByID(c echo.Context) error {
+	reservationID := c.Param("id")
+
+	var reservation model.Reservation
+	query := `SELECT id, user_id, total_price, status, expires_at, created_at, updated_at FROM reservations WHERE id = $1`
+	err := config.DB.QueryRow(query, reservationID).Scan(
+		&reservation.ReservationID,
+		&reservation.UserID,
+		&reservation.TotalPrice,
+		&reservation.Status,
+		&reservation.ExpiresAt,
+		&reservation.CreatedAt,
+		&reservation.UpdatedAt,
+	)
+	if err == sql.ErrNoRows {
+		return c.JSON(http.StatusNotFound, dto.ErrorResponse{Message: "Reservation not found"})
+	} else if err != nil {
+		return c.JSON(http.StatusInternalServerError, dto.ErrorResponse{Message: "Failed to retrieve reservation"})
+	}
+
+	bookingsQuery := `
+		SELECT b.id, b.user_id, b.room_id, r.hotel_id, b.checkin_date, b.checkout_date, b.total_price, b.status,
+			b.checkin_status, b.expires_at, b.cancellation_fee, b.canceled_at, b.reservation_id, b.created_at, b.updated_at
+		FROM bookings b
+		JOIN rooms r ON r.id = b.room_id
+		WHERE b.reservation_id = $1 ORDER BY b.id
+	`
+	rows, err := config.DB.Query(bookingsQuery, reservation.ReservationID)
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, dto.ErrorResponse{Message: "Failed to retrieve reservation bookings"})
+	}
+	defer rows.Close()
+
+	reservation.Bookings = []model.Booking{}
+	for rows.Next() {
+		var booking model.Booking
+		if err := rows.Scan(
+			&booking.BookingID,
+			&booking.UserID,
+			&booking.RoomID,
+			&booking.HotelID,
+			&booking.CheckinDate,
+			&booking.CheckoutDate,
+			&booking.TotalPrice,
+			&booking.Status,
+			&booking.CheckinStatus,
+			&booking.ExpiresAt,
+			&booking.CancellationFee,
+			&booking.CanceledAt,
+			&booking.ReservationID,
+			&booking.CreatedAt,
+			&booking.UpdatedAt,
+		); err != nil {
+			return c.JSON(http.StatusInternalServerError, dto.ErrorResponse{Message: "Failed to scan booking data"})
+		}
+		reservation.Bookings = append(reservation.Bookings, booking)
+	}
+
+	if err := rows.Err(); err != nil {
+		return c.JSON(http.StatusInternalServerError, dto.ErrorResponse{Message: "Error occurred during reservation retrieval"})
+	}
+
+	return c.JSON(http.StatusOK, reservation)
+}
+
+// UpdateReservationStatusHandler is called by payment-service once a
+// reservation is paid and confirms the reservation with all of its lines.
+// The payment has to match the current total of the reservation.
+func UpdateReservationStatusHandler(c echo.Context) error {
+	var req dto.UpdateReservationStatusRequest
+
+	if err := c.Bind(&req); err != nil {
+		return c.JSON(http.StatusBadRequest, dto.ErrorResponse{Message: "Invalid request"})
+	}
+
+	tx, err := config.DB.Begin()
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, dto.ErrorResponse{Message: "Failed to start transaction"})
+	}
+	defer tx.Rollback()
+
+	query := `
+		UPDATE reservations SET status = $1, expires_at = NULL, updated_at = NOW()
+		WHERE id = $2 AND status = $3 AND (expires_at IS NULL OR expires_at > NOW())
+		AND total_price = ROUND($4::numeric, 2)
+	`
+	res, err := tx.Exec(query, req.Status, req.ReservationID, model.Pending, req.Amount)
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, dto.ErrorResponse{Message: "Failed to update reservation status"})
+	}
+
+	rowsAffected, err := res.RowsAffected()
+	if err != nil || rowsAffected == 0 {
+		return c.JSON(http.StatusConflict, dto.ErrorResponse{Message: "Reservation is no longer pending, its hold has expired or the amount paid does not match its price"})
+	}
+
+	updateBookingsQuery := `
+		UPDATE bookings SET status = $1, expires_at = NULL, updated_at = NOW()
+		WHERE reservation_id = $2 AND status = $3
+	`
+	if _, err := tx.Exec(updateBookingsQuery, req.Status, req.ReservationID, model.Pending); err != nil {
+		return c.JSON(http.StatusInternalServerError, dto.ErrorResponse{Message: "Failed to update reservation status"})
+	}
+
+	if err := tx.Commit(); err != nil {
+		return c.JSON(http.StatusInternalServerError, dto.ErrorResponse{Message: "Failed to update reservation status"})
+	}
+
+	return c.JSON(http.StatusOK, dto.SuccessResponse{Message: "Reservation status updated successfully"})
+}
@@ -0,0 +1,218 @@
+package handler
+
+import (
+	"booking-service/config"
+	"booking-service/dto"
+	model "booking-service/models"
+	"database/sql"
+	"fmt"
+	"log"
+	"net/http"
+	"time"
+
+	"github.com/labstack/echo/v4"
+)
+
+const roomBlockColumns = `id, room_id, block_type, to_char(start_date, 'YYYY-MM-DD'), to_char(end_date, 'YYYY-MM-DD'),
+	reason, created_at, updated_at`
+
+// CreateRoomBlock takes a room out of inventory for a date range. Confirmed
+// bookings that overlap the block are left untouched and reported back so
+// that staff can move or cancel them.
+func CreateRoomBlock(c echo.Context) error {
+	roomID := c.Param("id")
+
+	var req dto.RoomBlockRequest
+
+	if err := c.Bind(&req); err != nil {
+		return c.JSON(http.StatusBadRequest, dto.ErrorResponse{Message: "Invalid request"})
+	}
+
+	if req.BlockType == "" {
+		req.BlockType = string(model.MaintenanceBlock)
+	}
+	if req.BlockType != string(model.MaintenanceBlock) && req.BlockType != string(model.OutOfOrderBlock) {
+		return c.JSON(http.StatusBadRequest, dto.ErrorResponse{Message: "block_type must be one of 'maintenance' or 'out_of_order'"})
+	}
+
+	if req.Reason == "" {
+		return c.JSON(http.StatusBadRequest, dto.ErrorResponse{Message: "reason is required"})
+	}
+
+	startDate, err := time.Parse(dateLayout, req.StartDate)
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, dto.ErrorResponse{Message: "Invalid start_date format"})
+	}
+	endDate, err := time.Parse(dateLayout, req.EndDate)
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, dto.ErrorResponse{Message: "Invalid end_date format"})
+	}
+	if endDate.Before(startDate) {
+		return c.JSON(http.StatusBadRequest, dto.ErrorResponse{Message: "end_date must not be before start_date"})
+	}
+
+	tx, err := config.DB.Begin()
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, dto.ErrorResponse{Message: "Failed to start transaction"})
+	}
+	defer tx.Rollback()
+
+	// Taking the room lock keeps a booking from slipping in between the
+	// conflict check below and the insert.
+	var lockedRoomID, hotelID int
+	err = tx.QueryRow(`SELECT id, hotel_id FROM rooms WHERE id = $1 FOR UPDATE`, roomID).Scan(&lockedRoomID, &hotelID)
+	if err == sql.ErrNoRows {
+		return c.JSON(http.StatusNotFound, dto.ErrorResponse{Message: "Room not found"})
+	} else if err != nil {
+		return c.JSON(http.StatusInternalServerError, dto.ErrorResponse{Message: "Failed to check room"})
+	}
+
+	if !canManageHotel(c, hotelID) {
+		return forbiddenHotel(c)
+	}
+
+	conflictQuery := `
+		SELECT id FROM bookings
+		WHERE room_id = $1 AND status = $2 AND checkin_date <= $4 AND checkout_date > $3
+		ORDER BY checkin_date, id
+	`
+	rows, err := tx.Query(conflictQuery, lockedRoomID, model.Confirmed, startDate, endDate)
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, dto.ErrorResponse{Message: "Failed to check existing bookings"})
+	}
+
+	conflicting := []int{}
+	for rows.Next() {
+		var bookingID int
+		if err := rows.Scan(&bookingID); err != nil {
+			rows.Close()
+			return c.JSON(http.StatusInternalServerError, dto.ErrorResponse{Message: "Failed to check existing bookings"})
+		}
+		conflicting = append(conflicting, bookingID)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return c.JSON(http.StatusInternalServerError, dto.ErrorResponse{Message: "Failed to check existing bookings"})
+	}
+
+	insertQuery := `
+		INSERT INTO room_blocks (room_id, block_type, start_date, end_date, reason, created_at, updated_at)
+		VALUES ($1, $2, $3, $4, $5, NOW(), NOW())
+		RETURNING id
+	`
+
+	var blockID int
+	err = tx.QueryRow(insertQuery, lockedRoomID, req.BlockType, startDate, endDate, req.Reason).Scan(&blockID)
+	if err != nil {
+		log.Println("Error creating room block:", err)
+		return c.JSON(http.StatusInternalServerError, dto.ErrorResponse{Message: "Failed to create room block"})
+	}
+
+	if err := tx.Commit(); err != nil {
+		return c.JSON(http.StatusInternalServerError, dto.ErrorResponse{Message: "Failed to create room block"})
+	}
+
+	response := dto.CreateRoomBlockResponse{
+		BlockID: blockID,
+		Message: "Room block created successfully",
+	}
+	if len(conflicting) > 0 {
+		response.ConflictingBookings = conflicting
+		response.Warning = fmt.Sprintf("The block overlaps %d confirmed booking(s) that still need to be moved or canceled", len(conflicting))
+	}
+
+	return c.JSON(http.StatusCreated, response)
+}
+
+func ListRoomBlocks(c echo.Context) error {
+	roomID := c.Param("id")
+
+	if ok, err := checkRoomAccess(c, config.DB, roomID); !ok {
+		return err
+	}
+
+	query := `SELECT ` + roomBlockColumns + ` FROM room_blocks WHERE room_id = $1 ORDER BY start_date, id`
+
+	rows, err := config.DB.Query(query, roomID)
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, dto.ErrorResponse{Message: "Failed to retrieve room blocks"})
+	}
+	defer rows.Close()
+
+	blocks := []model.RoomBlock{}
+	for rows.Next() {
+		var block model.RoomBlock
+		if err := rows.Scan(
+			&block.BlockID,
+			&block.RoomID,
+			&block.BlockType,
+			&block.StartDate,
+			&block.EndDate,
+			&block.Reason,
+			&block.CreatedAt,
+			&block.UpdatedAt,
+		); err != nil {
+			return c.JSON(http.StatusInternalServerError, dto.ErrorResponse{Message: "Failed to scan room block data"})
+		}
+		blocks = append(blocks, block)
+	}
+
+	if err := rows.Err(); err != nil {
+		return c.JSON(http.StatusInternalServerError, dto.ErrorResponse{Message: "Error occurred during room blocks retrieval"})
+	}
+
+	return c.JSON(http.StatusOK, blocks)
+}
+
+func DeleteRoomBlock(c echo.Context) error {
+	blockID := c.Param("id")
+
+	blockHotelQuery := `SELECT r.hotel_id FROM room_blocks rb JOIN rooms r ON r.id = rb.room_id WHERE rb.id = $1`
+	if ok, err := checkHotelAccess(c, config.DB, blockHotelQuery, blockID, "Room block not found"); !ok {
+		return err
+	}
+
+	res, err := config.DB.Exec(`DELETE FROM room_blocks WHERE id = $1`, blockID)
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, dto.ErrorResponse{Message: "Failed to delete room block"})
+	}
+
+	rowsAffected, err := res.RowsAffected()
+	if err != nil || rowsAffected == 0 {
+		return c.JSON(http.StatusNotFound, dto.ErrorResponse{Message: "Room block not found"})
+	}
+
+	return c.JSON(http.StatusOK, dto.SuccessResponse{Message: "Room block deleted successfully"})
+}
+
+// UpdateRoomStatus changes the physical status of a room. Use a room block
+// to take a room out of inventory for specific dates instead.
+func UpdateRoomStatus(c echo.Context) error {
+	roomID := c.Param("id")
+
+	var req dto.UpdateRoomStatusRequest
+
+	if err := c.Bind(&req); err != nil {
+		return c.JSON(http.StatusBadRequest, dto.ErrorResponse{Message: "Invalid request"})
+	}
+
+	if !isValidRoomStatus(model.RoomStatus(req.Status)) {
+		return c.JSON(http.StatusBadRequest, dto.ErrorResponse{Message: "status must be one of 'available', 'occupied' or 'maintenance'"})
+	}
+
+	if ok, err := checkRoomAccess(c, config.DB, roomID); !ok {
+		return err
+	}
+
+	res, err := config.DB.Exec(`UPDATE rooms SET status = $1, updated_at = NOW() WHERE id = $2`, req.Status, roomID)
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, dto.ErrorResponse{Message: "Failed to update room status"})
+	}
+
+	rowsAffected, err := res.RowsAffected()
+	if err != nil || rowsAffected == 0 {
+		return c.JSON(http.StatusNotFound, dto.ErrorResponse{Message: "Room not found"})
+	}
+
+	return c.JSON(http.StatusOK, dto.SuccessResponse{Message: "Room status updated successfully"})
+}
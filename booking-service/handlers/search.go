@@ -0,0 +1,133 @@
+package handler
+
+import (
+	"booking-service/config"
+	"booking-service/dto"
+	model "booking-service/models"
+	"fmt"
+	"log"
+	"net/http"
+	"strconv"
+
+	"github.com/labstack/echo/v4"
+)
+
+// SearchAvailability returns the hotels that have at least one room bookable
+// for the whole stay. min_price and max_price bound the average nightly rate
+// of the stay after rate plans are applied; adults and children leave out
+// rooms that cannot fit the party.
+func SearchAvailability(c echo.Context) error {
+	checkinDate, checkoutDate, err := parseStay(c.QueryParam("checkin_date"), c.QueryParam("checkout_date"))
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, dto.ErrorResponse{Message: err.Error()})
+	}
+
+	var minPrice, maxPrice float64
+	if value := c.QueryParam("min_price"); value != "" {
+		if minPrice, err = strconv.ParseFloat(value, 64); err != nil {
+			return c.JSON(http.StatusBadRequest, dto.ErrorResponse{Message: "Invalid min_price"})
+		}
+	}
+	if value := c.QueryParam("max_price"); value != "" {
+		if maxPrice, err = strconv.ParseFloat(value, 64); err != nil {
+			return c.JSON(http.StatusBadRequest, dto.ErrorResponse{Message: "Invalid max_price"})
+		}
+	}
+
+	adults, children, hasParty, err := parsePartyQuery(c)
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, dto.ErrorResponse{Message: err.Error()})
+	}
+
+	query := `
+		SELECT ` + hotelColumns + `, ` + roomColumns + `
+		FROM rooms r
+		JOIN hotels h ON h.id = r.hotel_id
+		WHERE r.status <> $3
+		AND r.deleted_at IS NULL
+		AND h.deleted_at IS NULL
+		AND NOT ` + roomBookedClause("r.id", 1, 2, 0) + `
+		AND NOT ` + roomBlockedClause("r.id", 1, 2)
+	args := []interface{}{checkinDate, checkoutDate, model.Maintenance}
+
+	if city := c.QueryParam("city"); city != "" {
+		args = append(args, city)
+		query += fmt.Sprintf(" AND h.city ILIKE $%d", len(args))
+	}
+
+	if country := c.QueryParam("country"); country != "" {
+		args = append(args, country)
+		query += fmt.Sprintf(" AND h.country ILIKE $%d", len(args))
+	}
+
+	if roomType := c.QueryParam("room_type"); roomType != "" {
+		args = append(args, roomType)
+		query += fmt.Sprintf(" AND r.room_type = $%d", len(args))
+	}
+
+	if hasParty {
+		args = append(args, adults, children)
+		query += " AND " + roomFitsClause(len(args)-1, len(args))
+	}
+
+	query += " ORDER BY h.id, r.price_per_night, r.id"
+
+	rows, err := config.DB.Query(query, args...)
+	if err != nil {
+		log.Println("Error searching availability:", err)
+		return c.JSON(http.StatusInternalServerError, dto.ErrorResponse{Message: "Failed to search availability"})
+	}
+	defer rows.Close()
+
+	var hotels []model.Hotel
+	var rooms []model.Room
+
+	for rows.Next() {
+		var hotel model.Hotel
+		var room model.Room
+		if err := rows.Scan(append(hotelFields(&hotel), roomFields(&room)...)...); err != nil {
+			return c.JSON(http.StatusInternalServerError, dto.ErrorResponse{Message: "Failed to scan search results"})
+		}
+		hotels = append(hotels, hotel)
+		rooms = append(rooms, room)
+	}
+
+	if err := rows.Err(); err != nil {
+		return c.JSON(http.StatusInternalServerError, dto.ErrorResponse{Message: "Error occurred during availability search"})
+	}
+
+	roomIDs := make([]int, len(rooms))
+	for i, room := range rooms {
+		roomIDs[i] = room.RoomID
+	}
+
+	ratePlans, err := loadRatePlans(config.DB, roomIDs, checkinDate, checkoutDate)
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, dto.ErrorResponse{Message: "Failed to calculate room prices"})
+	}
+
+	nights := stayNights(checkinDate, checkoutDate)
+	results := []dto.SearchHotelResult{}
+
+	for i, room := range rooms {
+		_, total := priceStay(room.PricePerNight, ratePlans[room.RoomID], checkinDate, checkoutDate)
+
+		averageRate := total / float64(nights)
+		if (minPrice > 0 && averageRate < minPrice) || (maxPrice > 0 && averageRate > maxPrice) {
+			continue
+		}
+
+		if len(results) == 0 || results[len(results)-1].HotelID != hotels[i].HotelID {
+			results = append(results, dto.SearchHotelResult{Hotel: hotels[i], Rooms: []dto.SearchRoomResult{}})
+		}
+
+		last := &results[len(results)-1]
+		last.Rooms = append(last.Rooms, dto.SearchRoomResult{
+			Room:       room,
+			Nights:     nights,
+			TotalPrice: total,
+		})
+	}
+
+	return c.JSON(http.StatusOK, results)
+}
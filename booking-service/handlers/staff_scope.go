@@ -0,0 +1,67 @@
+package handler
+
+import (
+	"booking-service/dto"
+	"database/sql"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/labstack/echo/v4"
+)
+
+// The gateway forwards the caller's role and, for hotel staff, the hotels
+// they are assigned to. Requests without a role header come from other
+// services and are not restricted.
+const (
+	userRoleHeader = "X-User-Role"
+	hotelIDsHeader = "X-Hotel-IDs"
+)
+
+// isGlobalStaff reports whether the caller may operate on every hotel.
+func isGlobalStaff(c echo.Context) bool {
+	role := c.Request().Header.Get(userRoleHeader)
+	return role == "" || role == "admin"
+}
+
+// canManageHotel reports whether the caller may operate on the given hotel.
+func canManageHotel(c echo.Context, hotelID int) bool {
+	if isGlobalStaff(c) {
+		return true
+	}
+
+	for _, value := range strings.Split(c.Request().Header.Get(hotelIDsHeader), ",") {
+		if id, err := strconv.Atoi(strings.TrimSpace(value)); err == nil && id == hotelID {
+			return true
+		}
+	}
+	return false
+}
+
+func forbiddenHotel(c echo.Context) error {
+	return c.JSON(http.StatusForbidden, dto.ErrorResponse{Message: "You do not have access to this hotel"})
+}
+
+// checkRoomAccess looks up the hotel of a room and writes the error
+// response when the room does not exist or the caller may not manage it.
+// The caller should return the error unchanged when ok is false.
+func checkRoomAccess(c echo.Context, db dbExecutor, roomID interface{}) (ok bool, err error) {
+	return checkHotelAccess(c, db, `SELECT hotel_id FROM rooms WHERE id = $1`, roomID, "Room not found")
+}
+
+// checkHotelAccess runs hotelQuery, which must select a single hotel ID for
+// the resource bound to $1, and checks the caller against it.
+func checkHotelAccess(c echo.Context, db dbExecutor, hotelQuery string, id interface{}, notFound string) (bool, error) {
+	var hotelID int
+	err := db.QueryRow(hotelQuery, id).Scan(&hotelID)
+	if err == sql.ErrNoRows {
+		return false, c.JSON(http.StatusNotFound, dto.ErrorResponse{Message: notFound})
+	} else if err != nil {
+		return false, c.JSON(http.StatusInternalServerError, dto.ErrorResponse{Message: "Failed to check hotel access"})
+	}
+
+	if !canManageHotel(c, hotelID) {
+		return false, forbiddenHotel(c)
+	}
+	return true, nil
+}
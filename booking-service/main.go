@@ -2,6 +2,7 @@ package main
 
 import (
 	"booking-service/config"
+	handler "booking-service/handlers"
 	"booking-service/router"
 	"log"
 
@@ -13,6 +14,8 @@ func main() {
 
     config.InitDB()
 
+    handler.StartHoldSweeper(config.HoldSweepInterval())
+
     router.InitRoutes(e)
 
     if err := e.Start(":5001"); err != nil {
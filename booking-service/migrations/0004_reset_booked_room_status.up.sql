@@ -0,0 +1,3 @@
+UPDATE rooms SET status = 'available' WHERE status = 'booked';
+
+CREATE INDEX IF NOT EXISTS idx_bookings_room_dates ON bookings (room_id, checkin_date, checkout_date);
@@ -0,0 +1,16 @@
+CREATE TABLE rate_plans (
+    id SERIAL PRIMARY KEY,
+    room_id INTEGER NOT NULL REFERENCES rooms(id) ON DELETE CASCADE,
+    name VARCHAR(100) NOT NULL,
+    start_date DATE,
+    end_date DATE,
+    days_of_week INTEGER[],
+    price_per_night DECIMAL(10, 2),
+    adjustment_percent DECIMAL(6, 2),
+    priority INTEGER NOT NULL DEFAULT 0,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    CHECK ((price_per_night IS NULL) <> (adjustment_percent IS NULL))
+);
+
+CREATE INDEX idx_rate_plans_room ON rate_plans (room_id);
@@ -0,0 +1,5 @@
+DROP INDEX IF EXISTS idx_bookings_pending_expiry;
+
+ALTER TABLE bookings DROP COLUMN IF EXISTS expires_at;
+
+ALTER TABLE hotels DROP COLUMN IF EXISTS hold_ttl_minutes;
@@ -0,0 +1,5 @@
+ALTER TABLE hotels ADD COLUMN hold_ttl_minutes INTEGER NOT NULL DEFAULT 30;
+
+ALTER TABLE bookings ADD COLUMN expires_at TIMESTAMP;
+
+CREATE INDEX idx_bookings_pending_expiry ON bookings (expires_at) WHERE status = 'pending';
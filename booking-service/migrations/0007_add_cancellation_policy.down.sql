@@ -0,0 +1,7 @@
+ALTER TABLE bookings
+    DROP COLUMN IF EXISTS canceled_at,
+    DROP COLUMN IF EXISTS cancellation_fee;
+
+ALTER TABLE hotels
+    DROP COLUMN IF EXISTS cancellation_penalty_percent,
+    DROP COLUMN IF EXISTS free_cancellation_days;
@@ -0,0 +1,7 @@
+ALTER TABLE hotels
+    ADD COLUMN free_cancellation_days INTEGER NOT NULL DEFAULT 1,
+    ADD COLUMN cancellation_penalty_percent DECIMAL(5, 2) NOT NULL DEFAULT 100;
+
+ALTER TABLE bookings
+    ADD COLUMN cancellation_fee DECIMAL(10, 2),
+    ADD COLUMN canceled_at TIMESTAMP;
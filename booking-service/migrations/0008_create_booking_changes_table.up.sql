@@ -0,0 +1,21 @@
+CREATE TABLE booking_changes (
+    id SERIAL PRIMARY KEY,
+    booking_id INT NOT NULL REFERENCES bookings(id) ON DELETE CASCADE,
+    user_id INT NOT NULL,
+    previous_room_id INT NOT NULL,
+    previous_checkin_date DATE NOT NULL,
+    previous_checkout_date DATE NOT NULL,
+    previous_total_price DECIMAL(10, 2) NOT NULL,
+    new_room_id INT NOT NULL,
+    new_checkin_date DATE NOT NULL,
+    new_checkout_date DATE NOT NULL,
+    new_total_price DECIMAL(10, 2) NOT NULL,
+    price_difference DECIMAL(10, 2) NOT NULL,
+    adjustment VARCHAR(20) NOT NULL DEFAULT 'none',
+    adjustment_status VARCHAR(20) NOT NULL DEFAULT 'not_required',
+    payment_uid UUID,
+    refund_id INT,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX idx_booking_changes_booking_id ON booking_changes (booking_id);
@@ -0,0 +1,14 @@
+CREATE TABLE reservations (
+    id SERIAL PRIMARY KEY,
+    user_id INTEGER NOT NULL,
+    total_price DECIMAL(10, 2) NOT NULL,
+    status VARCHAR(20) DEFAULT 'pending',
+    expires_at TIMESTAMP,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
+
+ALTER TABLE bookings ADD COLUMN reservation_id INTEGER REFERENCES reservations(id) ON DELETE SET NULL;
+
+CREATE INDEX idx_bookings_reservation_id ON bookings (reservation_id);
+CREATE INDEX idx_reservations_pending_expiry ON reservations (expires_at) WHERE status = 'pending';
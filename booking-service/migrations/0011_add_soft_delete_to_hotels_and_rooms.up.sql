@@ -0,0 +1,3 @@
+ALTER TABLE hotels ADD COLUMN deleted_at TIMESTAMP;
+
+ALTER TABLE rooms ADD COLUMN deleted_at TIMESTAMP;
@@ -0,0 +1,5 @@
+DROP TABLE IF EXISTS guests;
+
+ALTER TABLE bookings
+    DROP COLUMN IF EXISTS adults,
+    DROP COLUMN IF EXISTS children;
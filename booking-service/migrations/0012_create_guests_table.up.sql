@@ -0,0 +1,22 @@
+ALTER TABLE bookings
+    ADD COLUMN adults INTEGER NOT NULL DEFAULT 1 CHECK (adults >= 1),
+    ADD COLUMN children INTEGER NOT NULL DEFAULT 0 CHECK (children >= 0);
+
+CREATE TABLE guests (
+    id SERIAL PRIMARY KEY,
+    booking_id INTEGER NOT NULL REFERENCES bookings(id) ON DELETE CASCADE,
+    is_primary BOOLEAN NOT NULL DEFAULT FALSE,
+    first_name VARCHAR(100) NOT NULL,
+    last_name VARCHAR(100) NOT NULL,
+    email VARCHAR(255),
+    phone VARCHAR(50),
+    nationality CHAR(2),
+    date_of_birth DATE,
+    document_type VARCHAR(20),
+    document_number VARCHAR(50),
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX idx_guests_booking_id ON guests (booking_id);
+CREATE UNIQUE INDEX idx_guests_primary ON guests (booking_id) WHERE is_primary;
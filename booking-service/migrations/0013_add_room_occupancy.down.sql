@@ -0,0 +1,6 @@
+DROP INDEX IF EXISTS idx_rooms_occupancy;
+
+ALTER TABLE rooms
+    DROP COLUMN IF EXISTS max_adults,
+    DROP COLUMN IF EXISTS max_children,
+    DROP COLUMN IF EXISTS bed_configuration;
@@ -0,0 +1,12 @@
+ALTER TABLE rooms
+    ADD COLUMN max_adults INTEGER NOT NULL DEFAULT 2 CHECK (max_adults >= 1),
+    ADD COLUMN max_children INTEGER NOT NULL DEFAULT 0 CHECK (max_children >= 0),
+    ADD COLUMN bed_configuration JSONB NOT NULL DEFAULT '[]';
+
+-- Existing rooms get the defaults new rooms of their type are created with.
+UPDATE rooms SET max_adults = 1, max_children = 0, bed_configuration = '[{"type": "single", "count": 1}]' WHERE room_type = 'single';
+UPDATE rooms SET max_adults = 2, max_children = 1, bed_configuration = '[{"type": "double", "count": 1}]' WHERE room_type = 'double';
+UPDATE rooms SET max_adults = 2, max_children = 2, bed_configuration = '[{"type": "king", "count": 1}, {"type": "sofa_bed", "count": 1}]' WHERE room_type = 'suite';
+UPDATE rooms SET max_adults = 2, max_children = 1, bed_configuration = '[{"type": "king", "count": 1}]' WHERE room_type = 'deluxe';
+
+CREATE INDEX idx_rooms_occupancy ON rooms (max_adults, max_children);
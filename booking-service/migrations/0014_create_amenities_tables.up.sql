@@ -0,0 +1,31 @@
+CREATE TABLE amenities (
+    id SERIAL PRIMARY KEY,
+    code VARCHAR(50) NOT NULL UNIQUE,
+    name VARCHAR(100) NOT NULL,
+    scope VARCHAR(10) NOT NULL DEFAULT 'both' CHECK (scope IN ('hotel', 'room', 'both')),
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE TABLE hotel_amenities (
+    hotel_id INTEGER NOT NULL REFERENCES hotels(id) ON DELETE CASCADE,
+    amenity_id INTEGER NOT NULL REFERENCES amenities(id) ON DELETE CASCADE,
+    PRIMARY KEY (hotel_id, amenity_id)
+);
+
+CREATE TABLE room_amenities (
+    room_id INTEGER NOT NULL REFERENCES rooms(id) ON DELETE CASCADE,
+    amenity_id INTEGER NOT NULL REFERENCES amenities(id) ON DELETE CASCADE,
+    PRIMARY KEY (room_id, amenity_id)
+);
+
+CREATE INDEX idx_hotel_amenities_amenity_id ON hotel_amenities (amenity_id);
+CREATE INDEX idx_room_amenities_amenity_id ON room_amenities (amenity_id);
+
+INSERT INTO amenities (code, name, scope) VALUES
+    ('wifi', 'Wi-Fi', 'both'),
+    ('pool', 'Swimming pool', 'hotel'),
+    ('parking', 'Parking', 'hotel'),
+    ('breakfast', 'Breakfast included', 'hotel'),
+    ('accessible_room', 'Accessible room', 'room'),
+    ('sea_view', 'Sea view', 'room');
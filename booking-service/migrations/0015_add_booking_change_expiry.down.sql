@@ -0,0 +1,4 @@
+DROP INDEX IF EXISTS idx_booking_changes_payment_uid;
+DROP INDEX IF EXISTS idx_booking_changes_awaiting_payment;
+
+ALTER TABLE booking_changes DROP COLUMN IF EXISTS expires_at;
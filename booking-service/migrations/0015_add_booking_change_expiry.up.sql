@@ -0,0 +1,5 @@
+ALTER TABLE booking_changes ADD COLUMN expires_at TIMESTAMP;
+
+CREATE INDEX idx_booking_changes_awaiting_payment ON booking_changes (new_room_id, new_checkin_date)
+    WHERE adjustment_status = 'awaiting_payment';
+CREATE INDEX idx_booking_changes_payment_uid ON booking_changes (payment_uid);
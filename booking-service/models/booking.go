@@ -1,10 +1,17 @@
 package model
 
+import (
+    "database/sql/driver"
+    "encoding/json"
+    "fmt"
+)
+
+// RoomStatus describes the physical state of a room. Whether a room can be
+// reserved for given dates is derived from the bookings table instead.
 type RoomStatus string
 
 const (
     Available    RoomStatus = "available"
-    Booked       RoomStatus = "booked"
     Occupied     RoomStatus = "occupied"
     Maintenance  RoomStatus = "maintenance"
 )
@@ -35,6 +42,49 @@ const (
     Deluxe RoomType = "deluxe"
 )
 
+type BedType string
+
+const (
+    SingleBed BedType = "single"
+    DoubleBed BedType = "double"
+    QueenBed  BedType = "queen"
+    KingBed   BedType = "king"
+    SofaBed   BedType = "sofa_bed"
+    BunkBed   BedType = "bunk_bed"
+    Crib      BedType = "crib"
+)
+
+type Bed struct {
+    Type  BedType `json:"type"`
+    Count int     `json:"count"`
+}
+
+// BedConfiguration lists the beds of a room. It is stored as JSON.
+type BedConfiguration []Bed
+
+func (beds BedConfiguration) Value() (driver.Value, error) {
+    if beds == nil {
+        beds = BedConfiguration{}
+    }
+    return json.Marshal(beds)
+}
+
+func (beds *BedConfiguration) Scan(src interface{}) error {
+    var data []byte
+    switch value := src.(type) {
+    case []byte:
+        data = value
+    case string:
+        data = []byte(value)
+    case nil:
+        *beds = BedConfiguration{}
+        return nil
+    default:
+        return fmt.Errorf("cannot scan %T into BedConfiguration", src)
+    }
+    return json.Unmarshal(data, beds)
+}
+
 
 type Hotel struct {
     HotelID     int    `json:"id"`
@@ -44,31 +94,204 @@ type Hotel struct {
     Country     string `json:"country"`
     PhoneNumber string `json:"phone_number"`
     Email       string `json:"email"`
-    CreatedAt   string `json:"created_at"`
-    UpdatedAt   string `json:"updated_at"`
+    // HoldTTLMinutes is how long a pending booking blocks its room while
+    // waiting for payment.
+    HoldTTLMinutes int    `json:"hold_ttl_minutes"`
+    // Bookings canceled at least FreeCancellationDays before checkin are
+    // refunded in full; later cancellations forfeit
+    // CancellationPenaltyPercent of the total price.
+    FreeCancellationDays       int     `json:"free_cancellation_days"`
+    CancellationPenaltyPercent float64 `json:"cancellation_penalty_percent"`
+    CreatedAt      string `json:"created_at"`
+    UpdatedAt      string `json:"updated_at"`
+    // DeletedAt is set once the hotel is retired. It is kept so that
+    // historical bookings still resolve it.
+    DeletedAt      *string `json:"deleted_at,omitempty"`
+    // Amenities holds the codes of the hotel's amenities. It is filled in
+    // by the hotel listing and lookup.
+    Amenities      []string `json:"amenities,omitempty"`
 }
 
 type Room struct {
-    RoomID        int        `json:"id"`
-    HotelID       int        `json:"hotel_id"`
-    RoomNumber    string     `json:"room_number"`
-    RoomType      RoomType   `json:"room_type"`
-    PricePerNight float64    `json:"price_per_night"`
-    Description   string     `json:"description"`
-    Status        RoomStatus `json:"status"`
-    CreatedAt     string     `json:"created_at"`
-    UpdatedAt     string     `json:"updated_at"`
+    RoomID           int              `json:"id"`
+    HotelID          int              `json:"hotel_id"`
+    RoomNumber       string           `json:"room_number"`
+    RoomType         RoomType         `json:"room_type"`
+    PricePerNight    float64          `json:"price_per_night"`
+    Description      string           `json:"description"`
+    Status           RoomStatus       `json:"status"`
+    // MaxAdults and MaxChildren bound the party a room takes. Children may
+    // also use places left free by adults.
+    MaxAdults        int              `json:"max_adults"`
+    MaxChildren      int              `json:"max_children"`
+    BedConfiguration BedConfiguration `json:"bed_configuration"`
+    CreatedAt        string           `json:"created_at"`
+    UpdatedAt        string           `json:"updated_at"`
+    DeletedAt        *string          `json:"deleted_at,omitempty"`
+    // Amenities holds the codes of the room's own amenities, not those of
+    // its hotel. It is filled in by the room listing and lookup.
+    Amenities        []string         `json:"amenities,omitempty"`
+}
+
+type AmenityScope string
+
+const (
+    HotelAmenity AmenityScope = "hotel"
+    RoomAmenity  AmenityScope = "room"
+    AnyAmenity   AmenityScope = "both"
+)
+
+// Amenity is an entry of the amenity catalog. Scope tells whether it can
+// be given to hotels, rooms or both.
+type Amenity struct {
+    AmenityID int          `json:"id"`
+    Code      string       `json:"code"`
+    Name      string       `json:"name"`
+    Scope     AmenityScope `json:"scope"`
+    CreatedAt string       `json:"created_at"`
+    UpdatedAt string       `json:"updated_at"`
 }
 
 type Booking struct {
     BookingID    int           `json:"id"`
     UserID       int           `json:"user_id"`
     RoomID       int           `json:"room_id"`
+    // HotelID is filled in by the single booking and reservation lookups
+    // so callers can check hotel staff access.
+    HotelID      int           `json:"hotel_id,omitempty"`
     CheckinDate  string        `json:"checkin_date"`
     CheckoutDate string        `json:"checkout_date"`
     TotalPrice   float64       `json:"total_price"`
     Status       BookingStatus `json:"status"`
     CheckinStatus CheckinStatus `json:"checkin_status"`
+    ExpiresAt    *string       `json:"expires_at,omitempty"`
+    CancellationFee *float64   `json:"cancellation_fee,omitempty"`
+    CanceledAt   *string       `json:"canceled_at,omitempty"`
+    // ReservationID is set when the booking is one line of a multi-room
+    // reservation, which is then paid as a whole.
+    ReservationID *int         `json:"reservation_id,omitempty"`
+    // Adults and Children are the size of the party staying in the room.
+    Adults       int           `json:"adults"`
+    Children     int           `json:"children"`
+    // Guests is filled in by the single booking lookup and the data export.
+    Guests       []Guest       `json:"guests,omitempty"`
     CreatedAt    string        `json:"created_at"`
     UpdatedAt    string        `json:"updated_at"`
 }
+
+type DocumentType string
+
+const (
+    Passport       DocumentType = "passport"
+    NationalID     DocumentType = "national_id"
+    DrivingLicense DocumentType = "driving_license"
+)
+
+// Guest is a person staying under a booking, as registered for check-in.
+// It is separate from the account that made the booking. The primary
+// guest is the one the front desk addresses for the whole party.
+type Guest struct {
+    GuestID        int           `json:"id"`
+    BookingID      int           `json:"booking_id"`
+    IsPrimary      bool          `json:"is_primary"`
+    FirstName      string        `json:"first_name"`
+    LastName       string        `json:"last_name"`
+    Email          *string       `json:"email,omitempty"`
+    Phone          *string       `json:"phone,omitempty"`
+    Nationality    *string       `json:"nationality,omitempty"`
+    DateOfBirth    *string       `json:"date_of_birth,omitempty"`
+    DocumentType   *DocumentType `json:"document_type,omitempty"`
+    DocumentNumber *string       `json:"document_number,omitempty"`
+    CreatedAt      string        `json:"created_at"`
+    UpdatedAt      string        `json:"updated_at"`
+}
+
+// Reservation groups several bookings, possibly for different rooms and
+// dates, that are held and paid together. Its status follows the
+// BookingStatus values of its lines.
+type Reservation struct {
+    ReservationID int           `json:"id"`
+    UserID        int           `json:"user_id"`
+    TotalPrice    float64       `json:"total_price"`
+    Status        BookingStatus `json:"status"`
+    ExpiresAt     *string       `json:"expires_at,omitempty"`
+    Bookings      []Booking     `json:"bookings"`
+    CreatedAt     string        `json:"created_at"`
+    UpdatedAt     string        `json:"updated_at"`
+}
+
+// RatePlan overrides the base price of a room on the nights it matches.
+// StartDate and EndDate are inclusive and DaysOfWeek uses time.Weekday
+// numbering; empty fields match every night. A plan either sets a fixed
+// PricePerNight or adjusts the base price by AdjustmentPercent, and when
+// several plans match a night the one with the highest Priority wins.
+type RatePlan struct {
+    RatePlanID        int      `json:"id"`
+    RoomID            int      `json:"room_id"`
+    Name              string   `json:"name"`
+    StartDate         *string  `json:"start_date"`
+    EndDate           *string  `json:"end_date"`
+    DaysOfWeek        []int64  `json:"days_of_week"`
+    PricePerNight     *float64 `json:"price_per_night"`
+    AdjustmentPercent *float64 `json:"adjustment_percent"`
+    Priority          int      `json:"priority"`
+    CreatedAt         string   `json:"created_at"`
+    UpdatedAt         string   `json:"updated_at"`
+}
+
+// Adjustment is what payment-service was asked to do after a booking
+// change altered its total price.
+type Adjustment string
+
+const (
+    NoAdjustment     Adjustment = "none"
+    ChargeAdjustment Adjustment = "charge"
+    RefundAdjustment Adjustment = "refund"
+)
+
+// BookingChange records one modification of a booking's room or dates,
+// along with the price difference it caused.
+type BookingChange struct {
+    ChangeID             int        `json:"id"`
+    BookingID            int        `json:"booking_id"`
+    UserID               int        `json:"user_id"`
+    PreviousRoomID       int        `json:"previous_room_id"`
+    PreviousCheckinDate  string     `json:"previous_checkin_date"`
+    PreviousCheckoutDate string     `json:"previous_checkout_date"`
+    PreviousTotalPrice   float64    `json:"previous_total_price"`
+    NewRoomID            int        `json:"new_room_id"`
+    NewCheckinDate       string     `json:"new_checkin_date"`
+    NewCheckoutDate      string     `json:"new_checkout_date"`
+    NewTotalPrice        float64    `json:"new_total_price"`
+    PriceDifference      float64    `json:"price_difference"`
+    Adjustment           Adjustment `json:"adjustment"`
+    // AdjustmentStatus is "not_required", "requested" or "failed" for
+    // changes applied right away. A change that costs more than a paid
+    // booking is "awaiting_payment" until ExpiresAt, and then either
+    // "paid" and applied or "expired".
+    AdjustmentStatus     string     `json:"adjustment_status"`
+    PaymentUID           *string    `json:"payment_uid,omitempty"`
+    RefundID             *int       `json:"refund_id,omitempty"`
+    ExpiresAt            *string    `json:"expires_at,omitempty"`
+    CreatedAt            string     `json:"created_at"`
+}
+
+type BlockType string
+
+const (
+    MaintenanceBlock BlockType = "maintenance"
+    OutOfOrderBlock  BlockType = "out_of_order"
+)
+
+// RoomBlock takes a room out of inventory from StartDate to EndDate, both
+// inclusive, without changing its RoomStatus.
+type RoomBlock struct {
+    BlockID   int       `json:"id"`
+    RoomID    int       `json:"room_id"`
+    BlockType BlockType `json:"block_type"`
+    StartDate string    `json:"start_date"`
+    EndDate   string    `json:"end_date"`
+    Reason    string    `json:"reason"`
+    CreatedAt string    `json:"created_at"`
+    UpdatedAt string    `json:"updated_at"`
+}
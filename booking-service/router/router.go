@@ -15,19 +15,59 @@ func InitRoutes(e *echo.Echo) {
 	e.GET("/hotel", handler.GetAllHotels)
 	e.GET("/hotel/room", handler.ListRoomsByHotelId)
 	
+	e.GET("/search", handler.SearchAvailability)
+
 	e.GET("/hotel/:id", handler.GetHotelByID)
 	e.GET("/room/:id", handler.GetRoomByID)
 	
 	e.POST("/hotel", handler.CreateHotel)
 	e.POST(("/room"), handler.CreateRoom)
+
+	e.PUT("/hotel/:id", handler.UpdateHotel)
+	e.PATCH("/hotel/:id", handler.PatchHotel)
+	e.DELETE("/hotel/:id", handler.DeleteHotel)
+	e.PUT("/room/:id", handler.UpdateRoom)
+	e.PATCH("/room/:id", handler.PatchRoom)
+	e.DELETE("/room/:id", handler.DeleteRoom)
+
 	e.POST(("/booking"), handler.CreateBooking)
+	e.POST("/booking/quote", handler.QuoteBooking)
+	e.POST("/reservation", handler.CreateReservation)
+	e.GET("/reservation/:id", handler.GetReservationByID)
+
+	e.GET("/room/:id/rate-plan", handler.ListRatePlansByRoomID)
+	e.POST("/rate-plan", handler.CreateRatePlan)
+	e.PUT("/rate-plan/:id", handler.UpdateRatePlan)
+	e.DELETE("/rate-plan/:id", handler.DeleteRatePlan)
+
+	e.PUT("/room/:id/status", handler.UpdateRoomStatus)
+	e.GET("/room/:id/block", handler.ListRoomBlocks)
+	e.POST("/room/:id/block", handler.CreateRoomBlock)
+	e.DELETE("/room/block/:id", handler.DeleteRoomBlock)
+
+	e.GET("/amenity", handler.ListAmenities)
+	e.POST("/amenity", handler.CreateAmenity)
+	e.PUT("/amenity/:id", handler.UpdateAmenity)
+	e.DELETE("/amenity/:id", handler.DeleteAmenity)
+	e.PUT("/hotel/:id/amenities", handler.UpdateHotelAmenities)
+	e.PUT("/room/:id/amenities", handler.UpdateRoomAmenities)
 
 	e.GET("/booking/:user_id", handler.GetBookingsByUserID)
 	e.GET("/booking/detail/:booking_id", handler.GetBookingByID)
+	e.GET("/booking/detail/:booking_id/changes", handler.GetBookingChanges)
+	e.GET("/user/:user_id/export", handler.ExportUserData)
 
 	e.POST("/booking/callback/status", handler.UpdateBookingStatusHandler)
+	e.POST("/reservation/callback/status", handler.UpdateReservationStatusHandler)
+	e.POST("/booking/change/callback/status", handler.ApplyBookingChangeHandler)
+
+	e.POST("/booking/:booking_id/cancel", handler.CancelBooking)
+	e.PATCH("/booking/:booking_id", handler.ModifyBooking)
 
-	e.POST("/booking/refund/status", handler.UpdateBookingStatus)
 	e.PUT("/booking/checkin-status", handler.UpdateCheckinStatus)
+	e.PUT("/booking/:booking_id/party", handler.UpdateParty)
+	e.POST("/booking/:booking_id/guests", handler.AddGuest)
+	e.PUT("/booking/:booking_id/guests/:guest_id", handler.UpdateGuest)
+	e.DELETE("/booking/:booking_id/guests/:guest_id", handler.DeleteGuest)
 
 }
@@ -1,12 +1,17 @@
 package dto
 
+import (
+	"encoding/json"
+	"time"
+)
 
 type ErrorResponse struct {
 	Message string `json:"message"`
 }
 
 type CreatePaymentRequest struct {
-	BookingID     int     `json:"booking_id"`
+	BookingID     int     `json:"booking_id,omitempty"`
+	ReservationID *int    `json:"reservation_id,omitempty"`
 	UserID        int     `json:"user_id"`
 	Amount        float64 `json:"amount"`
 	PaymentMethod string  `json:"payment_method"`
@@ -17,7 +22,6 @@ type RegisterUserRequest struct {
 	Username string `json:"username"`
 	Email    string `json:"email"`
 	Password string `json:"password"`
-	Role     string `json:"role"`
 }
 
 type LoginUserRequest struct {
@@ -26,20 +30,56 @@ type LoginUserRequest struct {
 }
 
 type CreateBookingRequest struct {
-	UserID        int     `json:"user_id"`
-	RoomID        int     `json:"room_id"`
-	CheckinDate   string  `json:"checkin_date"`
-	CheckoutDate  string  `json:"checkout_date"`
-	TotalPrice    float64 `json:"total_price"`
+	UserID       int            `json:"user_id"`
+	RoomID       int            `json:"room_id"`
+	CheckinDate  string         `json:"checkin_date"`
+	CheckoutDate string         `json:"checkout_date"`
+	TotalPrice   float64        `json:"total_price"`
+	Adults       int            `json:"adults"`
+	Children     int            `json:"children"`
+	Guests       []GuestRequest `json:"guests"`
+}
+
+type GuestRequest struct {
+	IsPrimary      bool   `json:"is_primary"`
+	FirstName      string `json:"first_name"`
+	LastName       string `json:"last_name"`
+	Email          string `json:"email"`
+	Phone          string `json:"phone"`
+	Nationality    string `json:"nationality"`
+	DateOfBirth    string `json:"date_of_birth"`
+	DocumentType   string `json:"document_type"`
+	DocumentNumber string `json:"document_number"`
+}
+
+type UpdatePartyRequest struct {
+	Adults   int `json:"adults"`
+	Children int `json:"children"`
+}
+
+type QuoteBookingRequest struct {
+	RoomID       int    `json:"room_id"`
+	CheckinDate  string `json:"checkin_date"`
+	CheckoutDate string `json:"checkout_date"`
+	Adults       int    `json:"adults"`
+	Children     int    `json:"children"`
+}
+
+type Bed struct {
+	Type  string `json:"type"`
+	Count int    `json:"count"`
 }
 
 type CreateRoomRequest struct {
-	HotelID       int     `json:"hotel_id"`
-	RoomNumber    string  `json:"room_number"`
-	RoomType      string  `json:"room_type"`
-	PricePerNight float64 `json:"price_per_night"`
-	Description   string  `json:"description"`
-	Status        string  `json:"status"`
+	HotelID          int     `json:"hotel_id"`
+	RoomNumber       string  `json:"room_number"`
+	RoomType         string  `json:"room_type"`
+	PricePerNight    float64 `json:"price_per_night"`
+	Description      string  `json:"description"`
+	Status           string  `json:"status"`
+	MaxAdults        *int    `json:"max_adults,omitempty"`
+	MaxChildren      *int    `json:"max_children,omitempty"`
+	BedConfiguration *[]Bed  `json:"bed_configuration,omitempty"`
 }
 
 type CreateHotelRequest struct {
@@ -49,14 +89,182 @@ type CreateHotelRequest struct {
 	Country     string `json:"country"`
 	PhoneNumber string `json:"phone_number"`
 	Email       string `json:"email"`
-}
 
-type CreateRefundRequest struct {
-	UserID    int `json:"user_id"`
-	BookingID int `json:"booking_id"`
+	HoldTTLMinutes             *int     `json:"hold_ttl_minutes,omitempty"`
+	FreeCancellationDays       *int     `json:"free_cancellation_days,omitempty"`
+	CancellationPenaltyPercent *float64 `json:"cancellation_penalty_percent,omitempty"`
 }
 
 type UpdateCheckinStatusRequest struct {
 	BookingID     int    `json:"booking_id"`
 	CheckinStatus string `json:"checkin_status"`
-}
\ No newline at end of file
+}
+
+type RatePlanRequest struct {
+	RoomID            int      `json:"room_id"`
+	Name              string   `json:"name"`
+	StartDate         *string  `json:"start_date"`
+	EndDate           *string  `json:"end_date"`
+	DaysOfWeek        []int64  `json:"days_of_week"`
+	PricePerNight     *float64 `json:"price_per_night"`
+	AdjustmentPercent *float64 `json:"adjustment_percent"`
+	Priority          int      `json:"priority"`
+}
+
+type AmenityRequest struct {
+	Code  string `json:"code"`
+	Name  string `json:"name"`
+	Scope string `json:"scope"`
+}
+
+type UpdateAmenitiesRequest struct {
+	Amenities []string `json:"amenities"`
+}
+
+type CancelBookingRequest struct {
+	UserID int `json:"user_id"`
+}
+
+type ModifyBookingRequest struct {
+	UserID       int     `json:"user_id"`
+	RoomID       *int    `json:"room_id,omitempty"`
+	CheckinDate  *string `json:"checkin_date,omitempty"`
+	CheckoutDate *string `json:"checkout_date,omitempty"`
+}
+
+type ReservationLineRequest struct {
+	RoomID       int            `json:"room_id"`
+	CheckinDate  string         `json:"checkin_date"`
+	CheckoutDate string         `json:"checkout_date"`
+	Adults       int            `json:"adults"`
+	Children     int            `json:"children"`
+	Guests       []GuestRequest `json:"guests"`
+}
+
+type CreateReservationRequest struct {
+	UserID     int                      `json:"user_id"`
+	Lines      []ReservationLineRequest `json:"lines"`
+	TotalPrice float64                  `json:"total_price"`
+}
+
+type RoomBlockRequest struct {
+	BlockType string `json:"block_type"`
+	StartDate string `json:"start_date"`
+	EndDate   string `json:"end_date"`
+	Reason    string `json:"reason"`
+}
+
+type UpdateRoomStatusRequest struct {
+	Status string `json:"status"`
+}
+
+type UpdateHotelRequest struct {
+	Name        *string `json:"name,omitempty"`
+	Address     *string `json:"address,omitempty"`
+	City        *string `json:"city,omitempty"`
+	Country     *string `json:"country,omitempty"`
+	PhoneNumber *string `json:"phone_number,omitempty"`
+	Email       *string `json:"email,omitempty"`
+
+	HoldTTLMinutes             *int     `json:"hold_ttl_minutes,omitempty"`
+	FreeCancellationDays       *int     `json:"free_cancellation_days,omitempty"`
+	CancellationPenaltyPercent *float64 `json:"cancellation_penalty_percent,omitempty"`
+}
+
+type UpdateRoomRequest struct {
+	RoomNumber       *string  `json:"room_number,omitempty"`
+	RoomType         *string  `json:"room_type,omitempty"`
+	PricePerNight    *float64 `json:"price_per_night,omitempty"`
+	Description      *string  `json:"description,omitempty"`
+	Status           *string  `json:"status,omitempty"`
+	MaxAdults        *int     `json:"max_adults,omitempty"`
+	MaxChildren      *int     `json:"max_children,omitempty"`
+	BedConfiguration *[]Bed   `json:"bed_configuration,omitempty"`
+}
+
+type UpdateUserHotelsRequest struct {
+	HotelIDs []int `json:"hotel_ids"`
+}
+
+type CreateUserRequest struct {
+	Username string `json:"username"`
+	Email    string `json:"email"`
+	Password string `json:"password"`
+	Role     string `json:"role"`
+	HotelIDs []int  `json:"hotel_ids,omitempty"`
+}
+
+type UpdateUserRoleRequest struct {
+	Role     string `json:"role"`
+	HotelIDs []int  `json:"hotel_ids,omitempty"`
+}
+
+type UpdateUserStatusRequest struct {
+	Disabled *bool `json:"disabled"`
+}
+
+type RefreshTokenRequest struct {
+	RefreshToken string `json:"refresh_token"`
+}
+
+type VerifyEmailRequest struct {
+	Token string `json:"token" query:"token"`
+}
+
+type EmailRequest struct {
+	Email string `json:"email"`
+}
+
+type ResetPasswordRequest struct {
+	Token    string `json:"token" form:"token"`
+	Password string `json:"password" form:"password"`
+}
+
+type LoginChallengeRequest struct {
+	ChallengeToken string `json:"challenge_token"`
+	Code           string `json:"code,omitempty"`
+	RecoveryCode   string `json:"recovery_code,omitempty"`
+}
+
+// TwoFactorRequest carries the caller's ID, filled in from the token, and
+// the code confirming the change.
+type TwoFactorRequest struct {
+	UserID       int    `json:"user_id"`
+	Code         string `json:"code,omitempty"`
+	RecoveryCode string `json:"recovery_code,omitempty"`
+}
+
+type UpdateProfileRequest struct {
+	Username *string `json:"username,omitempty"`
+	Email    *string `json:"email,omitempty"`
+}
+
+type ChangePasswordRequest struct {
+	SessionID       string `json:"session_id"`
+	CurrentPassword string `json:"current_password"`
+	NewPassword     string `json:"new_password"`
+}
+
+type DeleteAccountRequest struct {
+	Password string `json:"password"`
+}
+
+type LogoutRequest struct {
+	UserID    int    `json:"user_id"`
+	SessionID string `json:"session_id"`
+}
+
+// UserDataExport is everything the services store about a user, as
+// gathered for a personal data export. Each section is passed through as
+// the owning service returned it.
+type UserDataExport struct {
+	ExportedAt     time.Time       `json:"exported_at"`
+	UserID         int             `json:"user_id"`
+	Account        json.RawMessage `json:"account"`
+	LoginAttempts  json.RawMessage `json:"login_attempts"`
+	Bookings       json.RawMessage `json:"bookings"`
+	Reservations   json.RawMessage `json:"reservations"`
+	BookingChanges json.RawMessage `json:"booking_changes"`
+	Payments       json.RawMessage `json:"payments"`
+	Refunds        json.RawMessage `json:"refunds"`
+}
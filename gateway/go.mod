@@ -2,10 +2,12 @@ module api-gateway
 
 go 1.21.0
 
-require github.com/joho/godotenv v1.5.1
+require (
+	github.com/golang-jwt/jwt/v5 v5.2.1
+	github.com/joho/godotenv v1.5.1
+)
 
 require (
-	github.com/form3tech-oss/jwt-go v3.2.5+incompatible // indirect
 	github.com/labstack/echo/v4 v4.12.0 // indirect
 	github.com/labstack/gommon v0.4.2 // indirect
 	github.com/mattn/go-colorable v0.1.13 // indirect
@@ -0,0 +1,68 @@
+package handler
+
+import (
+	"api-gateway/dto"
+	"fmt"
+	"net/http"
+	"net/url"
+
+	"github.com/labstack/echo/v4"
+)
+
+// VerifyEmailHandler takes the token from the body, or from the query
+// string when the link from the email is opened.
+func VerifyEmailHandler(c echo.Context) error {
+	var req dto.VerifyEmailRequest
+	if err := c.Bind(&req); err != nil {
+		return c.JSON(http.StatusBadRequest, dto.ErrorResponse{Message: "Invalid request"})
+	}
+
+	return sendToUserService(c, http.MethodPost, fmt.Sprintf("%s/email/verify", userServiceURL), req)
+}
+
+func ResendVerificationEmailHandler(c echo.Context) error {
+	var req dto.EmailRequest
+	if err := c.Bind(&req); err != nil {
+		return c.JSON(http.StatusBadRequest, dto.ErrorResponse{Message: "Invalid request"})
+	}
+
+	return sendToUserService(c, http.MethodPost, fmt.Sprintf("%s/email/verify/resend", userServiceURL), req)
+}
+
+// ForgotPasswordHandler asks user-service to email a password reset link.
+func ForgotPasswordHandler(c echo.Context) error {
+	var req dto.EmailRequest
+	if err := c.Bind(&req); err != nil {
+		return c.JSON(http.StatusBadRequest, dto.ErrorResponse{Message: "Invalid request"})
+	}
+
+	return sendToUserService(c, http.MethodPost, fmt.Sprintf("%s/password/forgot", userServiceURL), req)
+}
+
+// ResetPasswordFormHandler serves the page the password reset link opens.
+// The page posts the new password back to ResetPasswordHandler.
+func ResetPasswordFormHandler(c echo.Context) error {
+	token := c.QueryParam("token")
+	if token == "" {
+		return c.JSON(http.StatusBadRequest, dto.ErrorResponse{Message: "token is required"})
+	}
+
+	status, body, err := callUserService(http.MethodGet, fmt.Sprintf("%s/password/reset?token=%s", userServiceURL, url.QueryEscape(token)), nil, clientHeaders(c))
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, dto.ErrorResponse{Message: "Failed to connect to user-service"})
+	}
+	if status != http.StatusOK {
+		return c.JSONBlob(status, body)
+	}
+
+	return c.HTMLBlob(status, body)
+}
+
+func ResetPasswordHandler(c echo.Context) error {
+	var req dto.ResetPasswordRequest
+	if err := c.Bind(&req); err != nil {
+		return c.JSON(http.StatusBadRequest, dto.ErrorResponse{Message: "Invalid request"})
+	}
+
+	return sendToUserService(c, http.MethodPost, fmt.Sprintf("%s/password/reset", userServiceURL), req)
+}
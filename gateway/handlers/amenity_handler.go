@@ -0,0 +1,58 @@
+package handler
+
+import (
+	"api-gateway/dto"
+	"fmt"
+	"net/http"
+
+	"github.com/labstack/echo/v4"
+)
+
+func ListAmenitiesHandler(c echo.Context) error {
+	url := fmt.Sprintf("%s/amenity?%s", BookingServiceURL, c.QueryString())
+	return sendToBookingService(c, http.MethodGet, url, nil)
+}
+
+func CreateAmenityHandler(c echo.Context) error {
+	var req dto.AmenityRequest
+	if err := c.Bind(&req); err != nil {
+		return c.JSON(http.StatusBadRequest, dto.ErrorResponse{Message: "Invalid request"})
+	}
+
+	return sendToBookingService(c, http.MethodPost, BookingServiceURL+"/amenity", req)
+}
+
+func UpdateAmenityHandler(c echo.Context) error {
+	var req dto.AmenityRequest
+	if err := c.Bind(&req); err != nil {
+		return c.JSON(http.StatusBadRequest, dto.ErrorResponse{Message: "Invalid request"})
+	}
+
+	url := fmt.Sprintf("%s/amenity/%s", BookingServiceURL, c.Param("id"))
+	return sendToBookingService(c, http.MethodPut, url, req)
+}
+
+func DeleteAmenityHandler(c echo.Context) error {
+	url := fmt.Sprintf("%s/amenity/%s", BookingServiceURL, c.Param("id"))
+	return sendToBookingService(c, http.MethodDelete, url, nil)
+}
+
+func UpdateHotelAmenitiesHandler(c echo.Context) error {
+	var req dto.UpdateAmenitiesRequest
+	if err := c.Bind(&req); err != nil {
+		return c.JSON(http.StatusBadRequest, dto.ErrorResponse{Message: "Invalid request"})
+	}
+
+	url := fmt.Sprintf("%s/hotel/%s/amenities", BookingServiceURL, c.Param("id"))
+	return sendToBookingService(c, http.MethodPut, url, req)
+}
+
+func UpdateRoomAmenitiesHandler(c echo.Context) error {
+	var req dto.UpdateAmenitiesRequest
+	if err := c.Bind(&req); err != nil {
+		return c.JSON(http.StatusBadRequest, dto.ErrorResponse{Message: "Invalid request"})
+	}
+
+	url := fmt.Sprintf("%s/room/%s/amenities", BookingServiceURL, c.Param("id"))
+	return sendToBookingService(c, http.MethodPut, url, req)
+}
@@ -30,7 +30,24 @@ func init() {
 
 func GetListHotelsHandler(c echo.Context) error {
 
-	resp, err := http.Get(BookingServiceURL + "/hotel")
+	url := fmt.Sprintf("%s/hotel?%s", BookingServiceURL, c.QueryString())
+	resp, err := http.Get(url)
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, dto.ErrorResponse{Message: "Failed to connect to booking service"})
+	}
+	defer resp.Body.Close()
+
+	respBody, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, dto.ErrorResponse{Message: "Failed to read response from booking service"})
+	}
+
+	return c.JSONBlob(resp.StatusCode, respBody)
+}
+
+func SearchHandler(c echo.Context) error {
+	url := fmt.Sprintf("%s/search?%s", BookingServiceURL, c.QueryString())
+	resp, err := http.Get(url)
 	if err != nil {
 		return c.JSON(http.StatusInternalServerError, dto.ErrorResponse{Message: "Failed to connect to booking service"})
 	}
@@ -63,8 +80,7 @@ func GetHotelsHandler(c echo.Context) error {
 }
 
 func ListRoomsByHotelIdHandler(c echo.Context) error {
-	hotelID := c.QueryParam("hotel_id")
-	url := fmt.Sprintf("%s/hotel/room?hotel_id=%s", BookingServiceURL, hotelID)
+	url := fmt.Sprintf("%s/hotel/room?%s", BookingServiceURL, c.QueryString())
 	resp, err := http.Get(url)
 	if err != nil {
 		return c.JSON(http.StatusInternalServerError, dto.ErrorResponse{Message: "Failed to connect to booking service"})
@@ -102,12 +118,38 @@ func CreateHotelHandler(c echo.Context) error {
 		return c.JSON(http.StatusBadRequest, dto.ErrorResponse{Message: "Invalid request"})
 	}
 
+	return sendToBookingService(c, http.MethodPost, BookingServiceURL+"/hotel", req)
+}
+
+func CreateRoomHandler(c echo.Context) error {
+	var req dto.CreateRoomRequest
+	if err := c.Bind(&req); err != nil {
+		return c.JSON(http.StatusBadRequest, dto.ErrorResponse{Message: "Invalid request"})
+	}
+
+	return sendToBookingService(c, http.MethodPost, BookingServiceURL+"/room", req)
+}
+
+func CreateBookingHandler(c echo.Context) error {
+	userID, ok := c.Get("id").(float64)
+	if !ok {
+		return c.JSON(http.StatusUnauthorized, dto.ErrorResponse{Message: "Unauthorized"})
+	}
+
+	var req dto.CreateBookingRequest
+	if err := c.Bind(&req); err != nil {
+		return c.JSON(http.StatusBadRequest, dto.ErrorResponse{Message: "Invalid request"})
+	}
+
+	req.UserID = int(userID)
+
 	jsonData, err := json.Marshal(req)
 	if err != nil {
 		return c.JSON(http.StatusInternalServerError, dto.ErrorResponse{Message: "Failed to process request data"})
 	}
 
-	resp, err := http.Post(BookingServiceURL+"/hotel", "application/json", bytes.NewBuffer(jsonData))
+	url := BookingServiceURL + "/booking"
+	resp, err := http.Post(url, "application/json", bytes.NewBuffer(jsonData))
 	if err != nil {
 		return c.JSON(http.StatusInternalServerError, dto.ErrorResponse{Message: "Failed to connect to booking service"})
 	}
@@ -121,18 +163,25 @@ func CreateHotelHandler(c echo.Context) error {
 	return c.JSONBlob(resp.StatusCode, respBody)
 }
 
-func CreateRoomHandler(c echo.Context) error {
-	var req dto.CreateRoomRequest
+func CreateReservationHandler(c echo.Context) error {
+	userID, ok := c.Get("id").(float64)
+	if !ok {
+		return c.JSON(http.StatusUnauthorized, dto.ErrorResponse{Message: "Unauthorized"})
+	}
+
+	var req dto.CreateReservationRequest
 	if err := c.Bind(&req); err != nil {
 		return c.JSON(http.StatusBadRequest, dto.ErrorResponse{Message: "Invalid request"})
 	}
 
+	req.UserID = int(userID)
+
 	jsonData, err := json.Marshal(req)
 	if err != nil {
 		return c.JSON(http.StatusInternalServerError, dto.ErrorResponse{Message: "Failed to process request data"})
 	}
 
-	url := BookingServiceURL + "/room"
+	url := BookingServiceURL + "/reservation"
 	resp, err := http.Post(url, "application/json", bytes.NewBuffer(jsonData))
 	if err != nil {
 		return c.JSON(http.StatusInternalServerError, dto.ErrorResponse{Message: "Failed to connect to booking service"})
@@ -147,25 +196,34 @@ func CreateRoomHandler(c echo.Context) error {
 	return c.JSONBlob(resp.StatusCode, respBody)
 }
 
-func CreateBookingHandler(c echo.Context) error {
-	userID, ok := c.Get("id").(float64)
-	if !ok {
-		return c.JSON(http.StatusUnauthorized, dto.ErrorResponse{Message: "Unauthorized"})
+func GetReservationHandler(c echo.Context) error {
+	url := fmt.Sprintf("%s/reservation/%s", BookingServiceURL, c.Param("id"))
+	resp, err := http.Get(url)
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, dto.ErrorResponse{Message: "Failed to connect to booking service"})
 	}
+	defer resp.Body.Close()
 
-	var req dto.CreateBookingRequest
+	respBody, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, dto.ErrorResponse{Message: "Failed to read response from booking service"})
+	}
+
+	return c.JSONBlob(resp.StatusCode, respBody)
+}
+
+func QuoteBookingHandler(c echo.Context) error {
+	var req dto.QuoteBookingRequest
 	if err := c.Bind(&req); err != nil {
 		return c.JSON(http.StatusBadRequest, dto.ErrorResponse{Message: "Invalid request"})
 	}
 
-	req.UserID = int(userID)
-
 	jsonData, err := json.Marshal(req)
 	if err != nil {
 		return c.JSON(http.StatusInternalServerError, dto.ErrorResponse{Message: "Failed to process request data"})
 	}
 
-	url := BookingServiceURL + "/booking"
+	url := BookingServiceURL + "/booking/quote"
 	resp, err := http.Post(url, "application/json", bytes.NewBuffer(jsonData))
 	if err != nil {
 		return c.JSON(http.StatusInternalServerError, dto.ErrorResponse{Message: "Failed to connect to booking service"})
@@ -180,27 +238,74 @@ func CreateBookingHandler(c echo.Context) error {
 	return c.JSONBlob(resp.StatusCode, respBody)
 }
 
-func UpdateCheckinStatusHandler(c echo.Context) error {
-	var req dto.UpdateCheckinStatusRequest
+func CancelBookingHandler(c echo.Context) error {
+	userID, ok := c.Get("id").(float64)
+	if !ok {
+		return c.JSON(http.StatusUnauthorized, dto.ErrorResponse{Message: "Unauthorized"})
+	}
+
+	jsonData, err := json.Marshal(dto.CancelBookingRequest{UserID: int(userID)})
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, dto.ErrorResponse{Message: "Failed to process request data"})
+	}
+
+	url := fmt.Sprintf("%s/booking/%s/cancel", BookingServiceURL, c.Param("booking_id"))
+	resp, err := http.Post(url, "application/json", bytes.NewBuffer(jsonData))
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, dto.ErrorResponse{Message: "Failed to connect to booking service"})
+	}
+	defer resp.Body.Close()
+
+	respBody, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, dto.ErrorResponse{Message: "Failed to read response from booking service"})
+	}
+
+	return c.JSONBlob(resp.StatusCode, respBody)
+}
 
+func ModifyBookingHandler(c echo.Context) error {
+	userID, ok := c.Get("id").(float64)
+	if !ok {
+		return c.JSON(http.StatusUnauthorized, dto.ErrorResponse{Message: "Unauthorized"})
+	}
+
+	var req dto.ModifyBookingRequest
 	if err := c.Bind(&req); err != nil {
 		return c.JSON(http.StatusBadRequest, dto.ErrorResponse{Message: "Invalid request"})
 	}
 
+	req.UserID = int(userID)
+
 	jsonData, err := json.Marshal(req)
 	if err != nil {
 		return c.JSON(http.StatusInternalServerError, dto.ErrorResponse{Message: "Failed to process request data"})
 	}
 
-	url := fmt.Sprintf("%s/booking/checkin-status", BookingServiceURL)
-	client := &http.Client{}
-	reqToBookingService, err := http.NewRequest(http.MethodPut, url, bytes.NewBuffer(jsonData))
+	url := fmt.Sprintf("%s/booking/%s", BookingServiceURL, c.Param("booking_id"))
+	reqToBookingService, err := http.NewRequest(http.MethodPatch, url, bytes.NewBuffer(jsonData))
 	if err != nil {
 		return c.JSON(http.StatusInternalServerError, dto.ErrorResponse{Message: "Failed to create request"})
 	}
 	reqToBookingService.Header.Set("Content-Type", "application/json")
 
-	resp, err := client.Do(reqToBookingService)
+	resp, err := http.DefaultClient.Do(reqToBookingService)
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, dto.ErrorResponse{Message: "Failed to connect to booking service"})
+	}
+	defer resp.Body.Close()
+
+	respBody, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, dto.ErrorResponse{Message: "Failed to read response from booking service"})
+	}
+
+	return c.JSONBlob(resp.StatusCode, respBody)
+}
+
+func GetBookingChangesHandler(c echo.Context) error {
+	url := fmt.Sprintf("%s/booking/detail/%s/changes", BookingServiceURL, c.Param("booking_id"))
+	resp, err := http.Get(url)
 	if err != nil {
 		return c.JSON(http.StatusInternalServerError, dto.ErrorResponse{Message: "Failed to connect to booking service"})
 	}
@@ -214,4 +319,13 @@ func UpdateCheckinStatusHandler(c echo.Context) error {
 	return c.JSONBlob(resp.StatusCode, respBody)
 }
 
+func UpdateCheckinStatusHandler(c echo.Context) error {
+	var req dto.UpdateCheckinStatusRequest
 
+	if err := c.Bind(&req); err != nil {
+		return c.JSON(http.StatusBadRequest, dto.ErrorResponse{Message: "Invalid request"})
+	}
+
+	url := fmt.Sprintf("%s/booking/checkin-status", BookingServiceURL)
+	return sendToBookingService(c, http.MethodPut, url, req)
+}
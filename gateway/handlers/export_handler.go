@@ -0,0 +1,227 @@
+package handler
+
+import (
+	"api-gateway/dto"
+	"archive/zip"
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/labstack/echo/v4"
+)
+
+// fetchExport reads one service's share of a data export into result.
+func fetchExport(url string, result interface{}) error {
+	resp, err := http.Get(url)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("%s returned status %d", url, resp.StatusCode)
+	}
+
+	return json.NewDecoder(resp.Body).Decode(result)
+}
+
+// ExportUserDataHandler gathers everything stored about the caller from
+// user-service, booking-service and payment-service and returns it as a
+// JSON download. With format=zip the archive holds the same JSON together
+// with one CSV file per section.
+func ExportUserDataHandler(c echo.Context) error {
+	userID, ok := c.Get("id").(float64)
+	if !ok {
+		return c.JSON(http.StatusUnauthorized, dto.ErrorResponse{Message: "Unauthorized"})
+	}
+
+	format := c.QueryParam("format")
+	if format == "" {
+		format = "json"
+	}
+	if format != "json" && format != "zip" {
+		return c.JSON(http.StatusBadRequest, dto.ErrorResponse{Message: "format must be json or zip"})
+	}
+
+	export := dto.UserDataExport{ExportedAt: time.Now().UTC(), UserID: int(userID)}
+
+	var account struct {
+		Account       json.RawMessage `json:"account"`
+		LoginAttempts json.RawMessage `json:"login_attempts"`
+	}
+	var bookings struct {
+		Bookings       json.RawMessage `json:"bookings"`
+		Reservations   json.RawMessage `json:"reservations"`
+		BookingChanges json.RawMessage `json:"booking_changes"`
+	}
+	var payments struct {
+		Payments json.RawMessage `json:"payments"`
+		Refunds  json.RawMessage `json:"refunds"`
+	}
+
+	sources := []struct {
+		url    string
+		result interface{}
+	}{
+		{fmt.Sprintf("%s/user/%d/export", userServiceURL, export.UserID), &account},
+		{fmt.Sprintf("%s/user/%d/export", BookingServiceURL, export.UserID), &bookings},
+		{fmt.Sprintf("%s/user/%d/export", PaymentServiceURL, export.UserID), &payments},
+	}
+
+	// The services are independent, so they are asked at the same time. An
+	// export is only useful when complete, so any failure fails it.
+	errs := make([]error, len(sources))
+	var wg sync.WaitGroup
+	for i, source := range sources {
+		wg.Add(1)
+		go func(i int, url string, result interface{}) {
+			defer wg.Done()
+			errs[i] = fetchExport(url, result)
+		}(i, source.url, source.result)
+	}
+	wg.Wait()
+
+	for _, err := range errs {
+		if err != nil {
+			c.Logger().Errorf("Failed to export data of user %d: %v", export.UserID, err)
+			return c.JSON(http.StatusBadGateway, dto.ErrorResponse{Message: "Failed to gather user data"})
+		}
+	}
+
+	export.Account = account.Account
+	export.LoginAttempts = account.LoginAttempts
+	export.Bookings = bookings.Bookings
+	export.Reservations = bookings.Reservations
+	export.BookingChanges = bookings.BookingChanges
+	export.Payments = payments.Payments
+	export.Refunds = payments.Refunds
+
+	data, err := json.MarshalIndent(export, "", "  ")
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, dto.ErrorResponse{Message: "Failed to build export"})
+	}
+
+	name := fmt.Sprintf("user-%d-export-%s", export.UserID, export.ExportedAt.Format("20060102"))
+	if format == "json" {
+		c.Response().Header().Set(echo.HeaderContentDisposition, fmt.Sprintf("attachment; filename=%q", name+".json"))
+		return c.Blob(http.StatusOK, echo.MIMEApplicationJSONCharsetUTF8, data)
+	}
+
+	archive, err := exportArchive(data, export)
+	if err != nil {
+		c.Logger().Errorf("Failed to build export archive of user %d: %v", export.UserID, err)
+		return c.JSON(http.StatusInternalServerError, dto.ErrorResponse{Message: "Failed to build export"})
+	}
+
+	c.Response().Header().Set(echo.HeaderContentDisposition, fmt.Sprintf("attachment; filename=%q", name+".zip"))
+	return c.Blob(http.StatusOK, "application/zip", archive)
+}
+
+// exportArchive zips the full JSON export with a CSV file for each section.
+func exportArchive(data []byte, export dto.UserDataExport) ([]byte, error) {
+	var buf bytes.Buffer
+	archive := zip.NewWriter(&buf)
+
+	w, err := archive.Create("export.json")
+	if err != nil {
+		return nil, err
+	}
+	if _, err := w.Write(data); err != nil {
+		return nil, err
+	}
+
+	sections := []struct {
+		name string
+		data json.RawMessage
+	}{
+		{"account", export.Account},
+		{"login_attempts", export.LoginAttempts},
+		{"bookings", export.Bookings},
+		{"reservations", export.Reservations},
+		{"booking_changes", export.BookingChanges},
+		{"payments", export.Payments},
+		{"refunds", export.Refunds},
+	}
+	for _, section := range sections {
+		table, err := jsonToCSV(section.data)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", section.name, err)
+		}
+		w, err := archive.Create(section.name + ".csv")
+		if err != nil {
+			return nil, err
+		}
+		if _, err := w.Write(table); err != nil {
+			return nil, err
+		}
+	}
+
+	if err := archive.Close(); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// jsonToCSV turns a JSON object or array of objects into CSV with one row
+// per object. The columns are the keys of all objects in alphabetical
+// order; nested objects and arrays are written as JSON.
+func jsonToCSV(data json.RawMessage) ([]byte, error) {
+	var rows []map[string]interface{}
+	if trimmed := bytes.TrimSpace(data); len(trimmed) > 0 && trimmed[0] == '{' {
+		data = json.RawMessage("[" + string(trimmed) + "]")
+	}
+
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.UseNumber()
+	if err := decoder.Decode(&rows); err != nil {
+		return nil, err
+	}
+
+	seen := map[string]bool{}
+	var columns []string
+	for _, row := range rows {
+		for key := range row {
+			if !seen[key] {
+				seen[key] = true
+				columns = append(columns, key)
+			}
+		}
+	}
+	sort.Strings(columns)
+
+	var buf bytes.Buffer
+	w := csv.NewWriter(&buf)
+	if err := w.Write(columns); err != nil {
+		return nil, err
+	}
+	for _, row := range rows {
+		record := make([]string, len(columns))
+		for i, column := range columns {
+			switch value := row[column].(type) {
+			case nil:
+			case string:
+				record[i] = value
+			case json.Number:
+				record[i] = value.String()
+			case bool:
+				record[i] = fmt.Sprint(value)
+			default:
+				encoded, err := json.Marshal(value)
+				if err != nil {
+					return nil, err
+				}
+				record[i] = string(encoded)
+			}
+		}
+		if err := w.Write(record); err != nil {
+			return nil, err
+		}
+	}
+	w.Flush()
+	return buf.Bytes(), w.Error()
+}
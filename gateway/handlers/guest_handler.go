@@ -0,0 +1,47 @@
+package handler
+
+import (
+	"api-gateway/dto"
+	"fmt"
+	"net/http"
+
+	"github.com/labstack/echo/v4"
+)
+
+// AddGuestHandler registers a guest on a booking ahead of check-in.
+func AddGuestHandler(c echo.Context) error {
+	var req dto.GuestRequest
+	if err := c.Bind(&req); err != nil {
+		return c.JSON(http.StatusBadRequest, dto.ErrorResponse{Message: "Invalid request"})
+	}
+
+	url := fmt.Sprintf("%s/booking/%s/guests", BookingServiceURL, c.Param("booking_id"))
+	return sendToBookingService(c, http.MethodPost, url, req)
+}
+
+func UpdateGuestHandler(c echo.Context) error {
+	var req dto.GuestRequest
+	if err := c.Bind(&req); err != nil {
+		return c.JSON(http.StatusBadRequest, dto.ErrorResponse{Message: "Invalid request"})
+	}
+
+	url := fmt.Sprintf("%s/booking/%s/guests/%s", BookingServiceURL, c.Param("booking_id"), c.Param("guest_id"))
+	return sendToBookingService(c, http.MethodPut, url, req)
+}
+
+func DeleteGuestHandler(c echo.Context) error {
+	url := fmt.Sprintf("%s/booking/%s/guests/%s", BookingServiceURL, c.Param("booking_id"), c.Param("guest_id"))
+	return sendToBookingService(c, http.MethodDelete, url, nil)
+}
+
+// UpdatePartyHandler changes how many adults and children stay under a
+// booking.
+func UpdatePartyHandler(c echo.Context) error {
+	var req dto.UpdatePartyRequest
+	if err := c.Bind(&req); err != nil {
+		return c.JSON(http.StatusBadRequest, dto.ErrorResponse{Message: "Invalid request"})
+	}
+
+	url := fmt.Sprintf("%s/booking/%s/party", BookingServiceURL, c.Param("booking_id"))
+	return sendToBookingService(c, http.MethodPut, url, req)
+}
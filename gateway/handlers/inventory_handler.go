@@ -0,0 +1,99 @@
+package handler
+
+import (
+	"api-gateway/dto"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/labstack/echo/v4"
+)
+
+// setStaffHeaders passes the caller's role and assigned hotels on to
+// booking-service, which limits hotel staff to their own hotels.
+func setStaffHeaders(req *http.Request, c echo.Context) {
+	if role, ok := c.Get("role").(string); ok {
+		req.Header.Set("X-User-Role", role)
+	}
+
+	if hotelIDs, ok := c.Get("hotel_ids").([]int); ok {
+		ids := make([]string, len(hotelIDs))
+		for i, id := range hotelIDs {
+			ids[i] = strconv.Itoa(id)
+		}
+		req.Header.Set("X-Hotel-IDs", strings.Join(ids, ","))
+	}
+}
+
+// sendToBookingService forwards a request to booking-service on behalf of
+// the caller and relays its response. body is marshaled as JSON unless it
+// is nil.
+func sendToBookingService(c echo.Context, method, url string, body interface{}) error {
+	var reqBody io.Reader
+	if body != nil {
+		jsonData, err := json.Marshal(body)
+		if err != nil {
+			return c.JSON(http.StatusInternalServerError, dto.ErrorResponse{Message: "Failed to process request data"})
+		}
+		reqBody = bytes.NewBuffer(jsonData)
+	}
+
+	reqToBookingService, err := http.NewRequest(method, url, reqBody)
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, dto.ErrorResponse{Message: "Failed to create request"})
+	}
+	if body != nil {
+		reqToBookingService.Header.Set("Content-Type", "application/json")
+	}
+	setStaffHeaders(reqToBookingService, c)
+
+	resp, err := http.DefaultClient.Do(reqToBookingService)
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, dto.ErrorResponse{Message: "Failed to connect to booking service"})
+	}
+	defer resp.Body.Close()
+
+	respBody, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, dto.ErrorResponse{Message: "Failed to read response from booking service"})
+	}
+
+	return c.JSONBlob(resp.StatusCode, respBody)
+}
+
+// UpdateHotelHandler serves both PUT and PATCH; booking-service decides
+// how missing fields are treated based on the method.
+func UpdateHotelHandler(c echo.Context) error {
+	var req dto.UpdateHotelRequest
+	if err := c.Bind(&req); err != nil {
+		return c.JSON(http.StatusBadRequest, dto.ErrorResponse{Message: "Invalid request"})
+	}
+
+	url := fmt.Sprintf("%s/hotel/%s", BookingServiceURL, c.Param("id"))
+	return sendToBookingService(c, c.Request().Method, url, req)
+}
+
+func DeleteHotelHandler(c echo.Context) error {
+	url := fmt.Sprintf("%s/hotel/%s", BookingServiceURL, c.Param("id"))
+	return sendToBookingService(c, http.MethodDelete, url, nil)
+}
+
+// UpdateRoomHandler serves both PUT and PATCH, like UpdateHotelHandler.
+func UpdateRoomHandler(c echo.Context) error {
+	var req dto.UpdateRoomRequest
+	if err := c.Bind(&req); err != nil {
+		return c.JSON(http.StatusBadRequest, dto.ErrorResponse{Message: "Invalid request"})
+	}
+
+	url := fmt.Sprintf("%s/room/%s", BookingServiceURL, c.Param("id"))
+	return sendToBookingService(c, c.Request().Method, url, req)
+}
+
+func DeleteRoomHandler(c echo.Context) error {
+	url := fmt.Sprintf("%s/room/%s", BookingServiceURL, c.Param("id"))
+	return sendToBookingService(c, http.MethodDelete, url, nil)
+}
@@ -0,0 +1,77 @@
+package handler
+
+import (
+	middleware "api-gateway/middlewares"
+	"encoding/json"
+	"fmt"
+	"net/http"
+
+	"github.com/labstack/echo/v4"
+)
+
+// fetchOwner reads a booking or reservation from booking-service into
+// result. It reports false when the resource does not exist.
+func fetchOwner(url string, result interface{}) (bool, error) {
+	resp, err := http.Get(url)
+	if err != nil {
+		return false, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode == http.StatusNotFound {
+		return false, nil
+	}
+	if resp.StatusCode != http.StatusOK {
+		return false, fmt.Errorf("booking service returned status %d", resp.StatusCode)
+	}
+
+	return true, json.NewDecoder(resp.Body).Decode(result)
+}
+
+// BookingOwner looks up the guest and hotel of a booking.
+func BookingOwner(id int) (*middleware.Owner, error) {
+	var booking struct {
+		UserID  int `json:"user_id"`
+		HotelID int `json:"hotel_id"`
+	}
+
+	found, err := fetchOwner(fmt.Sprintf("%s/booking/detail/%d", BookingServiceURL, id), &booking)
+	if err != nil || !found {
+		return nil, err
+	}
+
+	return &middleware.Owner{UserID: booking.UserID, HotelIDs: []int{booking.HotelID}}, nil
+}
+
+// ReservationOwner looks up the guest of a reservation and the hotels of
+// its bookings.
+func ReservationOwner(id int) (*middleware.Owner, error) {
+	var reservation struct {
+		UserID   int `json:"user_id"`
+		Bookings []struct {
+			HotelID int `json:"hotel_id"`
+		} `json:"bookings"`
+	}
+
+	found, err := fetchOwner(fmt.Sprintf("%s/reservation/%d", BookingServiceURL, id), &reservation)
+	if err != nil || !found {
+		return nil, err
+	}
+
+	owner := &middleware.Owner{UserID: reservation.UserID}
+	for _, booking := range reservation.Bookings {
+		owner.HotelIDs = append(owner.HotelIDs, booking.HotelID)
+	}
+	return owner, nil
+}
+
+// guestID returns the user a request acts for: the owner of the booking
+// when staff act on a guest's behalf, otherwise the caller.
+func guestID(c echo.Context) (int, bool) {
+	if owner, ok := c.Get("owner").(middleware.Owner); ok {
+		return owner.UserID, true
+	}
+
+	userID, ok := c.Get("id").(float64)
+	return int(userID), ok
+}
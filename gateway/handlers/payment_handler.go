@@ -9,7 +9,6 @@ import (
 	"log"
 	"net/http"
 	"os"
-	"strconv"
 
 	"github.com/joho/godotenv"
 	"github.com/labstack/echo/v4"
@@ -31,7 +30,7 @@ func init() {
 }
 
 func CreatePaymentHandler(c echo.Context) error {
-	userID, ok := c.Get("id").(float64)
+	userID, ok := guestID(c)
 	if !ok {
 		return c.JSON(http.StatusUnauthorized, dto.ErrorResponse{Message: "Unauthorized"})
 	}
@@ -41,7 +40,7 @@ func CreatePaymentHandler(c echo.Context) error {
 		return c.JSON(http.StatusBadRequest, dto.ErrorResponse{Message: "Invalid request"})
 	}
 
-	req.UserID = int(userID)
+	req.UserID = userID
 
 	jsonData, err := json.Marshal(req)
 	if err != nil {
@@ -63,38 +62,30 @@ func CreatePaymentHandler(c echo.Context) error {
 	return c.JSONBlob(resp.StatusCode, respBody)
 }
 
+// CreateRefundHandler serves the older refund endpoint. Refunds follow the
+// hotel's cancellation policy, so it cancels the booking the same way as
+// CancelBookingHandler.
 func CreateRefundHandler(c echo.Context) error {
-	userID, ok := c.Get("id").(float64)
+	userID, ok := guestID(c)
 	if !ok {
 		return c.JSON(http.StatusUnauthorized, dto.ErrorResponse{Message: "Unauthorized"})
 	}
 
-	bookingIDParam := c.Param("booking_id")
-	bookingID, err := strconv.Atoi(bookingIDParam)
-	if err != nil {
-		return c.JSON(http.StatusBadRequest, dto.ErrorResponse{Message: "Invalid booking_id"})
-	}
-
-	refundRequest := dto.CreateRefundRequest{
-		UserID:    int(userID),
-		BookingID: bookingID,
-	}
-
-	jsonData, err := json.Marshal(refundRequest)
+	jsonData, err := json.Marshal(dto.CancelBookingRequest{UserID: userID})
 	if err != nil {
 		return c.JSON(http.StatusInternalServerError, dto.ErrorResponse{Message: "Failed to process refund request"})
 	}
 
-	url := fmt.Sprintf("%s/refund", PaymentServiceURL)
+	url := fmt.Sprintf("%s/booking/%s/cancel", BookingServiceURL, c.Param("booking_id"))
 	resp, err := http.Post(url, "application/json", bytes.NewBuffer(jsonData))
 	if err != nil {
-		return c.JSON(http.StatusInternalServerError, dto.ErrorResponse{Message: "Failed to connect to payment service"})
+		return c.JSON(http.StatusInternalServerError, dto.ErrorResponse{Message: "Failed to connect to booking service"})
 	}
 	defer resp.Body.Close()
 
 	respBody, err := io.ReadAll(resp.Body)
 	if err != nil {
-		return c.JSON(http.StatusInternalServerError, dto.ErrorResponse{Message: "Failed to read response from payment service"})
+		return c.JSON(http.StatusInternalServerError, dto.ErrorResponse{Message: "Failed to read response from booking service"})
 	}
 
 	return c.JSONBlob(resp.StatusCode, respBody)
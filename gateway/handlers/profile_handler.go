@@ -0,0 +1,84 @@
+package handler
+
+import (
+	"api-gateway/dto"
+	middleware "api-gateway/middlewares"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/labstack/echo/v4"
+)
+
+func UpdateProfileHandler(c echo.Context) error {
+	userID, ok := c.Get("id").(float64)
+	if !ok {
+		return c.JSON(http.StatusUnauthorized, dto.ErrorResponse{Message: "Unauthorized"})
+	}
+
+	var req dto.UpdateProfileRequest
+	if err := c.Bind(&req); err != nil {
+		return c.JSON(http.StatusBadRequest, dto.ErrorResponse{Message: "Invalid request"})
+	}
+
+	url := fmt.Sprintf("%s/user/%.0f", userServiceURL, userID)
+	return sendToUserService(c, http.MethodPatch, url, req)
+}
+
+// ConfirmEmailChangeHandler switches an account to the new email address
+// the confirmation link was sent to. The link opens it with the token in
+// the query string.
+func ConfirmEmailChangeHandler(c echo.Context) error {
+	var req dto.VerifyEmailRequest
+	if err := c.Bind(&req); err != nil {
+		return c.JSON(http.StatusBadRequest, dto.ErrorResponse{Message: "Invalid request"})
+	}
+
+	return sendToUserService(c, http.MethodPost, fmt.Sprintf("%s/email/change/confirm", userServiceURL), req)
+}
+
+// ChangePasswordHandler changes the caller's password. The session making
+// the request stays signed in while all others are revoked.
+func ChangePasswordHandler(c echo.Context) error {
+	userID, ok := c.Get("id").(float64)
+	if !ok {
+		return c.JSON(http.StatusUnauthorized, dto.ErrorResponse{Message: "Unauthorized"})
+	}
+
+	var req dto.ChangePasswordRequest
+	if err := c.Bind(&req); err != nil {
+		return c.JSON(http.StatusBadRequest, dto.ErrorResponse{Message: "Invalid request"})
+	}
+	req.SessionID, _ = c.Get("session_id").(string)
+
+	url := fmt.Sprintf("%s/user/%.0f/password", userServiceURL, userID)
+	return sendToUserService(c, http.MethodPut, url, req)
+}
+
+// DeleteAccountHandler closes the caller's account. Like a logout, the
+// session is revoked here right away.
+func DeleteAccountHandler(c echo.Context) error {
+	userID, ok := c.Get("id").(float64)
+	if !ok {
+		return c.JSON(http.StatusUnauthorized, dto.ErrorResponse{Message: "Unauthorized"})
+	}
+	sessionID, _ := c.Get("session_id").(string)
+	expiresAt, _ := c.Get("token_expires_at").(time.Time)
+
+	var req dto.DeleteAccountRequest
+	if err := c.Bind(&req); err != nil {
+		return c.JSON(http.StatusBadRequest, dto.ErrorResponse{Message: "Invalid request"})
+	}
+
+	url := fmt.Sprintf("%s/user/%.0f", userServiceURL, userID)
+	status, respBody, err := callUserService(http.MethodDelete, url, req, clientHeaders(c))
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, dto.ErrorResponse{Message: "Failed to connect to user-service"})
+	}
+
+	if status == http.StatusOK {
+		middleware.RevokeSession(sessionID, expiresAt)
+	}
+
+	return c.JSONBlob(status, respBody)
+}
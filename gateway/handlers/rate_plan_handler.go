@@ -0,0 +1,38 @@
+package handler
+
+import (
+	"api-gateway/dto"
+	"fmt"
+	"net/http"
+
+	"github.com/labstack/echo/v4"
+)
+
+func ListRatePlansHandler(c echo.Context) error {
+	url := fmt.Sprintf("%s/room/%s/rate-plan", BookingServiceURL, c.Param("id"))
+	return sendToBookingService(c, http.MethodGet, url, nil)
+}
+
+func CreateRatePlanHandler(c echo.Context) error {
+	var req dto.RatePlanRequest
+	if err := c.Bind(&req); err != nil {
+		return c.JSON(http.StatusBadRequest, dto.ErrorResponse{Message: "Invalid request"})
+	}
+
+	return sendToBookingService(c, http.MethodPost, BookingServiceURL+"/rate-plan", req)
+}
+
+func UpdateRatePlanHandler(c echo.Context) error {
+	var req dto.RatePlanRequest
+	if err := c.Bind(&req); err != nil {
+		return c.JSON(http.StatusBadRequest, dto.ErrorResponse{Message: "Invalid request"})
+	}
+
+	url := fmt.Sprintf("%s/rate-plan/%s", BookingServiceURL, c.Param("id"))
+	return sendToBookingService(c, http.MethodPut, url, req)
+}
+
+func DeleteRatePlanHandler(c echo.Context) error {
+	url := fmt.Sprintf("%s/rate-plan/%s", BookingServiceURL, c.Param("id"))
+	return sendToBookingService(c, http.MethodDelete, url, nil)
+}
@@ -0,0 +1,39 @@
+package handler
+
+import (
+	"api-gateway/dto"
+	"fmt"
+	"net/http"
+
+	"github.com/labstack/echo/v4"
+)
+
+func UpdateRoomStatusHandler(c echo.Context) error {
+	var req dto.UpdateRoomStatusRequest
+	if err := c.Bind(&req); err != nil {
+		return c.JSON(http.StatusBadRequest, dto.ErrorResponse{Message: "Invalid request"})
+	}
+
+	url := fmt.Sprintf("%s/room/%s/status", BookingServiceURL, c.Param("id"))
+	return sendToBookingService(c, http.MethodPut, url, req)
+}
+
+func ListRoomBlocksHandler(c echo.Context) error {
+	url := fmt.Sprintf("%s/room/%s/block", BookingServiceURL, c.Param("id"))
+	return sendToBookingService(c, http.MethodGet, url, nil)
+}
+
+func CreateRoomBlockHandler(c echo.Context) error {
+	var req dto.RoomBlockRequest
+	if err := c.Bind(&req); err != nil {
+		return c.JSON(http.StatusBadRequest, dto.ErrorResponse{Message: "Invalid request"})
+	}
+
+	url := fmt.Sprintf("%s/room/%s/block", BookingServiceURL, c.Param("id"))
+	return sendToBookingService(c, http.MethodPost, url, req)
+}
+
+func DeleteRoomBlockHandler(c echo.Context) error {
+	url := fmt.Sprintf("%s/room/block/%s", BookingServiceURL, c.Param("id"))
+	return sendToBookingService(c, http.MethodDelete, url, nil)
+}
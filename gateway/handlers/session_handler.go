@@ -0,0 +1,43 @@
+package handler
+
+import (
+	"api-gateway/dto"
+	middleware "api-gateway/middlewares"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/labstack/echo/v4"
+)
+
+func RefreshTokenHandler(c echo.Context) error {
+	var req dto.RefreshTokenRequest
+	if err := c.Bind(&req); err != nil {
+		return c.JSON(http.StatusBadRequest, dto.ErrorResponse{Message: "Invalid request"})
+	}
+
+	return sendToUserService(c, http.MethodPost, fmt.Sprintf("%s/token/refresh", userServiceURL), req)
+}
+
+// LogoutHandler ends the caller's session. The session is also revoked in
+// this gateway right away rather than at the next revocation sync.
+func LogoutHandler(c echo.Context) error {
+	userID, ok := c.Get("id").(float64)
+	if !ok {
+		return c.JSON(http.StatusUnauthorized, dto.ErrorResponse{Message: "Unauthorized"})
+	}
+	sessionID, _ := c.Get("session_id").(string)
+	expiresAt, _ := c.Get("token_expires_at").(time.Time)
+
+	req := dto.LogoutRequest{UserID: int(userID), SessionID: sessionID}
+	status, respBody, err := callUserService(http.MethodPost, fmt.Sprintf("%s/logout", userServiceURL), req, clientHeaders(c))
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, dto.ErrorResponse{Message: "Failed to connect to user-service"})
+	}
+
+	if status == http.StatusOK {
+		middleware.RevokeSession(sessionID, expiresAt)
+	}
+
+	return c.JSONBlob(status, respBody)
+}
@@ -0,0 +1,69 @@
+package handler
+
+import (
+	"api-gateway/dto"
+	"fmt"
+	"net/http"
+
+	"github.com/labstack/echo/v4"
+)
+
+// VerifyLoginChallengeHandler is the second login step, which exchanges
+// the challenge token and a code for the session tokens.
+func VerifyLoginChallengeHandler(c echo.Context) error {
+	var req dto.LoginChallengeRequest
+	if err := c.Bind(&req); err != nil {
+		return c.JSON(http.StatusBadRequest, dto.ErrorResponse{Message: "Invalid request"})
+	}
+
+	return sendToUserService(c, http.MethodPost, fmt.Sprintf("%s/login/2fa", userServiceURL), req)
+}
+
+// SetupLoginChallengeHandler generates the TOTP secret for users who have
+// to set up two-factor authentication before they can log in.
+func SetupLoginChallengeHandler(c echo.Context) error {
+	var req dto.LoginChallengeRequest
+	if err := c.Bind(&req); err != nil {
+		return c.JSON(http.StatusBadRequest, dto.ErrorResponse{Message: "Invalid request"})
+	}
+
+	return sendToUserService(c, http.MethodPost, fmt.Sprintf("%s/login/2fa/setup", userServiceURL), req)
+}
+
+// sendTwoFactorRequest forwards a change to the caller's own second factor.
+func sendTwoFactorRequest(c echo.Context, path string) error {
+	userID, ok := c.Get("id").(float64)
+	if !ok {
+		return c.JSON(http.StatusUnauthorized, dto.ErrorResponse{Message: "Unauthorized"})
+	}
+
+	var req dto.TwoFactorRequest
+	if err := c.Bind(&req); err != nil {
+		return c.JSON(http.StatusBadRequest, dto.ErrorResponse{Message: "Invalid request"})
+	}
+	req.UserID = int(userID)
+
+	return sendToUserService(c, http.MethodPost, fmt.Sprintf("%s%s", userServiceURL, path), req)
+}
+
+func SetupTwoFactorHandler(c echo.Context) error {
+	return sendTwoFactorRequest(c, "/2fa/setup")
+}
+
+func EnableTwoFactorHandler(c echo.Context) error {
+	return sendTwoFactorRequest(c, "/2fa/enable")
+}
+
+func DisableTwoFactorHandler(c echo.Context) error {
+	return sendTwoFactorRequest(c, "/2fa/disable")
+}
+
+func RegenerateRecoveryCodesHandler(c echo.Context) error {
+	return sendTwoFactorRequest(c, "/2fa/recovery-codes")
+}
+
+// ResetTwoFactorHandler removes the second factor of a user who lost it.
+func ResetTwoFactorHandler(c echo.Context) error {
+	url := fmt.Sprintf("%s/admin/users/%s/2fa", userServiceURL, c.Param("id"))
+	return sendToUserService(c, http.MethodDelete, url, nil)
+}
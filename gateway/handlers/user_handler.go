@@ -55,6 +55,8 @@ func Register(c echo.Context) error {
 }
 
 
+// Login forwards the client's address and user agent, which user-service
+// uses to throttle failed logins and record them for auditing.
 func Login(c echo.Context) error {
 	var req dto.LoginUserRequest
 
@@ -62,12 +64,18 @@ func Login(c echo.Context) error {
 		return c.JSON(http.StatusBadRequest, dto.ErrorResponse{Message: "Invalid request"})
 	}
 
-	jsonData, err := json.Marshal(req)
-	if err != nil {
-		return c.JSON(http.StatusInternalServerError, dto.ErrorResponse{Message: "Failed to process request"})
+	return sendToUserService(c, http.MethodPost, fmt.Sprintf("%s/login", userServiceURL), req)
+}
+
+func GetUserByIDHandler(c echo.Context) error {
+	idFloat, ok := c.Get("id").(float64)
+	if !ok {
+		return c.JSON(http.StatusUnauthorized, dto.ErrorResponse{Message: "Unauthorized"})
 	}
 
-	resp, err := http.Post(fmt.Sprintf("%s/login", userServiceURL), "application/json", bytes.NewBuffer(jsonData))
+	userID := fmt.Sprintf("%.0f", idFloat)
+
+	resp, err := http.Get(fmt.Sprintf("%s/user/%s", userServiceURL, userID))
 	if err != nil {
 		return c.JSON(http.StatusInternalServerError, dto.ErrorResponse{Message: "Failed to connect to user-service"})
 	}
@@ -81,28 +89,127 @@ func Login(c echo.Context) error {
 	return c.JSONBlob(resp.StatusCode, respBody)
 }
 
-func GetUserByIDHandler(c echo.Context) error {
-	idFloat, ok := c.Get("id").(float64)
-	if !ok {
-		return c.JSON(http.StatusUnauthorized, dto.ErrorResponse{Message: "Unauthorized"})
+
+// clientHeaders identifies the client a request to user-service is made
+// for.
+func clientHeaders(c echo.Context) http.Header {
+	header := http.Header{}
+	header.Set(echo.HeaderXRealIP, c.RealIP())
+	header.Set("User-Agent", c.Request().UserAgent())
+	return header
+}
+
+// callUserService sends a request to user-service and returns its status
+// and body. body is marshaled as JSON unless it is nil.
+func callUserService(method, url string, body interface{}, header http.Header) (int, []byte, error) {
+	var reqBody io.Reader
+	if body != nil {
+		jsonData, err := json.Marshal(body)
+		if err != nil {
+			return 0, nil, err
+		}
+		reqBody = bytes.NewBuffer(jsonData)
 	}
 
-	userID := fmt.Sprintf("%.0f", idFloat)
+	reqToUserService, err := http.NewRequest(method, url, reqBody)
+	if err != nil {
+		return 0, nil, err
+	}
+	for name, values := range header {
+		reqToUserService.Header[name] = values
+	}
+	if body != nil {
+		reqToUserService.Header.Set("Content-Type", "application/json")
+	}
 
-	resp, err := http.Get(fmt.Sprintf("%s/user/%s", userServiceURL, userID))
+	resp, err := http.DefaultClient.Do(reqToUserService)
 	if err != nil {
-		return c.JSON(http.StatusInternalServerError, dto.ErrorResponse{Message: "Failed to connect to user-service"})
+		return 0, nil, err
 	}
 	defer resp.Body.Close()
 
 	respBody, err := io.ReadAll(resp.Body)
 	if err != nil {
-		return c.JSON(http.StatusInternalServerError, dto.ErrorResponse{Message: "Failed to read response from user-service"})
+		return 0, nil, err
 	}
+	return resp.StatusCode, respBody, nil
+}
 
-	return c.JSONBlob(resp.StatusCode, respBody)
+// sendToUserService forwards a request to user-service and relays its
+// response.
+func sendToUserService(c echo.Context, method, url string, body interface{}) error {
+	status, respBody, err := callUserService(method, url, body, clientHeaders(c))
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, dto.ErrorResponse{Message: "Failed to connect to user-service"})
+	}
+
+	return c.JSONBlob(status, respBody)
+}
+
+// UpdateUserHotelsHandler assigns a hotel_manager or front_desk user to the
+// hotels they work at.
+func UpdateUserHotelsHandler(c echo.Context) error {
+	var req dto.UpdateUserHotelsRequest
+	if err := c.Bind(&req); err != nil {
+		return c.JSON(http.StatusBadRequest, dto.ErrorResponse{Message: "Invalid request"})
+	}
+
+	url := fmt.Sprintf("%s/user/%s/hotels", userServiceURL, c.Param("id"))
+	return sendToUserService(c, http.MethodPut, url, req)
+}
+
+// CreateUserHandler creates an account with any role, such as hotel staff
+// or another administrator.
+func CreateUserHandler(c echo.Context) error {
+	var req dto.CreateUserRequest
+	if err := c.Bind(&req); err != nil {
+		return c.JSON(http.StatusBadRequest, dto.ErrorResponse{Message: "Invalid request"})
+	}
+
+	return sendToUserService(c, http.MethodPost, fmt.Sprintf("%s/admin/users", userServiceURL), req)
+}
+
+// ListUsersHandler lists accounts, passing the search, role, disabled,
+// limit and cursor query parameters through.
+func ListUsersHandler(c echo.Context) error {
+	url := fmt.Sprintf("%s/admin/users?%s", userServiceURL, c.QueryString())
+	return sendToUserService(c, http.MethodGet, url, nil)
 }
 
+// UpdateUserRoleHandler changes the role and hotel assignments of an account.
+func UpdateUserRoleHandler(c echo.Context) error {
+	var req dto.UpdateUserRoleRequest
+	if err := c.Bind(&req); err != nil {
+		return c.JSON(http.StatusBadRequest, dto.ErrorResponse{Message: "Invalid request"})
+	}
+
+	url := fmt.Sprintf("%s/admin/users/%s/role", userServiceURL, c.Param("id"))
+	return sendToUserService(c, http.MethodPut, url, req)
+}
+
+// UnlockUserHandler lifts a lockout caused by failed logins.
+func UnlockUserHandler(c echo.Context) error {
+	url := fmt.Sprintf("%s/admin/users/%s/unlock", userServiceURL, c.Param("id"))
+	return sendToUserService(c, http.MethodPost, url, nil)
+}
+
+// ListLoginAttemptsHandler lists the login audit trail, passing the
+// user_id, email, ip, outcome, limit and cursor query parameters through.
+func ListLoginAttemptsHandler(c echo.Context) error {
+	url := fmt.Sprintf("%s/admin/login-attempts?%s", userServiceURL, c.QueryString())
+	return sendToUserService(c, http.MethodGet, url, nil)
+}
+
+// UpdateUserStatusHandler disables or re-enables an account.
+func UpdateUserStatusHandler(c echo.Context) error {
+	var req dto.UpdateUserStatusRequest
+	if err := c.Bind(&req); err != nil {
+		return c.JSON(http.StatusBadRequest, dto.ErrorResponse{Message: "Invalid request"})
+	}
+
+	url := fmt.Sprintf("%s/admin/users/%s/status", userServiceURL, c.Param("id"))
+	return sendToUserService(c, http.MethodPut, url, req)
+}
 
 func GetListBooking(c echo.Context) error {
 	userID, ok := c.Get("id").(float64)
@@ -110,7 +217,7 @@ func GetListBooking(c echo.Context) error {
 		return c.JSON(http.StatusUnauthorized, dto.ErrorResponse{Message: "Unauthorized"})
 	}
 
-	url := fmt.Sprintf("%s/booking/%d", BookingServiceURL, int(userID))
+	url := fmt.Sprintf("%s/booking/%d?%s", BookingServiceURL, int(userID), c.QueryString())
 	resp, err := http.Get(url)
 	if err != nil {
 		return c.JSON(http.StatusInternalServerError, dto.ErrorResponse{Message: "Failed to connect to booking service"})
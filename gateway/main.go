@@ -1,6 +1,7 @@
 package main
 
 import (
+	middleware "api-gateway/middlewares"
 	"api-gateway/router"
 	"log"
 	"os"
@@ -10,9 +11,14 @@ import (
 
 func main() {
 	e := echo.New()
+	// Only trust X-Forwarded-For when it was added by a proxy on a private
+	// network, so clients cannot pick the address login throttling uses.
+	e.IPExtractor = echo.ExtractIPFromXFFHeader()
 
 	router.InitRoutes(e)
 
+	middleware.StartRevocationSync(os.Getenv("USER_SERVICE_URL") + "/token/revocations")
+
 	port := os.Getenv("GATEWAY_PORT")
 	if port == "" {
 		port = "8080"
@@ -4,12 +4,10 @@ import (
 	"log"
 	"strings"
 
-	"github.com/form3tech-oss/jwt-go"
+	"github.com/golang-jwt/jwt/v5"
 	"github.com/labstack/echo/v4"
 )
 
-var jwtSecret = []byte("rahasia")
-
 func Authentication(next echo.HandlerFunc) echo.HandlerFunc {
 	return func(c echo.Context) error {
 		authHeader := c.Request().Header.Get("Authorization")
@@ -26,9 +24,8 @@ func Authentication(next echo.HandlerFunc) echo.HandlerFunc {
 			})
 		}
 
-		token, err := jwt.Parse(tokenString, func(t *jwt.Token) (interface{}, error) {
-			return jwtSecret, nil
-		})
+		token, err := jwt.Parse(tokenString, loadKeySet().keyFunc,
+			jwt.WithValidMethods([]string{"HS256", "RS256", "ES256"}), jwt.WithExpirationRequired())
 
 		if err != nil || !token.Valid {
 			return c.JSON(401, echo.Map{
@@ -60,8 +57,37 @@ func Authentication(next echo.HandlerFunc) echo.HandlerFunc {
 			})
 		}
 
+		// Every access token belongs to a login session, which is what
+		// logout revokes.
+		sessionID, ok := claims["sid"].(string)
+		if !ok || isSessionRevoked(sessionID) {
+			return c.JSON(401, echo.Map{
+				"message": "unauthorized",
+			})
+		}
+
+		expiresAt, err := claims.GetExpirationTime()
+		if err != nil || expiresAt == nil {
+			return c.JSON(401, echo.Map{
+				"message": "unauthorized",
+			})
+		}
+
 		c.Set("id", userID)
 		c.Set("role", role)
+		c.Set("session_id", sessionID)
+		c.Set("token_expires_at", expiresAt.Time)
+
+		// Hotel staff tokens list the hotels the user is assigned to.
+		if rawHotelIDs, ok := claims["hotel_ids"].([]interface{}); ok {
+			hotelIDs := make([]int, 0, len(rawHotelIDs))
+			for _, rawID := range rawHotelIDs {
+				if id, ok := rawID.(float64); ok {
+					hotelIDs = append(hotelIDs, int(id))
+				}
+			}
+			c.Set("hotel_ids", hotelIDs)
+		}
 
 		return next(c)
 	}
@@ -90,3 +116,21 @@ func UserAuth(next echo.HandlerFunc) echo.HandlerFunc {
 		return next(c)
 	}
 }
+
+// RoleAuth only lets through users with one of the given roles. Hotel staff
+// are further limited to their own hotels by booking-service.
+func RoleAuth(roles ...string) echo.MiddlewareFunc {
+	return func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(c echo.Context) error {
+			role, _ := c.Get("role").(string)
+			for _, allowed := range roles {
+				if role == allowed {
+					return next(c)
+				}
+			}
+			return c.JSON(403, echo.Map{
+				"message": "forbidden",
+			})
+		}
+	}
+}
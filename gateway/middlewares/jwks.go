@@ -0,0 +1,240 @@
+package middleware
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rsa"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"math/big"
+	"net/http"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+const (
+	// jwksCacheTTL is how long fetched keys are trusted before the key set
+	// is fetched again.
+	jwksCacheTTL = 10 * time.Minute
+
+	// jwksMinRefreshInterval limits refetches triggered by tokens with an
+	// unknown kid, so forged tokens cannot flood user-service.
+	jwksMinRefreshInterval = 30 * time.Second
+)
+
+// keySet resolves the key a token was signed with. Public keys come from
+// the user-service JWKS endpoint and are cached; HS256 secrets, which are
+// never published, come from JWT_KEYS.
+type keySet struct {
+	url     string
+	client  *http.Client
+	secrets map[string][]byte
+
+	mu          sync.Mutex
+	keys        map[string]interface{}
+	fetchedAt   time.Time
+	attemptedAt time.Time
+}
+
+var (
+	tokenKeys     *keySet
+	tokenKeysOnce sync.Once
+)
+
+// loadKeySet builds the key set from the environment on first use.
+// JWKS_URL defaults to the JWKS endpoint of USER_SERVICE_URL.
+func loadKeySet() *keySet {
+	tokenKeysOnce.Do(func() {
+		url := os.Getenv("JWKS_URL")
+		if url == "" {
+			url = os.Getenv("USER_SERVICE_URL") + "/.well-known/jwks.json"
+		}
+
+		secrets, err := parseSharedSecrets(os.Getenv("JWT_KEYS"))
+		if err != nil {
+			log.Fatalf("Failed to parse JWT_KEYS: %v\n", err)
+		}
+
+		tokenKeys = &keySet{
+			url:     url,
+			client:  &http.Client{Timeout: 5 * time.Second},
+			secrets: secrets,
+			keys:    map[string]interface{}{},
+		}
+	})
+	return tokenKeys
+}
+
+// parseSharedSecrets reads the HS256 entries of JWT_KEYS, which uses the
+// same format as user-service. Asymmetric entries are ignored since their
+// public keys are published through JWKS.
+func parseSharedSecrets(raw string) (map[string][]byte, error) {
+	secrets := map[string][]byte{}
+	if raw == "" {
+		return secrets, nil
+	}
+
+	var configs []struct {
+		ID     string `json:"kid"`
+		Alg    string `json:"alg"`
+		Secret string `json:"secret"`
+	}
+	if err := json.Unmarshal([]byte(raw), &configs); err != nil {
+		return nil, err
+	}
+
+	for _, cfg := range configs {
+		if cfg.Alg == "HS256" && cfg.ID != "" && cfg.Secret != "" {
+			secrets[cfg.ID] = []byte(cfg.Secret)
+		}
+	}
+	return secrets, nil
+}
+
+// keyFunc is the jwt.Keyfunc for tokens issued by user-service. It makes
+// sure the token's algorithm matches the type of the key its kid names.
+func (s *keySet) keyFunc(token *jwt.Token) (interface{}, error) {
+	kid, _ := token.Header["kid"].(string)
+	if kid == "" {
+		return nil, errors.New("token has no kid")
+	}
+
+	key, err := s.key(kid)
+	if err != nil {
+		return nil, err
+	}
+
+	var ok bool
+	switch key.(type) {
+	case []byte:
+		_, ok = token.Method.(*jwt.SigningMethodHMAC)
+	case *rsa.PublicKey:
+		_, ok = token.Method.(*jwt.SigningMethodRSA)
+	case *ecdsa.PublicKey:
+		_, ok = token.Method.(*jwt.SigningMethodECDSA)
+	}
+	if !ok {
+		return nil, fmt.Errorf("unexpected signing method %s for key %q", token.Method.Alg(), kid)
+	}
+	return key, nil
+}
+
+func (s *keySet) key(kid string) (interface{}, error) {
+	if secret, ok := s.secrets[kid]; ok {
+		return secret, nil
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	key, ok := s.keys[kid]
+	stale := time.Since(s.fetchedAt) > jwksCacheTTL
+	if (!ok || stale) && time.Since(s.attemptedAt) > jwksMinRefreshInterval {
+		s.attemptedAt = time.Now()
+		if err := s.refresh(); err != nil {
+			// Keep serving the cached keys while user-service is down.
+			log.Println("Error fetching JWKS:", err)
+		}
+		key, ok = s.keys[kid]
+	}
+
+	if !ok {
+		return nil, fmt.Errorf("unknown key %q", kid)
+	}
+	return key, nil
+}
+
+// refresh replaces the cached keys with the current JWKS. Callers must
+// hold s.mu.
+func (s *keySet) refresh() error {
+	resp, err := s.client.Get(s.url)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("JWKS endpoint returned status %d", resp.StatusCode)
+	}
+
+	var jwks struct {
+		Keys []jsonWebKey `json:"keys"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&jwks); err != nil {
+		return err
+	}
+
+	keys := map[string]interface{}{}
+	for _, jwk := range jwks.Keys {
+		key, err := jwk.publicKey()
+		if err != nil {
+			log.Printf("Skipping JWKS key %q: %v\n", jwk.Kid, err)
+			continue
+		}
+		keys[jwk.Kid] = key
+	}
+
+	s.keys = keys
+	s.fetchedAt = time.Now()
+	return nil
+}
+
+type jsonWebKey struct {
+	Kty string `json:"kty"`
+	Kid string `json:"kid"`
+	N   string `json:"n"`
+	E   string `json:"e"`
+	Crv string `json:"crv"`
+	X   string `json:"x"`
+	Y   string `json:"y"`
+}
+
+func (k jsonWebKey) publicKey() (interface{}, error) {
+	switch k.Kty {
+	case "RSA":
+		n, err := decodeBigInt(k.N)
+		if err != nil {
+			return nil, err
+		}
+		e, err := decodeBigInt(k.E)
+		if err != nil {
+			return nil, err
+		}
+		if !e.IsInt64() {
+			return nil, errors.New("RSA exponent is too large")
+		}
+		return &rsa.PublicKey{N: n, E: int(e.Int64())}, nil
+	case "EC":
+		if k.Crv != "P-256" {
+			return nil, fmt.Errorf("unsupported curve %q", k.Crv)
+		}
+		x, err := decodeBigInt(k.X)
+		if err != nil {
+			return nil, err
+		}
+		y, err := decodeBigInt(k.Y)
+		if err != nil {
+			return nil, err
+		}
+		if !elliptic.P256().IsOnCurve(x, y) {
+			return nil, errors.New("point is not on curve P-256")
+		}
+		return &ecdsa.PublicKey{Curve: elliptic.P256(), X: x, Y: y}, nil
+	default:
+		return nil, fmt.Errorf("unsupported key type %q", k.Kty)
+	}
+}
+
+func decodeBigInt(value string) (*big.Int, error) {
+	data, err := base64.RawURLEncoding.DecodeString(value)
+	if err != nil {
+		return nil, err
+	}
+	return new(big.Int).SetBytes(data), nil
+}
@@ -0,0 +1,128 @@
+package middleware
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"log"
+	"strconv"
+
+	"github.com/labstack/echo/v4"
+)
+
+// Owner is who a booking or reservation belongs to: the guest who made it
+// and the hotels it is at.
+type Owner struct {
+	UserID   int
+	HotelIDs []int
+}
+
+// OwnerLookup fetches the owner of a resource. It returns nil without an
+// error when the resource does not exist.
+type OwnerLookup func(id int) (*Owner, error)
+
+// IDSource extracts the ID of the resource a request refers to. A zero ID
+// means the request does not refer to such a resource.
+type IDSource func(c echo.Context) (int, error)
+
+// FromParam reads the resource ID from a path parameter.
+func FromParam(name string) IDSource {
+	return func(c echo.Context) (int, error) {
+		return strconv.Atoi(c.Param(name))
+	}
+}
+
+// FromBody reads the resource ID from a field of the JSON request body. The
+// body is restored afterwards so the handler can still bind it.
+func FromBody(field string) IDSource {
+	return func(c echo.Context) (int, error) {
+		body, err := io.ReadAll(c.Request().Body)
+		if err != nil {
+			return 0, err
+		}
+		c.Request().Body = io.NopCloser(bytes.NewReader(body))
+
+		var fields map[string]json.RawMessage
+		if err := json.Unmarshal(body, &fields); err != nil {
+			return 0, err
+		}
+
+		raw, ok := fields[field]
+		if !ok || string(raw) == "null" {
+			return 0, nil
+		}
+
+		var id int
+		err = json.Unmarshal(raw, &id)
+		return id, err
+	}
+}
+
+// CanAccess reports whether the authenticated caller may act on a resource
+// owned by owner. Guests may only reach their own bookings, admins reach
+// every booking and hotel staff reach the bookings at their hotels. A
+// reservation spanning several hotels is only open to staff assigned to
+// all of them, since it exposes the bookings at each.
+func CanAccess(c echo.Context, owner Owner) bool {
+	role, _ := c.Get("role").(string)
+
+	switch role {
+	case "admin":
+		return true
+	case "hotel_manager", "front_desk":
+		if len(owner.HotelIDs) == 0 {
+			return false
+		}
+		hotelIDs, _ := c.Get("hotel_ids").([]int)
+		assigned := make(map[int]bool, len(hotelIDs))
+		for _, hotelID := range hotelIDs {
+			assigned[hotelID] = true
+		}
+		for _, hotelID := range owner.HotelIDs {
+			if !assigned[hotelID] {
+				return false
+			}
+		}
+		return true
+	default:
+		userID, ok := c.Get("id").(float64)
+		return ok && int(userID) == owner.UserID
+	}
+}
+
+// Ownership lets a request through only when the caller may access the
+// resource it refers to. Resources the caller may not see are reported as
+// not found so their existence is not leaked. The owner is stored under
+// "owner" for handlers that act on the guest's behalf.
+func Ownership(lookup OwnerLookup, source IDSource, notFound string) echo.MiddlewareFunc {
+	return func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(c echo.Context) error {
+			id, err := source(c)
+			if err != nil {
+				return c.JSON(400, echo.Map{
+					"message": "invalid request",
+				})
+			}
+			if id == 0 {
+				return next(c)
+			}
+
+			owner, err := lookup(id)
+			if err != nil {
+				log.Println("Error looking up owner:", err)
+				return c.JSON(500, echo.Map{
+					"message": "failed to verify access",
+				})
+			}
+
+			if owner == nil || !CanAccess(c, *owner) {
+				return c.JSON(404, echo.Map{
+					"message": notFound,
+				})
+			}
+
+			c.Set("owner", *owner)
+			return next(c)
+		}
+	}
+}
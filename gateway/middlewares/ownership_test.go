@@ -0,0 +1,156 @@
+package middleware
+
+import (
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/labstack/echo/v4"
+)
+
+// bookings maps booking IDs to their owners for the fake lookup.
+var bookings = map[int]Owner{
+	1: {UserID: 10, HotelIDs: []int{100}},
+	2: {UserID: 20, HotelIDs: []int{200}},
+	3: {UserID: 30, HotelIDs: []int{100, 200}},
+}
+
+func lookupBooking(id int) (*Owner, error) {
+	owner, ok := bookings[id]
+	if !ok {
+		return nil, nil
+	}
+	return &owner, nil
+}
+
+type caller struct {
+	userID   float64
+	role     string
+	hotelIDs []int
+}
+
+// serve runs a request through Ownership with the caller already
+// authenticated and returns the recorded response.
+func serve(t *testing.T, who caller, method, path, body string, source IDSource, lookup OwnerLookup) *httptest.ResponseRecorder {
+	t.Helper()
+
+	e := echo.New()
+	authenticate := func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(c echo.Context) error {
+			c.Set("id", who.userID)
+			c.Set("role", who.role)
+			if who.hotelIDs != nil {
+				c.Set("hotel_ids", who.hotelIDs)
+			}
+			return next(c)
+		}
+	}
+	handler := func(c echo.Context) error {
+		body, _ := io.ReadAll(c.Request().Body)
+		return c.String(http.StatusOK, string(body))
+	}
+
+	ownership := Ownership(lookup, source, "Booking not found")
+	e.GET("/booking/detail/:booking_id", handler, authenticate, ownership)
+	e.POST("/payment", handler, authenticate, ownership)
+
+	req := httptest.NewRequest(method, path, strings.NewReader(body))
+	req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+	rec := httptest.NewRecorder()
+	e.ServeHTTP(rec, req)
+	return rec
+}
+
+func TestOwnershipBookingDetail(t *testing.T) {
+	tests := []struct {
+		name   string
+		caller caller
+		path   string
+		want   int
+	}{
+		{"owner", caller{userID: 10, role: "user"}, "/booking/detail/1", http.StatusOK},
+		{"other user", caller{userID: 20, role: "user"}, "/booking/detail/1", http.StatusNotFound},
+		{"missing booking", caller{userID: 10, role: "user"}, "/booking/detail/99", http.StatusNotFound},
+		{"admin", caller{userID: 1, role: "admin"}, "/booking/detail/2", http.StatusOK},
+		{"staff of the hotel", caller{userID: 5, role: "front_desk", hotelIDs: []int{100}}, "/booking/detail/1", http.StatusOK},
+		{"staff of another hotel", caller{userID: 5, role: "hotel_manager", hotelIDs: []int{100}}, "/booking/detail/2", http.StatusNotFound},
+		{"staff without hotels", caller{userID: 5, role: "front_desk"}, "/booking/detail/1", http.StatusNotFound},
+		{"staff of one of several hotels", caller{userID: 5, role: "front_desk", hotelIDs: []int{100}}, "/booking/detail/3", http.StatusNotFound},
+		{"staff of every hotel", caller{userID: 5, role: "hotel_manager", hotelIDs: []int{200, 100}}, "/booking/detail/3", http.StatusOK},
+		{"invalid id", caller{userID: 10, role: "user"}, "/booking/detail/abc", http.StatusBadRequest},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rec := serve(t, tt.caller, http.MethodGet, tt.path, "", FromParam("booking_id"), lookupBooking)
+			if rec.Code != tt.want {
+				t.Fatalf("status = %d, want %d (body %s)", rec.Code, tt.want, rec.Body.String())
+			}
+		})
+	}
+}
+
+func TestOwnershipFromBody(t *testing.T) {
+	tests := []struct {
+		name   string
+		caller caller
+		body   string
+		want   int
+	}{
+		{"own booking", caller{userID: 10, role: "user"}, `{"booking_id": 1, "amount": 50}`, http.StatusOK},
+		{"another user's booking", caller{userID: 10, role: "user"}, `{"booking_id": 2, "amount": 50}`, http.StatusNotFound},
+		{"no booking in body", caller{userID: 10, role: "user"}, `{"reservation_id": 7}`, http.StatusOK},
+		{"malformed body", caller{userID: 10, role: "user"}, `{"booking_id":`, http.StatusBadRequest},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rec := serve(t, tt.caller, http.MethodPost, "/payment", tt.body, FromBody("booking_id"), lookupBooking)
+			if rec.Code != tt.want {
+				t.Fatalf("status = %d, want %d (body %s)", rec.Code, tt.want, rec.Body.String())
+			}
+			// The handler must still see the original body.
+			if rec.Code == http.StatusOK && rec.Body.String() != tt.body {
+				t.Fatalf("handler body = %q, want %q", rec.Body.String(), tt.body)
+			}
+		})
+	}
+}
+
+func TestOwnershipStoresOwner(t *testing.T) {
+	e := echo.New()
+	req := httptest.NewRequest(http.MethodGet, "/", nil)
+	c := e.NewContext(req, httptest.NewRecorder())
+	c.SetParamNames("booking_id")
+	c.SetParamValues("1")
+	c.Set("id", float64(5))
+	c.Set("role", "front_desk")
+	c.Set("hotel_ids", []int{100})
+
+	var got Owner
+	next := func(c echo.Context) error {
+		got, _ = c.Get("owner").(Owner)
+		return nil
+	}
+
+	if err := Ownership(lookupBooking, FromParam("booking_id"), "Booking not found")(next)(c); err != nil {
+		t.Fatal(err)
+	}
+	if got.UserID != 10 {
+		t.Fatalf("owner user = %d, want 10", got.UserID)
+	}
+}
+
+func TestOwnershipLookupError(t *testing.T) {
+	failing := func(id int) (*Owner, error) {
+		return nil, errors.New("booking service unavailable")
+	}
+
+	rec := serve(t, caller{userID: 10, role: "user"}, http.MethodGet, "/booking/detail/1", "", FromParam("booking_id"), failing)
+	if rec.Code != http.StatusInternalServerError {
+		t.Fatalf("status = %d, want %d", rec.Code, http.StatusInternalServerError)
+	}
+}
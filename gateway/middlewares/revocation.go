@@ -0,0 +1,89 @@
+package middleware
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// revocationSyncInterval is how often sessions revoked outside this
+// gateway, such as on refresh token reuse, are picked up.
+const revocationSyncInterval = 10 * time.Second
+
+// revokedSessions maps the login sessions whose access tokens must be
+// rejected to the time their last access token expires.
+var revokedSessions = struct {
+	sync.RWMutex
+	expiresAt map[string]time.Time
+}{expiresAt: map[string]time.Time{}}
+
+// RevokeSession rejects every access token of a session from now on.
+func RevokeSession(sessionID string, expiresAt time.Time) {
+	revokedSessions.Lock()
+	defer revokedSessions.Unlock()
+
+	if expiresAt.After(revokedSessions.expiresAt[sessionID]) {
+		revokedSessions.expiresAt[sessionID] = expiresAt
+	}
+}
+
+func isSessionRevoked(sessionID string) bool {
+	revokedSessions.RLock()
+	defer revokedSessions.RUnlock()
+
+	_, revoked := revokedSessions.expiresAt[sessionID]
+	return revoked
+}
+
+// StartRevocationSync keeps the revocation list in line with user-service
+// in the background.
+func StartRevocationSync(url string) {
+	client := &http.Client{Timeout: 5 * time.Second}
+
+	go func() {
+		for {
+			if err := syncRevokedSessions(client, url); err != nil {
+				log.Println("Error syncing revoked sessions:", err)
+			}
+			time.Sleep(revocationSyncInterval)
+		}
+	}()
+}
+
+func syncRevokedSessions(client *http.Client, url string) error {
+	resp, err := client.Get(url)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("user service returned status %d", resp.StatusCode)
+	}
+
+	var sessions []struct {
+		SessionID string    `json:"session_id"`
+		ExpiresAt time.Time `json:"expires_at"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&sessions); err != nil {
+		return err
+	}
+
+	for _, session := range sessions {
+		RevokeSession(session.SessionID, session.ExpiresAt)
+	}
+
+	// Once its last access token has expired a session no longer needs to
+	// be tracked.
+	revokedSessions.Lock()
+	defer revokedSessions.Unlock()
+	for sessionID, expiresAt := range revokedSessions.expiresAt {
+		if time.Now().After(expiresAt) {
+			delete(revokedSessions.expiresAt, sessionID)
+		}
+	}
+	return nil
+}
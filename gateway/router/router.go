@@ -14,33 +14,128 @@ func InitRoutes(e *echo.Echo) {
 
 	e.POST("/register", handler.Register)
 	e.POST("/login", handler.Login)
+	e.POST("/login/2fa", handler.VerifyLoginChallengeHandler)
+	e.POST("/login/2fa/setup", handler.SetupLoginChallengeHandler)
+	e.POST("/token/refresh", handler.RefreshTokenHandler)
+	e.POST("/logout", handler.LogoutHandler, middleware.Authentication)
+
+	e.GET("/email/verify", handler.VerifyEmailHandler)
+	e.POST("/email/verify", handler.VerifyEmailHandler)
+	e.POST("/email/verify/resend", handler.ResendVerificationEmailHandler)
+	e.POST("/password/forgot", handler.ForgotPasswordHandler)
+	e.GET("/password/reset", handler.ResetPasswordFormHandler)
+	e.POST("/password/reset", handler.ResetPasswordHandler)
+	e.GET("/email/change/confirm", handler.ConfirmEmailChangeHandler)
+	e.POST("/email/change/confirm", handler.ConfirmEmailChangeHandler)
 
 	e.GET("/hotel", handler.GetListHotelsHandler)
 	e.GET("/hotel/:id", handler.GetHotelsHandler)
+	e.GET("/search", handler.SearchHandler)
+	e.POST("/booking/quote", handler.QuoteBookingHandler)
 
 	e.GET("/hotel/room", handler.ListRoomsByHotelIdHandler)
 	e.GET("/room/:id", handler.GetRoomByIDHandler)
+	e.GET("/amenity", handler.ListAmenitiesHandler)
 	
 
 	user := e.Group("/api")
 	user.Use(middleware.Authentication, middleware.UserAuth) 
 	{
 		user.GET("/user", handler.GetUserByIDHandler)
+		user.PATCH("/user", handler.UpdateProfileHandler)
+		user.PUT("/user/password", handler.ChangePasswordHandler)
+		user.DELETE("/user", handler.DeleteAccountHandler)
+		user.GET("/user/export", handler.ExportUserDataHandler)
 
 		user.POST("/booking", handler.CreateBookingHandler)
 		user.GET("/booking", handler.GetListBooking)
-		user.GET("/booking/detail/:booking_id", handler.GetDetailBooking)
+		user.POST("/booking/:booking_id/cancel", handler.CancelBookingHandler)
+		user.PATCH("/booking/:booking_id", handler.ModifyBookingHandler)
+
+		user.POST("/reservation", handler.CreateReservationHandler)
+	}
+
+	// Bookings are reachable by the guest who made them and by staff of
+	// the hotel; everyone else gets a 404.
+	bookingOwner := middleware.Ownership(handler.BookingOwner, middleware.FromParam("booking_id"), "Booking not found")
+	reservationOwner := middleware.Ownership(handler.ReservationOwner, middleware.FromParam("id"), "Reservation not found")
+
+	guest := e.Group("/api")
+	guest.Use(middleware.Authentication, middleware.RoleAuth("user", "admin", "hotel_manager", "front_desk"))
+	{
+		guest.GET("/booking/detail/:booking_id", handler.GetDetailBooking, bookingOwner)
+		guest.GET("/booking/detail/:booking_id/changes", handler.GetBookingChangesHandler, bookingOwner)
+		guest.GET("/reservation/:id", handler.GetReservationHandler, reservationOwner)
 
-		user.POST("/payment", handler.CreatePaymentHandler)
-		user.POST("/refund/:booking_id", handler.CreateRefundHandler)
+		guest.POST("/payment", handler.CreatePaymentHandler,
+			middleware.Ownership(handler.BookingOwner, middleware.FromBody("booking_id"), "Booking not found"),
+			middleware.Ownership(handler.ReservationOwner, middleware.FromBody("reservation_id"), "Reservation not found"))
+		guest.POST("/refund/:booking_id", handler.CreateRefundHandler, bookingOwner)
+	}
+
+	// Every signed-in account manages its own second factor.
+	account := e.Group("/api")
+	account.Use(middleware.Authentication)
+	{
+		account.POST("/2fa/setup", handler.SetupTwoFactorHandler)
+		account.POST("/2fa/enable", handler.EnableTwoFactorHandler)
+		account.POST("/2fa/disable", handler.DisableTwoFactorHandler)
+		account.POST("/2fa/recovery-codes", handler.RegenerateRecoveryCodesHandler)
 	}
 
 	admin := e.Group("/api")
 	admin.Use(middleware.Authentication, middleware.AdminAuth)
 	{
 		admin.POST("/hotel", handler.CreateHotelHandler)
-		admin.POST("/room", handler.CreateRoomHandler)
-		admin.PUT("/booking/checkin-status", handler.UpdateCheckinStatusHandler)
+		admin.DELETE("/hotel/:id", handler.DeleteHotelHandler)
+
+		admin.POST("/amenity", handler.CreateAmenityHandler)
+		admin.PUT("/amenity/:id", handler.UpdateAmenityHandler)
+		admin.DELETE("/amenity/:id", handler.DeleteAmenityHandler)
 
-	}	
+		admin.POST("/admin/users", handler.CreateUserHandler)
+		admin.GET("/admin/users", handler.ListUsersHandler)
+		admin.PUT("/admin/users/:id/role", handler.UpdateUserRoleHandler)
+		admin.PUT("/admin/users/:id/status", handler.UpdateUserStatusHandler)
+		admin.POST("/admin/users/:id/unlock", handler.UnlockUserHandler)
+		admin.GET("/admin/login-attempts", handler.ListLoginAttemptsHandler)
+		admin.DELETE("/admin/users/:id/2fa", handler.ResetTwoFactorHandler)
+		admin.PUT("/admin/users/:id/hotels", handler.UpdateUserHotelsHandler)
+	}
+
+	// Hotel managers run the hotels they are assigned to; booking-service
+	// rejects requests for any other hotel.
+	manager := e.Group("/api")
+	manager.Use(middleware.Authentication, middleware.RoleAuth("admin", "hotel_manager"))
+	{
+		manager.PUT("/hotel/:id", handler.UpdateHotelHandler)
+		manager.PATCH("/hotel/:id", handler.UpdateHotelHandler)
+		manager.POST("/room", handler.CreateRoomHandler)
+		manager.PUT("/room/:id", handler.UpdateRoomHandler)
+		manager.PATCH("/room/:id", handler.UpdateRoomHandler)
+		manager.DELETE("/room/:id", handler.DeleteRoomHandler)
+
+		manager.GET("/room/:id/rate-plan", handler.ListRatePlansHandler)
+		manager.POST("/rate-plan", handler.CreateRatePlanHandler)
+		manager.PUT("/rate-plan/:id", handler.UpdateRatePlanHandler)
+		manager.DELETE("/rate-plan/:id", handler.DeleteRatePlanHandler)
+
+		manager.POST("/room/:id/block", handler.CreateRoomBlockHandler)
+		manager.DELETE("/room/block/:id", handler.DeleteRoomBlockHandler)
+
+		manager.PUT("/hotel/:id/amenities", handler.UpdateHotelAmenitiesHandler)
+		manager.PUT("/room/:id/amenities", handler.UpdateRoomAmenitiesHandler)
+	}
+
+	frontDesk := e.Group("/api")
+	frontDesk.Use(middleware.Authentication, middleware.RoleAuth("admin", "hotel_manager", "front_desk"))
+	{
+		frontDesk.PUT("/booking/checkin-status", handler.UpdateCheckinStatusHandler)
+		frontDesk.PUT("/booking/:booking_id/party", handler.UpdatePartyHandler)
+		frontDesk.POST("/booking/:booking_id/guests", handler.AddGuestHandler)
+		frontDesk.PUT("/booking/:booking_id/guests/:guest_id", handler.UpdateGuestHandler)
+		frontDesk.DELETE("/booking/:booking_id/guests/:guest_id", handler.DeleteGuestHandler)
+		frontDesk.PUT("/room/:id/status", handler.UpdateRoomStatusHandler)
+		frontDesk.GET("/room/:id/block", handler.ListRoomBlocksHandler)
+	}
 }
\ No newline at end of file
@@ -1,11 +1,13 @@
 package dto
 
 import (
+	"payment-service/models"
 	"time"
 )
 
 type CreatePaymentRequest struct {
 	BookingID     int     `json:"booking_id"`
+	ReservationID *int    `json:"reservation_id"`
 	UserID        int     `json:"user_id"`
 	Amount        float64 `json:"amount"`
 	PaymentMethod string  `json:"payment_method"`
@@ -14,7 +16,8 @@ type CreatePaymentRequest struct {
 type CreatePaymentResponse struct {
 	PaymentID     int       `json:"payment_id"`
 	PaymentUID    string	`json:"payment_uid"`
-	BookingID     int       `json:"booking_id"`
+	BookingID     int       `json:"booking_id,omitempty"`
+	ReservationID *int      `json:"reservation_id,omitempty"`
 	UserID        int       `json:"user_id"`
 	Amount        float64   `json:"amount"`
 	PaymentMethod string    `json:"payment_method"`
@@ -32,9 +35,41 @@ type SuccessResponse struct {
 	Message string `json:"message"`
 }
 
+// UpdateBookingStatusRequest confirms a booking once it is paid. Amount is
+// what was paid, which must still be the total of the booking.
 type UpdateBookingStatusRequest struct {
-	BookingID int    `json:"booking_id"`
-	Status    string `json:"status"`
+	BookingID int     `json:"booking_id"`
+	Status    string  `json:"status"`
+	Amount    float64 `json:"amount"`
+}
+
+// BookingChangeCallbackRequest applies a booking change once its extra
+// charge is paid.
+type BookingChangeCallbackRequest struct {
+	PaymentUID string  `json:"payment_uid"`
+	Amount     float64 `json:"amount"`
+}
+
+// AmountDue is the part of a booking-service booking or reservation that
+// a payment is checked against. ReservationID is only set for bookings.
+type AmountDue struct {
+	UserID        int     `json:"user_id"`
+	Status        string  `json:"status"`
+	TotalPrice    float64 `json:"total_price"`
+	ReservationID *int    `json:"reservation_id"`
+}
+
+type ExpirePaymentRequest struct {
+	BookingID     int  `json:"booking_id"`
+	ReservationID *int `json:"reservation_id"`
+}
+
+// UpdateReservationStatusRequest confirms a paid reservation. Amount is
+// what was paid, which must still be the total of the reservation.
+type UpdateReservationStatusRequest struct {
+	ReservationID int     `json:"reservation_id"`
+	Status        string  `json:"status"`
+	Amount        float64 `json:"amount"`
 }
 
 type CallbackRequest struct {
@@ -46,13 +81,28 @@ type CreateRefundResponse struct {
 	Message  string `json:"message"`
 }
 
-type CreateRefundRequest struct {
-	UserID    *int `json:"user_id"`
-	BookingID *int `json:"booking_id"`
+type CancellationRefundRequest struct {
+	BookingID        int     `json:"booking_id"`
+	ReservationID    *int    `json:"reservation_id"`
+	UserID           int     `json:"user_id"`
+	RefundableAmount float64 `json:"refundable_amount"`
 }
 
-type UpdateBookingStatusRefundRequest struct {
-	UserID    *int    `json:"user_id"`
-	BookingID *int    `json:"booking_id"`
-	Status    string `json:"status"`
-}
\ No newline at end of file
+type PaymentAdjustmentRequest struct {
+	BookingID     int     `json:"booking_id"`
+	ReservationID *int    `json:"reservation_id"`
+	UserID        int     `json:"user_id"`
+	Amount        float64 `json:"amount"`
+}
+
+type PaymentAdjustmentResponse struct {
+	PaymentUID *string `json:"payment_uid,omitempty"`
+	RefundID   *int    `json:"refund_id,omitempty"`
+	Message    string  `json:"message"`
+}
+
+// UserDataExport is a user's share of the personal data export.
+type UserDataExport struct {
+	Payments []models.Payment `json:"payments"`
+	Refunds  []models.Refund  `json:"refunds"`
+}
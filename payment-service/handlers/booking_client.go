@@ -0,0 +1,60 @@
+package handler
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"net/http"
+	"os"
+	"payment-service/dto"
+)
+
+// errNotFound is returned by getFromBookingService when booking-service
+// does not know the requested resource.
+var errNotFound = errors.New("not found")
+
+// getFromBookingService fetches a resource from booking-service and decodes
+// it into result.
+func getFromBookingService(path string, result interface{}) error {
+	bookingServiceURL := os.Getenv("BOOKING_SERVICE_URL")
+	if bookingServiceURL == "" {
+		return errors.New("booking service URL is not configured")
+	}
+
+	resp, err := http.Get(bookingServiceURL + path)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode == http.StatusNotFound {
+		return errNotFound
+	}
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("booking service responded with status %d", resp.StatusCode)
+	}
+
+	return json.NewDecoder(resp.Body).Decode(result)
+}
+
+// fetchBookingDue returns what the user owes for a booking according to
+// booking-service, which is the only service that prices stays.
+func fetchBookingDue(bookingID int) (dto.AmountDue, error) {
+	var due dto.AmountDue
+	err := getFromBookingService(fmt.Sprintf("/booking/detail/%d", bookingID), &due)
+	return due, err
+}
+
+// fetchReservationDue returns what the user owes for a reservation, which
+// is paid as a whole.
+func fetchReservationDue(reservationID int) (dto.AmountDue, error) {
+	var due dto.AmountDue
+	err := getFromBookingService(fmt.Sprintf("/reservation/%d", reservationID), &due)
+	return due, err
+}
+
+// sameAmount compares two amounts of money to the cent.
+func sameAmount(a, b float64) bool {
+	return math.Round(a*100) == math.Round(b*100)
+}
@@ -0,0 +1,91 @@
+package handler
+
+import (
+	"net/http"
+	"payment-service/config"
+	"payment-service/dto"
+	"payment-service/models"
+
+	"github.com/labstack/echo/v4"
+)
+
+// ExportUserData returns the payments a user made and the refunds issued
+// against them. It backs the gateway's personal data export.
+func ExportUserData(c echo.Context) error {
+	userID := c.Param("user_id")
+
+	// payments has no created_at; a payment is created at its payment_date.
+	query := `
+		SELECT id, booking_id, reservation_id, user_id, COALESCE(payment_uid::text, ''), amount,
+			COALESCE(payment_method, ''), payment_status, purpose, payment_date, refunded_at, payment_date, updated_at
+		FROM payments WHERE user_id = $1 ORDER BY id
+	`
+	rows, err := config.DB.Query(query, userID)
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, dto.ErrorResponse{Message: "Failed to retrieve payments"})
+	}
+	defer rows.Close()
+
+	export := dto.UserDataExport{Payments: []models.Payment{}, Refunds: []models.Refund{}}
+	for rows.Next() {
+		var payment models.Payment
+		if err := rows.Scan(
+			&payment.ID,
+			&payment.BookingID,
+			&payment.ReservationID,
+			&payment.UserID,
+			&payment.PaymentUID,
+			&payment.Amount,
+			&payment.PaymentMethod,
+			&payment.PaymentStatus,
+			&payment.Purpose,
+			&payment.PaymentDate,
+			&payment.RefundedAt,
+			&payment.CreatedAt,
+			&payment.UpdatedAt,
+		); err != nil {
+			return c.JSON(http.StatusInternalServerError, dto.ErrorResponse{Message: "Failed to scan payment data"})
+		}
+		export.Payments = append(export.Payments, payment)
+	}
+	if err := rows.Err(); err != nil {
+		return c.JSON(http.StatusInternalServerError, dto.ErrorResponse{Message: "Error occurred during payments retrieval"})
+	}
+
+	// Refunds only know their payment, so they are found through it. A
+	// refund is issued when it is created.
+	query = `
+		SELECT r.id, r.payment_id, r.booking_id, r.refund_amount, r.refund_status, r.reason, r.created_at, r.created_at, r.updated_at
+		FROM refunds r
+		JOIN payments p ON p.id = r.payment_id
+		WHERE p.user_id = $1 ORDER BY r.id
+	`
+	rows, err = config.DB.Query(query, userID)
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, dto.ErrorResponse{Message: "Failed to retrieve refunds"})
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var refund models.Refund
+		if err := rows.Scan(
+			&refund.ID,
+			&refund.PaymentID,
+			&refund.BookingID,
+			&refund.RefundAmount,
+			&refund.RefundStatus,
+			&refund.Reason,
+			&refund.RefundDate,
+			&refund.CreatedAt,
+			&refund.UpdatedAt,
+		); err != nil {
+			return c.JSON(http.StatusInternalServerError, dto.ErrorResponse{Message: "Failed to scan refund data"})
+		}
+		export.Refunds = append(export.Refunds, refund)
+	}
+	if err := rows.Err(); err != nil {
+		return c.JSON(http.StatusInternalServerError, dto.ErrorResponse{Message: "Error occurred during refunds retrieval"})
+	}
+
+	return c.JSON(http.StatusOK, export)
+}
@@ -17,6 +17,43 @@ import (
 )
 
 
+// paymentScope returns the payments column and ID that identify what was
+// paid for a booking: its reservation when it is one line of a
+// reservation, the booking itself otherwise.
+func paymentScope(bookingID int, reservationID *int) (string, int) {
+	if reservationID != nil {
+		return "reservation_id", *reservationID
+	}
+	return "booking_id", bookingID
+}
+
+// netPaidAmount returns what has been paid within a payment scope,
+// including adjustment charges, minus the refunds already granted.
+func netPaidAmount(column string, id int) (float64, error) {
+	query := `
+		SELECT
+			COALESCE((SELECT SUM(amount) FROM payments WHERE ` + column + ` = $1 AND payment_status = 'success'), 0) -
+			COALESCE((SELECT SUM(r.refund_amount) FROM refunds r JOIN payments p ON p.id = r.payment_id
+				WHERE p.` + column + ` = $1 AND r.refund_status <> 'denied'), 0)
+	`
+
+	var amount float64
+	err := config.DB.QueryRow(query, id).Scan(&amount)
+	return amount, err
+}
+
+// findCompletedPayment returns the original, successful payment of the user
+// within a payment scope.
+func findCompletedPayment(userID int, column string, id int) (int, error) {
+	query := `
+		SELECT id FROM payments
+		WHERE user_id = $1 AND ` + column + ` = $2 AND payment_status = 'success' AND purpose = 'booking'
+	`
+
+	var paymentID int
+	err := config.DB.QueryRow(query, userID, id).Scan(&paymentID)
+	return paymentID, err
+}
 
 func CreatePayment(c echo.Context) error {
 	var req dto.CreatePaymentRequest
@@ -25,23 +62,67 @@ func CreatePayment(c echo.Context) error {
 		return c.JSON(http.StatusBadRequest, dto.ErrorResponse{Message: "Invalid request"})
 	}
 
-	if req.BookingID == 0 || req.UserID == 0 || req.Amount <= 0 || req.PaymentMethod == "" {
+	if req.UserID == 0 || req.Amount <= 0 || req.PaymentMethod == "" {
 		return c.JSON(http.StatusBadRequest, dto.ErrorResponse{Message: "Missing or invalid payment details"})
 	}
 
+	if (req.BookingID == 0) == (req.ReservationID == nil) {
+		return c.JSON(http.StatusBadRequest, dto.ErrorResponse{Message: "Exactly one of booking_id or reservation_id is required"})
+	}
+
+	var bookingID *int
+	if req.BookingID != 0 {
+		bookingID = &req.BookingID
+
+		// The amount is checked against the price booking-service computed,
+		// since this payment is what confirms the booking.
+		due, err := fetchBookingDue(req.BookingID)
+		if err == errNotFound || (err == nil && due.UserID != req.UserID) {
+			return c.JSON(http.StatusNotFound, dto.ErrorResponse{Message: "Booking not found"})
+		} else if err != nil {
+			log.Println("Error retrieving booking for payment:", err)
+			return c.JSON(http.StatusBadGateway, dto.ErrorResponse{Message: "Failed to retrieve booking"})
+		}
+		if due.ReservationID != nil {
+			return c.JSON(http.StatusBadRequest, dto.ErrorResponse{Message: "The booking is part of a reservation, pay the reservation instead"})
+		}
+		if due.Status != "pending" {
+			return c.JSON(http.StatusConflict, dto.ErrorResponse{Message: "Booking is not awaiting payment"})
+		}
+		if !sameAmount(req.Amount, due.TotalPrice) {
+			return c.JSON(http.StatusBadRequest, dto.ErrorResponse{Message: fmt.Sprintf("amount must equal the booking total of %.2f", due.TotalPrice)})
+		}
+	} else {
+		// A reservation is paid as a whole, so the amount has to cover
+		// every line of it.
+		due, err := fetchReservationDue(*req.ReservationID)
+		if err == errNotFound || (err == nil && due.UserID != req.UserID) {
+			return c.JSON(http.StatusNotFound, dto.ErrorResponse{Message: "Reservation not found"})
+		} else if err != nil {
+			log.Println("Error retrieving reservation for payment:", err)
+			return c.JSON(http.StatusBadGateway, dto.ErrorResponse{Message: "Failed to retrieve reservation"})
+		}
+		if due.Status != "pending" {
+			return c.JSON(http.StatusConflict, dto.ErrorResponse{Message: "Reservation is not awaiting payment"})
+		}
+		if !sameAmount(req.Amount, due.TotalPrice) {
+			return c.JSON(http.StatusBadRequest, dto.ErrorResponse{Message: fmt.Sprintf("amount must equal the reservation total of %.2f", due.TotalPrice)})
+		}
+	}
+
 	paymentUID := uuid.New().String()
 
 	log.Println(paymentUID, "paymentUID")
 
 	query := `
-		INSERT INTO payments (payment_uid, booking_id, user_id, amount, payment_method, payment_status, payment_date, updated_at)
-		VALUES ($1, $2, $3, $4, $5, $6, NOW(), NOW())
+		INSERT INTO payments (payment_uid, booking_id, reservation_id, user_id, amount, payment_method, payment_status, payment_date, updated_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, NOW(), NOW())
 		RETURNING id, payment_date
 	`
 
 	var paymentID int
 	var paymentDate time.Time
-	err := config.DB.QueryRow(query, paymentUID, req.BookingID, req.UserID, req.Amount, req.PaymentMethod, "pending").
+	err := config.DB.QueryRow(query, paymentUID, bookingID, req.ReservationID, req.UserID, req.Amount, req.PaymentMethod, "pending").
 		Scan(&paymentID, &paymentDate)
 	if err != nil {
 		return c.JSON(http.StatusInternalServerError, dto.ErrorResponse{Message: "Failed to create payment"})
@@ -51,6 +132,7 @@ func CreatePayment(c echo.Context) error {
 		PaymentID:     paymentID,
 		PaymentUID:    paymentUID,
 		BookingID:     req.BookingID,
+		ReservationID: req.ReservationID,
 		UserID:        req.UserID,
 		Amount:        req.Amount,
 		PaymentMethod: req.PaymentMethod,
@@ -67,19 +149,20 @@ func PaymentCallbackHandler(c echo.Context) error {
 		return c.JSON(http.StatusBadRequest, dto.ErrorResponse{Message: "Invalid request"})
 	}
 
-	query := `SELECT id, booking_id FROM payments WHERE payment_uid = $1`
-	var paymentID, bookingID int
-	err := config.DB.QueryRow(query, req.PaymentUID).Scan(&paymentID, &bookingID)
+	query := `SELECT id, booking_id, reservation_id, amount, payment_status, purpose FROM payments WHERE payment_uid = $1`
+	var paymentID int
+	var bookingID, reservationID *int
+	var amount float64
+	var paymentStatus, purpose string
+	err := config.DB.QueryRow(query, req.PaymentUID).Scan(&paymentID, &bookingID, &reservationID, &amount, &paymentStatus, &purpose)
 	if err == sql.ErrNoRows {
 		return c.JSON(http.StatusNotFound, dto.ErrorResponse{Message: "Payment not found"})
 	} else if err != nil {
 		return c.JSON(http.StatusInternalServerError, dto.ErrorResponse{Message: "Failed to retrieve payment"})
 	}
 
-	updateQuery := `UPDATE payments SET payment_status = 'success' WHERE id = $1`
-	_, err = config.DB.Exec(updateQuery, paymentID)
-	if err != nil {
-		return c.JSON(http.StatusInternalServerError, dto.ErrorResponse{Message: "Failed to update payment status"})
+	if paymentStatus != "pending" {
+		return c.JSON(http.StatusConflict, dto.ErrorResponse{Message: "Payment is no longer pending"})
 	}
 
 	bookingServiceURL := os.Getenv("BOOKING_SERVICE_URL")
@@ -87,100 +170,233 @@ func PaymentCallbackHandler(c echo.Context) error {
 		return c.JSON(http.StatusInternalServerError, dto.ErrorResponse{Message: "Booking service URL is not configured"})
 	}
 
-	reqBody := dto.UpdateBookingStatusRequest{
-		BookingID: bookingID,
-		Status:    "confirmed",
+	// A reservation is paid as a whole and confirms all of its bookings.
+	// Adjustment charges pay for a change of a confirmed booking, which
+	// booking-service only applies once it is paid.
+	var reqBody interface{}
+	callbackPath := "/booking/callback/status"
+	if purpose == "adjustment" {
+		reqBody = dto.BookingChangeCallbackRequest{
+			PaymentUID: req.PaymentUID,
+			Amount:     amount,
+		}
+		callbackPath = "/booking/change/callback/status"
+	} else if reservationID != nil {
+		reqBody = dto.UpdateReservationStatusRequest{
+			ReservationID: *reservationID,
+			Status:        "confirmed",
+			Amount:        amount,
+		}
+		callbackPath = "/reservation/callback/status"
+	} else {
+		reqBody = dto.UpdateBookingStatusRequest{
+			BookingID: *bookingID,
+			Status:    "confirmed",
+			Amount:    amount,
+		}
 	}
 	jsonBody, err := json.Marshal(reqBody)
 	if err != nil {
 		return c.JSON(http.StatusInternalServerError, dto.ErrorResponse{Message: "Failed to prepare booking update request"})
 	}
 
-	resp, err := http.Post(bookingServiceURL+"/booking/callback/status", "application/json", bytes.NewBuffer(jsonBody))
-	if err != nil || resp.StatusCode != http.StatusOK {
+	resp, err := http.Post(bookingServiceURL+callbackPath, "application/json", bytes.NewBuffer(jsonBody))
+	if err != nil {
 		return c.JSON(http.StatusInternalServerError, dto.ErrorResponse{Message: "Failed to update booking status"})
 	}
 	defer resp.Body.Close()
 
+	// The booking hold or change expired before the payment arrived or
+	// the booking was repriced since, so the room is no longer reserved
+	// for this payment.
+	if resp.StatusCode == http.StatusConflict {
+		expireQuery := `UPDATE payments SET payment_status = 'expired', updated_at = NOW() WHERE id = $1`
+		if _, err := config.DB.Exec(expireQuery, paymentID); err != nil {
+			log.Println("Failed to expire payment:", err)
+		}
+		return c.JSON(http.StatusConflict, dto.ErrorResponse{Message: "Booking hold has expired or its price has changed"})
+	}
+
+	if resp.StatusCode != http.StatusOK {
+		return c.JSON(http.StatusInternalServerError, dto.ErrorResponse{Message: "Failed to update booking status"})
+	}
+
+	updateQuery := `UPDATE payments SET payment_status = 'success', updated_at = NOW() WHERE id = $1`
+	_, err = config.DB.Exec(updateQuery, paymentID)
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, dto.ErrorResponse{Message: "Failed to update payment status"})
+	}
+
 	return c.JSON(http.StatusOK, dto.SuccessResponse{Message: "Payment status updated successfully"})
 }
 
-func CreateRefund(c echo.Context) error {
-	var req dto.CreateRefundRequest
+func ExpirePayments(c echo.Context) error {
+	var req dto.ExpirePaymentRequest
 
 	if err := c.Bind(&req); err != nil {
 		return c.JSON(http.StatusBadRequest, dto.ErrorResponse{Message: "Invalid request"})
 	}
 
-	if req.UserID == nil || req.BookingID == nil {
-		return c.JSON(http.StatusBadRequest, dto.ErrorResponse{Message: "User ID and Booking ID are required"})
+	if (req.BookingID == 0) == (req.ReservationID == nil) {
+		return c.JSON(http.StatusBadRequest, dto.ErrorResponse{Message: "Exactly one of booking_id or reservation_id is required"})
 	}
 
-	bookingServiceURL := os.Getenv("BOOKING_SERVICE_URL")
-	if bookingServiceURL == "" {
-		return c.JSON(http.StatusInternalServerError, dto.ErrorResponse{Message: "Booking service URL is not configured"})
+	column, id := paymentScope(req.BookingID, req.ReservationID)
+	query := `
+		UPDATE payments SET payment_status = 'expired', updated_at = NOW()
+		WHERE ` + column + ` = $1 AND payment_status = 'pending'
+	`
+	_, err := config.DB.Exec(query, id)
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, dto.ErrorResponse{Message: "Failed to expire payments"})
 	}
 
-	updateStatusRequest := dto.UpdateBookingStatusRefundRequest{
-		UserID:    req.UserID,
-		BookingID: req.BookingID,
-		Status:    "request_refund",
+	return c.JSON(http.StatusOK, dto.SuccessResponse{Message: "Pending payments expired successfully"})
+}
+
+func CreateCancellationRefund(c echo.Context) error {
+	var req dto.CancellationRefundRequest
+
+	if err := c.Bind(&req); err != nil {
+		return c.JSON(http.StatusBadRequest, dto.ErrorResponse{Message: "Invalid request"})
 	}
 
-	jsonUpdateStatusData, err := json.Marshal(updateStatusRequest)
-	if err != nil {
-		return c.JSON(http.StatusInternalServerError, dto.ErrorResponse{Message: "Failed to process status update request"})
+	if req.BookingID == 0 || req.UserID == 0 || req.RefundableAmount < 0 {
+		return c.JSON(http.StatusBadRequest, dto.ErrorResponse{Message: "Missing or invalid cancellation details"})
 	}
 
-	url := fmt.Sprintf("%s/booking/refund/status", bookingServiceURL)
-	updateStatusResp, err := http.Post(url, "application/json", bytes.NewBuffer(jsonUpdateStatusData))
-	if err != nil {
-		return c.JSON(http.StatusInternalServerError, dto.ErrorResponse{Message: "Failed to connect to booking service"})
+	column, id := paymentScope(req.BookingID, req.ReservationID)
+
+	// A canceled booking can no longer be paid for. For a reservation this
+	// also expires payments opened for its previous total.
+	expireQuery := `
+		UPDATE payments SET payment_status = 'expired', updated_at = NOW()
+		WHERE ` + column + ` = $1 AND payment_status = 'pending'
+	`
+	if _, err := config.DB.Exec(expireQuery, id); err != nil {
+		return c.JSON(http.StatusInternalServerError, dto.ErrorResponse{Message: "Failed to expire pending payments"})
 	}
-	defer updateStatusResp.Body.Close()
 
-	if updateStatusResp.StatusCode != http.StatusOK {
-		return c.JSON(updateStatusResp.StatusCode, dto.ErrorResponse{Message: "Failed to update booking status"})
+	if req.RefundableAmount == 0 {
+		return c.JSON(http.StatusOK, dto.SuccessResponse{Message: "Booking canceled without refundable amount"})
 	}
 
-	var paymentID int
-	var paymentAmount float64
-	checkPaymentQuery := `
-		SELECT id, amount FROM payments WHERE user_id = $1 AND booking_id = $2 AND payment_status = 'success'
-	`
-	err = config.DB.QueryRow(checkPaymentQuery, req.UserID, req.BookingID).Scan(&paymentID, &paymentAmount)
-	if err != nil {
-		if err == sql.ErrNoRows {
-			return c.JSON(http.StatusNotFound, dto.ErrorResponse{Message: "No completed payment found for the provided booking"})
-		}
+	paymentID, err := findCompletedPayment(req.UserID, column, id)
+	if err == sql.ErrNoRows {
+		return c.JSON(http.StatusNotFound, dto.ErrorResponse{Message: "No completed payment found for the provided booking"})
+	} else if err != nil {
 		return c.JSON(http.StatusInternalServerError, dto.ErrorResponse{Message: "Failed to check payment"})
 	}
 
+	// A reservation payment may be refunded once per canceled line.
 	var existingRefundID int
-	checkRefundQuery := `SELECT id FROM refunds WHERE payment_id = $1`
-	err = config.DB.QueryRow(checkRefundQuery, paymentID).Scan(&existingRefundID)
+	checkRefundQuery := `
+		SELECT id FROM refunds
+		WHERE payment_id = $1 AND (reason = 'refund' OR (reason = 'cancellation' AND booking_id = $2))
+	`
+	err = config.DB.QueryRow(checkRefundQuery, paymentID, req.BookingID).Scan(&existingRefundID)
 	if err == nil {
 		return c.JSON(http.StatusBadRequest, dto.ErrorResponse{Message: "Refund request already exists for this booking"})
 	} else if err != sql.ErrNoRows {
 		return c.JSON(http.StatusInternalServerError, dto.ErrorResponse{Message: "Failed to check existing refund"})
 	}
 
+	paymentAmount, err := netPaidAmount(column, id)
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, dto.ErrorResponse{Message: "Failed to calculate paid amount"})
+	}
+
+	refundAmount := req.RefundableAmount
+	if refundAmount > paymentAmount {
+		refundAmount = paymentAmount
+	}
+
 	query := `
-		INSERT INTO refunds (payment_id, refund_amount, refund_status, created_at, updated_at)
-		VALUES ($1, $2, $3, NOW(), NOW())
+		INSERT INTO refunds (payment_id, booking_id, refund_amount, refund_status, reason, created_at, updated_at)
+		VALUES ($1, $2, $3, $4, $5, NOW(), NOW())
 		RETURNING id
 	`
 
 	var refundID int
-	err = config.DB.QueryRow(query, paymentID, paymentAmount, "requested").Scan(&refundID)
+	err = config.DB.QueryRow(query, paymentID, req.BookingID, refundAmount, "requested", "cancellation").Scan(&refundID)
 	if err != nil {
 		log.Println(err, "error")
 		return c.JSON(http.StatusInternalServerError, dto.ErrorResponse{Message: "Failed to create refund"})
 	}
 
-	return c.JSON(http.StatusCreated, dto.CreateRefundResponse{
+	return c.JSON(http.StatusOK, dto.CreateRefundResponse{
 		RefundID: refundID,
 		Message:  "Refund requested successfully",
 	})
 }
 
+// CreatePaymentAdjustment settles the price difference of a modified
+// booking. A positive amount opens a pending adjustment charge for the user
+// to pay; a negative amount requests a partial refund of what was paid.
+func CreatePaymentAdjustment(c echo.Context) error {
+	var req dto.PaymentAdjustmentRequest
+
+	if err := c.Bind(&req); err != nil {
+		return c.JSON(http.StatusBadRequest, dto.ErrorResponse{Message: "Invalid request"})
+	}
+
+	if req.BookingID == 0 || req.UserID == 0 || req.Amount == 0 {
+		return c.JSON(http.StatusBadRequest, dto.ErrorResponse{Message: "Missing or invalid adjustment details"})
+	}
+
+	column, id := paymentScope(req.BookingID, req.ReservationID)
+
+	if req.Amount > 0 {
+		paymentUID := uuid.New().String()
+
+		query := `
+			INSERT INTO payments (payment_uid, booking_id, reservation_id, user_id, amount, payment_method, payment_status, purpose, payment_date, updated_at)
+			VALUES ($1, $2, $3, $4, $5, $6, $7, $8, NOW(), NOW())
+		`
+		_, err := config.DB.Exec(query, paymentUID, req.BookingID, req.ReservationID, req.UserID, req.Amount, "adjustment", "pending", "adjustment")
+		if err != nil {
+			log.Println("Error creating adjustment payment:", err)
+			return c.JSON(http.StatusInternalServerError, dto.ErrorResponse{Message: "Failed to create adjustment payment"})
+		}
+
+		return c.JSON(http.StatusOK, dto.PaymentAdjustmentResponse{
+			PaymentUID: &paymentUID,
+			Message:    "Adjustment payment created successfully",
+		})
+	}
+
+	paymentID, err := findCompletedPayment(req.UserID, column, id)
+	if err == sql.ErrNoRows {
+		return c.JSON(http.StatusNotFound, dto.ErrorResponse{Message: "No completed payment found for the provided booking"})
+	} else if err != nil {
+		return c.JSON(http.StatusInternalServerError, dto.ErrorResponse{Message: "Failed to check payment"})
+	}
+
+	paidAmount, err := netPaidAmount(column, id)
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, dto.ErrorResponse{Message: "Failed to calculate paid amount"})
+	}
+
+	refundAmount := -req.Amount
+	if refundAmount > paidAmount {
+		refundAmount = paidAmount
+	}
+
+	query := `
+		INSERT INTO refunds (payment_id, booking_id, refund_amount, refund_status, reason, created_at, updated_at)
+		VALUES ($1, $2, $3, $4, $5, NOW(), NOW())
+		RETURNING id
+	`
+
+	var refundID int
+	err = config.DB.QueryRow(query, paymentID, req.BookingID, refundAmount, "requested", "modification").Scan(&refundID)
+	if err != nil {
+		log.Println("Error creating adjustment refund:", err)
+		return c.JSON(http.StatusInternalServerError, dto.ErrorResponse{Message: "Failed to create refund"})
+	}
+
+	return c.JSON(http.StatusOK, dto.PaymentAdjustmentResponse{
+		RefundID: &refundID,
+		Message:  "Partial refund requested successfully",
+	})
+}
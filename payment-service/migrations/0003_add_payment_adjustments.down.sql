@@ -0,0 +1,5 @@
+ALTER TABLE refunds
+    DROP COLUMN IF EXISTS reason;
+
+ALTER TABLE payments
+    DROP COLUMN IF EXISTS purpose;
@@ -0,0 +1,5 @@
+ALTER TABLE payments
+    ADD COLUMN purpose VARCHAR(20) NOT NULL DEFAULT 'booking';
+
+ALTER TABLE refunds
+    ADD COLUMN reason VARCHAR(20) NOT NULL DEFAULT 'refund';
@@ -0,0 +1,7 @@
+ALTER TABLE refunds DROP COLUMN IF EXISTS booking_id;
+
+DROP INDEX IF EXISTS idx_payments_reservation_id;
+
+ALTER TABLE payments
+    DROP CONSTRAINT IF EXISTS payments_booking_or_reservation,
+    DROP COLUMN IF EXISTS reservation_id;
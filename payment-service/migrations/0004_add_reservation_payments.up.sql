@@ -0,0 +1,8 @@
+ALTER TABLE payments
+    ALTER COLUMN booking_id DROP NOT NULL,
+    ADD COLUMN reservation_id INT,
+    ADD CONSTRAINT payments_booking_or_reservation CHECK (booking_id IS NOT NULL OR reservation_id IS NOT NULL);
+
+ALTER TABLE refunds ADD COLUMN booking_id INT;
+
+CREATE INDEX idx_payments_reservation_id ON payments (reservation_id);
@@ -4,12 +4,14 @@ import "time"
 
 type Payment struct {
 	ID            int       `json:"id"`
-	BookingID     int       `json:"booking_id"`
+	BookingID     *int      `json:"booking_id"`
+	ReservationID *int      `json:"reservation_id"`
 	UserID        int       `json:"user_id"`
 	PaymentUID    string    `json:"payment_uid"` 
 	Amount        float64   `json:"amount"`
 	PaymentMethod string    `json:"payment_method"`
-	PaymentStatus string    `json:"payment_status"` // "pending", "completed", "refunded"
+	PaymentStatus string    `json:"payment_status"` // "pending", "success", "expired", "refunded"
+	Purpose       string    `json:"purpose"`        // "booking", "adjustment"
 	PaymentDate   time.Time `json:"payment_date"`
 	RefundedAt    *time.Time `json:"refunded_at,omitempty"`
 	CreatedAt     time.Time `json:"created_at"`
@@ -19,8 +21,10 @@ type Payment struct {
 type Refund struct {
 	ID           int       `json:"id"`
 	PaymentID    int       `json:"payment_id"` 
+	BookingID    *int      `json:"booking_id"`
 	RefundAmount float64   `json:"refund_amount"`
 	RefundStatus string    `json:"refund_status"` // "requested", "completed", "denied"
+	Reason       string    `json:"reason"`        // "refund", "cancellation", "modification"
 	RefundDate   time.Time `json:"refund_date"`
 	CreatedAt    time.Time `json:"created_at"`
 	UpdatedAt    time.Time `json:"updated_at"`
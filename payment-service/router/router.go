@@ -14,5 +14,8 @@ func InitRoutes(e *echo.Echo) {
 
 	e.POST("/payment", handler.CreatePayment)
 	e.POST("/payment/callback", handler.PaymentCallbackHandler)
-	e.POST("/refund", handler.CreateRefund)
+	e.POST("/payment/expire", handler.ExpirePayments)
+	e.POST("/payment/adjustment", handler.CreatePaymentAdjustment)
+	e.POST("/refund/cancellation", handler.CreateCancellationRefund)
+	e.GET("/user/:user_id/export", handler.ExportUserData)
 }
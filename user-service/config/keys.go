@@ -0,0 +1,157 @@
+package config
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/json"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"time"
+)
+
+// SigningKey is a key tokens are signed with. Alg is HS256, RS256 or ES256
+// and Key holds the matching []byte, *rsa.PrivateKey or *ecdsa.PrivateKey.
+type SigningKey struct {
+	ID  string
+	Alg string
+	Key interface{}
+}
+
+var (
+	// SigningKeys holds every configured key. Keys other than the active
+	// one are still published so tokens signed before a rotation keep
+	// validating until they expire.
+	SigningKeys []SigningKey
+
+	// ActiveSigningKey signs new tokens.
+	ActiveSigningKey SigningKey
+)
+
+type signingKeyConfig struct {
+	ID             string `json:"kid"`
+	Alg            string `json:"alg"`
+	Secret         string `json:"secret"`
+	PrivateKey     string `json:"private_key"`
+	PrivateKeyFile string `json:"private_key_file"`
+}
+
+// InitJWTKeys loads the signing keys from JWT_KEYS, a JSON array such as
+//
+//	[{"kid": "2024-06", "alg": "RS256", "private_key_file": "/run/secrets/jwt.pem"},
+//	 {"kid": "legacy", "alg": "HS256", "secret": "..."}]
+//
+// JWT_ACTIVE_KID selects the key new tokens are signed with and defaults
+// to the first one. Without JWT_KEYS a temporary ES256 key is generated,
+// which is only suitable for development since tokens stop validating once
+// the service restarts.
+func InitJWTKeys() {
+	raw := os.Getenv("JWT_KEYS")
+	if raw == "" {
+		log.Println("JWT_KEYS not set, signing tokens with a temporary ES256 key")
+
+		key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+		if err != nil {
+			log.Fatalf("Failed to generate signing key: %v\n", err)
+		}
+		ActiveSigningKey = SigningKey{ID: "temporary-" + time.Now().UTC().Format("20060102150405"), Alg: "ES256", Key: key}
+		SigningKeys = []SigningKey{ActiveSigningKey}
+		return
+	}
+
+	var configs []signingKeyConfig
+	if err := json.Unmarshal([]byte(raw), &configs); err != nil {
+		log.Fatalf("Failed to parse JWT_KEYS: %v\n", err)
+	}
+	if len(configs) == 0 {
+		log.Fatal("JWT_KEYS must contain at least one key")
+	}
+
+	seen := map[string]bool{}
+	for _, cfg := range configs {
+		key, err := parseSigningKey(cfg)
+		if err != nil {
+			log.Fatalf("Invalid JWT key %q: %v\n", cfg.ID, err)
+		}
+		if seen[key.ID] {
+			log.Fatalf("Duplicate JWT key %q\n", key.ID)
+		}
+		seen[key.ID] = true
+		SigningKeys = append(SigningKeys, key)
+	}
+
+	activeID := os.Getenv("JWT_ACTIVE_KID")
+	if activeID == "" {
+		activeID = SigningKeys[0].ID
+	}
+	for _, key := range SigningKeys {
+		if key.ID == activeID {
+			ActiveSigningKey = key
+			log.Printf("Signing tokens with %s key %q\n", key.Alg, key.ID)
+			return
+		}
+	}
+	log.Fatalf("JWT_ACTIVE_KID %q does not match any key in JWT_KEYS\n", activeID)
+}
+
+func parseSigningKey(cfg signingKeyConfig) (SigningKey, error) {
+	if cfg.ID == "" {
+		return SigningKey{}, errors.New("kid is required")
+	}
+
+	if cfg.Alg == "HS256" {
+		if len(cfg.Secret) < 32 {
+			return SigningKey{}, errors.New("HS256 secret must be at least 32 characters")
+		}
+		return SigningKey{ID: cfg.ID, Alg: cfg.Alg, Key: []byte(cfg.Secret)}, nil
+	}
+
+	if cfg.Alg != "RS256" && cfg.Alg != "ES256" {
+		return SigningKey{}, fmt.Errorf("unsupported alg %q, must be HS256, RS256 or ES256", cfg.Alg)
+	}
+
+	pemData := []byte(cfg.PrivateKey)
+	if cfg.PrivateKeyFile != "" {
+		var err error
+		if pemData, err = os.ReadFile(cfg.PrivateKeyFile); err != nil {
+			return SigningKey{}, err
+		}
+	}
+
+	block, _ := pem.Decode(pemData)
+	if block == nil {
+		return SigningKey{}, errors.New("private key must be PEM encoded")
+	}
+
+	key, err := x509.ParsePKCS8PrivateKey(block.Bytes)
+	if err != nil {
+		if key, err = x509.ParsePKCS1PrivateKey(block.Bytes); err != nil {
+			if key, err = x509.ParseECPrivateKey(block.Bytes); err != nil {
+				return SigningKey{}, errors.New("unrecognized private key format")
+			}
+		}
+	}
+
+	switch privateKey := key.(type) {
+	case *rsa.PrivateKey:
+		if cfg.Alg != "RS256" {
+			return SigningKey{}, errors.New("RSA key requires alg RS256")
+		}
+		if privateKey.N.BitLen() < 2048 {
+			return SigningKey{}, errors.New("RSA key must be at least 2048 bits")
+		}
+	case *ecdsa.PrivateKey:
+		if cfg.Alg != "ES256" || privateKey.Curve != elliptic.P256() {
+			return SigningKey{}, errors.New("EC key requires alg ES256 on curve P-256")
+		}
+	default:
+		return SigningKey{}, errors.New("unsupported private key type")
+	}
+
+	return SigningKey{ID: cfg.ID, Alg: cfg.Alg, Key: key}, nil
+}
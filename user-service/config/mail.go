@@ -0,0 +1,70 @@
+package config
+
+import (
+	"log"
+	"os"
+	"strings"
+	"user-service/mailer"
+)
+
+var (
+	// Mailer delivers account emails.
+	Mailer mailer.Mailer
+
+	// AppBaseURL is where the links in account emails point to.
+	AppBaseURL string
+)
+
+// InitMailer selects the mail transport from MAIL_DRIVER:
+//
+//   - smtp sends through SMTP_HOST, SMTP_PORT, SMTP_USERNAME and SMTP_PASSWORD
+//   - file writes .eml files to MAIL_DIR (default "mail")
+//   - memory keeps messages in memory
+//
+// The default is file, so local runs never send real email. MAIL_FROM sets
+// the sender and APP_BASE_URL the base of the links in the emails. The
+// links are opened with GET, so APP_BASE_URL must point at the gateway or
+// at this service, which both serve the link paths with GET.
+func InitMailer() {
+	from := os.Getenv("MAIL_FROM")
+	if from == "" {
+		from = "no-reply@localhost"
+	}
+
+	AppBaseURL = strings.TrimSuffix(os.Getenv("APP_BASE_URL"), "/")
+	if AppBaseURL == "" {
+		AppBaseURL = "http://localhost:8080"
+	}
+
+	switch driver := os.Getenv("MAIL_DRIVER"); driver {
+	case "smtp":
+		host := os.Getenv("SMTP_HOST")
+		if host == "" {
+			log.Fatal("SMTP_HOST must be set when MAIL_DRIVER is smtp")
+		}
+		port := os.Getenv("SMTP_PORT")
+		if port == "" {
+			port = "587"
+		}
+		Mailer = &mailer.SMTPMailer{
+			Host:     host,
+			Port:     port,
+			Username: os.Getenv("SMTP_USERNAME"),
+			Password: os.Getenv("SMTP_PASSWORD"),
+			From:     from,
+		}
+		log.Printf("Sending email through %s:%s\n", host, port)
+	case "memory":
+		Mailer = &mailer.MemoryMailer{}
+		log.Println("Keeping outgoing email in memory")
+	case "", "file":
+		dir := os.Getenv("MAIL_DIR")
+		if dir == "" {
+			dir = "mail"
+		}
+		Mailer = &mailer.FileMailer{Dir: dir, From: from}
+		log.Printf("Writing outgoing email to %s\n", dir)
+	default:
+		log.Fatalf("Unknown MAIL_DRIVER %q, must be smtp, file or memory\n", driver)
+	}
+}
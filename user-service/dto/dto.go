@@ -1,20 +1,76 @@
 package dto
 
+import (
+	"time"
+	"user-service/models"
+)
 
 type LoginUserRequest struct {
 	Email    string `json:"email"`
 	Password string `json:"password"`
 }
 
+// LoginUserResponse carries a short-lived access token and the refresh
+// token to renew it with. ExpiresIn is the access token lifetime in seconds.
+// RecoveryCodes is only set when two-factor authentication was set up
+// during the login.
 type LoginUserResponse struct {
-	Token string `json:"token"`
+	Token         string   `json:"token"`
+	RefreshToken  string   `json:"refresh_token"`
+	ExpiresIn     int      `json:"expires_in"`
+	RecoveryCodes []string `json:"recovery_codes,omitempty"`
+}
+
+// LoginChallengeResponse is returned instead of tokens when the password
+// was right but a second factor is needed. EnrollmentRequired means the
+// user has to set one up first.
+type LoginChallengeResponse struct {
+	TwoFactorRequired  bool   `json:"two_factor_required"`
+	EnrollmentRequired bool   `json:"enrollment_required"`
+	ChallengeToken     string `json:"challenge_token"`
+	ExpiresIn          int    `json:"expires_in"`
+}
+
+type LoginChallengeRequest struct {
+	ChallengeToken string `json:"challenge_token"`
+	Code           string `json:"code"`
+	RecoveryCode   string `json:"recovery_code"`
+}
+
+type TwoFactorRequest struct {
+	UserID       int    `json:"user_id"`
+	Code         string `json:"code"`
+	RecoveryCode string `json:"recovery_code"`
+}
+
+type TwoFactorSetupResponse struct {
+	Secret     string `json:"secret"`
+	OTPAuthURI string `json:"otpauth_uri"`
+}
+
+type RecoveryCodesResponse struct {
+	RecoveryCodes []string `json:"recovery_codes"`
+	Message       string   `json:"message"`
+}
+
+type RefreshTokenRequest struct {
+	RefreshToken string `json:"refresh_token"`
+}
+
+type LogoutRequest struct {
+	UserID    int    `json:"user_id"`
+	SessionID string `json:"session_id"`
+}
+
+type RevokedSession struct {
+	SessionID string    `json:"session_id"`
+	ExpiresAt time.Time `json:"expires_at"`
 }
 
 type RegisterUserRequest struct {
 	Username string `json:"username"`
 	Email    string `json:"email"`
 	Password string `json:"password"`
-	Role     string `json:"role"`
 }
 
 type RegisterUserResponse struct {
@@ -22,9 +78,114 @@ type RegisterUserResponse struct {
 	Username string `json:"username"`
 	Email    string `json:"email"`
 	Role     string `json:"role"`
+	HotelIDs []int  `json:"hotel_ids,omitempty"`
 	Message  string `json:"message"`
 }
 
+type VerifyEmailRequest struct {
+	Token string `json:"token" query:"token"`
+}
+
+// EmailRequest names the account a verification or password reset email
+// is sent for.
+type EmailRequest struct {
+	Email string `json:"email"`
+}
+
+type ResetPasswordRequest struct {
+	Token    string `json:"token" form:"token"`
+	Password string `json:"password" form:"password"`
+}
+
+// UpdateProfileRequest changes only the fields that are set.
+type UpdateProfileRequest struct {
+	Username *string `json:"username"`
+	Email    *string `json:"email"`
+}
+
+type ProfileResponse struct {
+	UserID       int     `json:"user_id"`
+	Username     string  `json:"username"`
+	Email        string  `json:"email"`
+	PendingEmail *string `json:"pending_email,omitempty"`
+	Message      string  `json:"message"`
+}
+
+// ChangePasswordRequest carries the session making the change, which is
+// the only one left signed in afterwards.
+type ChangePasswordRequest struct {
+	SessionID       string `json:"session_id"`
+	CurrentPassword string `json:"current_password"`
+	NewPassword     string `json:"new_password"`
+}
+
+type DeleteAccountRequest struct {
+	Password string `json:"password"`
+}
+
 type ErrorResponse struct {
 	Message string `json:"message"`
-}
\ No newline at end of file
+}
+
+type SuccessResponse struct {
+	Message string `json:"message"`
+}
+
+// CreateUserRequest is the admin variant of RegisterUserRequest, which may
+// pick any role.
+type CreateUserRequest struct {
+	Username string `json:"username"`
+	Email    string `json:"email"`
+	Password string `json:"password"`
+	Role     string `json:"role"`
+	HotelIDs []int  `json:"hotel_ids"`
+}
+
+type UpdateUserRoleRequest struct {
+	Role     string `json:"role"`
+	HotelIDs []int  `json:"hotel_ids"`
+}
+
+type UpdateUserStatusRequest struct {
+	Disabled *bool `json:"disabled"`
+}
+
+// PageResponse wraps one page of a list endpoint. NextCursor is null on
+// the last page.
+type PageResponse struct {
+	Data       interface{} `json:"data"`
+	NextCursor *string     `json:"next_cursor"`
+}
+
+type UpdateUserHotelsRequest struct {
+	HotelIDs []int `json:"hotel_ids"`
+}
+
+type UpdateUserHotelsResponse struct {
+	UserID   int    `json:"user_id"`
+	HotelIDs []int  `json:"hotel_ids"`
+	Message  string `json:"message"`
+}
+
+// JSONWebKey is a public key in RFC 7517 format.
+type JSONWebKey struct {
+	Kty string `json:"kty"`
+	Kid string `json:"kid"`
+	Use string `json:"use"`
+	Alg string `json:"alg"`
+	N   string `json:"n,omitempty"`
+	E   string `json:"e,omitempty"`
+	Crv string `json:"crv,omitempty"`
+	X   string `json:"x,omitempty"`
+	Y   string `json:"y,omitempty"`
+}
+
+type JWKSResponse struct {
+	Keys []JSONWebKey `json:"keys"`
+}
+
+// UserDataExport is the account's share of the personal data export.
+type UserDataExport struct {
+	Account       models.User           `json:"account"`
+	LoginAttempts []models.LoginAttempt `json:"login_attempts"`
+}
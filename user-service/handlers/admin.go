@@ -0,0 +1,455 @@
+package handler
+
+import (
+	"database/sql"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+	"user-service/config"
+	"user-service/dto"
+	"user-service/models"
+
+	"github.com/labstack/echo/v4"
+	"github.com/lib/pq"
+)
+
+const (
+	defaultPageLimit = 20
+	maxPageLimit     = 100
+)
+
+// CreateUser lets an administrator create an account with any role,
+// including hotel staff bound to hotel_ids.
+func CreateUser(c echo.Context) error {
+	var req dto.CreateUserRequest
+
+	if err := c.Bind(&req); err != nil {
+		return c.JSON(http.StatusBadRequest, dto.ErrorResponse{Message: "Invalid request"})
+	}
+
+	if req.Role == "" {
+		req.Role = models.RoleUser
+	}
+
+	account := dto.RegisterUserRequest{Username: req.Username, Email: req.Email, Password: req.Password}
+	return createUser(c, account, req.Role, req.HotelIDs, "User created successfully")
+}
+
+// ListUsers returns accounts ordered by ID. search matches the username or
+// email, role and disabled filter the result, and cursor continues after
+// the last user of the previous page.
+func ListUsers(c echo.Context) error {
+	limit, err := parseLimit(c)
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, dto.ErrorResponse{Message: err.Error()})
+	}
+
+	query := `
+		SELECT u.id, u.username, u.email, u.role, u.email_verified_at, u.totp_enabled_at IS NOT NULL, u.disabled_at, u.locked_until, u.deleted_at, u.created_at, u.updated_at,
+			COALESCE((SELECT array_agg(hotel_id ORDER BY hotel_id) FROM user_hotels WHERE user_id = u.id), '{}')
+		FROM users u
+		WHERE 1 = 1
+	`
+	var args []interface{}
+
+	if search := strings.TrimSpace(c.QueryParam("search")); search != "" {
+		args = append(args, "%"+search+"%")
+		query += fmt.Sprintf(" AND (u.username ILIKE $%d OR u.email ILIKE $%d)", len(args), len(args))
+	}
+
+	if role := c.QueryParam("role"); role != "" {
+		if !isValidRole(role) {
+			return c.JSON(http.StatusBadRequest, dto.ErrorResponse{Message: "Role must be one of 'user', 'admin', 'hotel_manager' or 'front_desk'"})
+		}
+		args = append(args, role)
+		query += fmt.Sprintf(" AND u.role = $%d", len(args))
+	}
+
+	if value := c.QueryParam("disabled"); value != "" {
+		disabled, err := strconv.ParseBool(value)
+		if err != nil {
+			return c.JSON(http.StatusBadRequest, dto.ErrorResponse{Message: "disabled must be true or false"})
+		}
+		if disabled {
+			query += " AND u.disabled_at IS NOT NULL"
+		} else {
+			query += " AND u.disabled_at IS NULL"
+		}
+	}
+
+	if value := c.QueryParam("cursor"); value != "" {
+		afterID, err := decodeIDCursor(value)
+		if err != nil {
+			return c.JSON(http.StatusBadRequest, dto.ErrorResponse{Message: "Invalid cursor"})
+		}
+		args = append(args, afterID)
+		query += fmt.Sprintf(" AND u.id > $%d", len(args))
+	}
+
+	query += fmt.Sprintf(" ORDER BY u.id LIMIT %d", limit+1)
+
+	rows, err := config.DB.Query(query, args...)
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, dto.ErrorResponse{Message: "Failed to retrieve users"})
+	}
+	defer rows.Close()
+
+	users := []models.User{}
+	for rows.Next() {
+		var user models.User
+		var hotelIDs pq.Int64Array
+		err := rows.Scan(&user.UserID, &user.Username, &user.Email, &user.Role, &user.EmailVerifiedAt, &user.TwoFactorEnabled, &user.DisabledAt, &user.LockedUntil, &user.DeletedAt, &user.CreatedAt, &user.UpdatedAt, &hotelIDs)
+		if err != nil {
+			return c.JSON(http.StatusInternalServerError, dto.ErrorResponse{Message: "Failed to scan user"})
+		}
+		for _, id := range hotelIDs {
+			user.HotelIDs = append(user.HotelIDs, int(id))
+		}
+		users = append(users, user)
+	}
+
+	if err := rows.Err(); err != nil {
+		return c.JSON(http.StatusInternalServerError, dto.ErrorResponse{Message: "Error occurred during users retrieval"})
+	}
+
+	var nextCursor *string
+	if len(users) > limit {
+		users = users[:limit]
+		cursor := encodeIDCursor(users[limit-1].UserID)
+		nextCursor = &cursor
+	}
+
+	return c.JSON(http.StatusOK, dto.PageResponse{Data: users, NextCursor: nextCursor})
+}
+
+// parseLimit reads the page size from the limit query parameter.
+func parseLimit(c echo.Context) (int, error) {
+	value := c.QueryParam("limit")
+	if value == "" {
+		return defaultPageLimit, nil
+	}
+
+	limit, err := strconv.Atoi(value)
+	if err != nil || limit <= 0 {
+		return 0, errors.New("limit must be a positive integer")
+	}
+	if limit > maxPageLimit {
+		limit = maxPageLimit
+	}
+	return limit, nil
+}
+
+// encodeIDCursor and decodeIDCursor turn the ID of the last row of a page
+// into the opaque cursor of the next one.
+func encodeIDCursor(id int) string {
+	return base64.RawURLEncoding.EncodeToString([]byte(strconv.Itoa(id)))
+}
+
+func decodeIDCursor(value string) (int, error) {
+	raw, err := base64.RawURLEncoding.DecodeString(value)
+	if err != nil {
+		return 0, err
+	}
+	return strconv.Atoi(string(raw))
+}
+
+// UpdateUserRole changes the role of an account. Hotel staff roles need
+// hotel_ids; other roles drop any hotel assignments. The user's sessions are
+// revoked so the new role applies right away instead of at the next login.
+func UpdateUserRole(c echo.Context) error {
+	userID := c.Param("id")
+
+	var req dto.UpdateUserRoleRequest
+
+	if err := c.Bind(&req); err != nil {
+		return c.JSON(http.StatusBadRequest, dto.ErrorResponse{Message: "Invalid request"})
+	}
+
+	if !isValidRole(req.Role) {
+		return c.JSON(http.StatusBadRequest, dto.ErrorResponse{Message: "Role must be one of 'user', 'admin', 'hotel_manager' or 'front_desk'"})
+	}
+
+	if models.IsHotelStaff(req.Role) {
+		if err := validateHotelIDs(req.HotelIDs); err != nil {
+			return c.JSON(http.StatusBadRequest, dto.ErrorResponse{Message: err.Error()})
+		}
+	} else if len(req.HotelIDs) > 0 {
+		return c.JSON(http.StatusBadRequest, dto.ErrorResponse{Message: "hotel_ids can only be set for hotel staff"})
+	}
+
+	tx, err := config.DB.Begin()
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, dto.ErrorResponse{Message: "Failed to start transaction"})
+	}
+	defer tx.Rollback()
+
+	user, err := lockUser(tx, userID)
+	if err == sql.ErrNoRows {
+		return c.JSON(http.StatusNotFound, dto.ErrorResponse{Message: "User not found"})
+	} else if err != nil {
+		return c.JSON(http.StatusInternalServerError, dto.ErrorResponse{Message: "Failed to retrieve user"})
+	}
+
+	if user.DeletedAt != nil {
+		return c.JSON(http.StatusConflict, dto.ErrorResponse{Message: "Deleted accounts cannot be changed"})
+	}
+
+	if user.Role == models.RoleAdmin && req.Role != models.RoleAdmin && user.DisabledAt == nil {
+		last, err := isLastActiveAdmin(tx, user.UserID)
+		if err != nil {
+			return c.JSON(http.StatusInternalServerError, dto.ErrorResponse{Message: "Failed to update role"})
+		}
+		if last {
+			return c.JSON(http.StatusConflict, dto.ErrorResponse{Message: "Cannot remove the last active administrator"})
+		}
+	}
+
+	if _, err := tx.Exec(`UPDATE users SET role = $1, updated_at = NOW() WHERE id = $2`, req.Role, user.UserID); err != nil {
+		return c.JSON(http.StatusInternalServerError, dto.ErrorResponse{Message: "Failed to update role"})
+	}
+
+	if err := replaceUserHotels(tx, user.UserID, req.HotelIDs); err != nil {
+		return c.JSON(http.StatusInternalServerError, dto.ErrorResponse{Message: "Failed to update role"})
+	}
+
+	if err := revokeUserSessions(tx, user.UserID); err != nil {
+		return c.JSON(http.StatusInternalServerError, dto.ErrorResponse{Message: "Failed to revoke sessions"})
+	}
+
+	if err := tx.Commit(); err != nil {
+		return c.JSON(http.StatusInternalServerError, dto.ErrorResponse{Message: "Failed to update role"})
+	}
+
+	return c.JSON(http.StatusOK, dto.SuccessResponse{Message: "User role updated successfully"})
+}
+
+// UpdateUserStatus disables or re-enables an account. Disabled accounts
+// cannot log in or refresh tokens, and their sessions are revoked.
+func UpdateUserStatus(c echo.Context) error {
+	userID := c.Param("id")
+
+	var req dto.UpdateUserStatusRequest
+
+	if err := c.Bind(&req); err != nil {
+		return c.JSON(http.StatusBadRequest, dto.ErrorResponse{Message: "Invalid request"})
+	}
+
+	if req.Disabled == nil {
+		return c.JSON(http.StatusBadRequest, dto.ErrorResponse{Message: "disabled is required"})
+	}
+
+	tx, err := config.DB.Begin()
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, dto.ErrorResponse{Message: "Failed to start transaction"})
+	}
+	defer tx.Rollback()
+
+	user, err := lockUser(tx, userID)
+	if err == sql.ErrNoRows {
+		return c.JSON(http.StatusNotFound, dto.ErrorResponse{Message: "User not found"})
+	} else if err != nil {
+		return c.JSON(http.StatusInternalServerError, dto.ErrorResponse{Message: "Failed to retrieve user"})
+	}
+
+	if !*req.Disabled {
+		if user.DeletedAt != nil {
+			return c.JSON(http.StatusConflict, dto.ErrorResponse{Message: "Deleted accounts cannot be enabled"})
+		}
+		if _, err := tx.Exec(`UPDATE users SET disabled_at = NULL, updated_at = NOW() WHERE id = $1`, user.UserID); err != nil {
+			return c.JSON(http.StatusInternalServerError, dto.ErrorResponse{Message: "Failed to update user status"})
+		}
+		if err := tx.Commit(); err != nil {
+			return c.JSON(http.StatusInternalServerError, dto.ErrorResponse{Message: "Failed to update user status"})
+		}
+		return c.JSON(http.StatusOK, dto.SuccessResponse{Message: "User enabled successfully"})
+	}
+
+	if user.Role == models.RoleAdmin && user.DisabledAt == nil {
+		last, err := isLastActiveAdmin(tx, user.UserID)
+		if err != nil {
+			return c.JSON(http.StatusInternalServerError, dto.ErrorResponse{Message: "Failed to update user status"})
+		}
+		if last {
+			return c.JSON(http.StatusConflict, dto.ErrorResponse{Message: "Cannot disable the last active administrator"})
+		}
+	}
+
+	query := `UPDATE users SET disabled_at = COALESCE(disabled_at, NOW()), updated_at = NOW() WHERE id = $1`
+	if _, err := tx.Exec(query, user.UserID); err != nil {
+		return c.JSON(http.StatusInternalServerError, dto.ErrorResponse{Message: "Failed to update user status"})
+	}
+
+	if err := revokeUserSessions(tx, user.UserID); err != nil {
+		return c.JSON(http.StatusInternalServerError, dto.ErrorResponse{Message: "Failed to revoke sessions"})
+	}
+
+	if err := tx.Commit(); err != nil {
+		return c.JSON(http.StatusInternalServerError, dto.ErrorResponse{Message: "Failed to update user status"})
+	}
+
+	return c.JSON(http.StatusOK, dto.SuccessResponse{Message: "User disabled successfully"})
+}
+
+// UnlockUser lifts a lockout caused by failed logins and starts the
+// failure count over.
+func UnlockUser(c echo.Context) error {
+	userID := c.Param("id")
+
+	tx, err := config.DB.Begin()
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, dto.ErrorResponse{Message: "Failed to start transaction"})
+	}
+	defer tx.Rollback()
+
+	var user models.User
+	err = tx.QueryRow(`SELECT id, email FROM users WHERE id = $1 FOR UPDATE`, userID).Scan(&user.UserID, &user.Email)
+	if err == sql.ErrNoRows {
+		return c.JSON(http.StatusNotFound, dto.ErrorResponse{Message: "User not found"})
+	} else if err != nil {
+		return c.JSON(http.StatusInternalServerError, dto.ErrorResponse{Message: "Failed to retrieve user"})
+	}
+
+	if _, err := tx.Exec(`UPDATE users SET locked_until = NULL, updated_at = NOW() WHERE id = $1`, user.UserID); err != nil {
+		return c.JSON(http.StatusInternalServerError, dto.ErrorResponse{Message: "Failed to unlock user"})
+	}
+
+	query := `
+		INSERT INTO login_attempts (user_id, email, ip_address, user_agent, outcome, created_at)
+		VALUES ($1, $2, $3, $4, $5, NOW())
+	`
+	_, err = tx.Exec(query, user.UserID, user.Email, c.RealIP(), c.Request().UserAgent(), loginUnlocked)
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, dto.ErrorResponse{Message: "Failed to unlock user"})
+	}
+
+	if err := tx.Commit(); err != nil {
+		return c.JSON(http.StatusInternalServerError, dto.ErrorResponse{Message: "Failed to unlock user"})
+	}
+
+	return c.JSON(http.StatusOK, dto.SuccessResponse{Message: "User unlocked successfully"})
+}
+
+// ListLoginAttempts returns the login audit trail, newest first, filtered
+// by user_id, email, ip and outcome.
+func ListLoginAttempts(c echo.Context) error {
+	limit, err := parseLimit(c)
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, dto.ErrorResponse{Message: err.Error()})
+	}
+
+	query := `SELECT id, user_id, email, ip_address, user_agent, outcome, created_at FROM login_attempts WHERE 1 = 1`
+	var args []interface{}
+
+	if value := c.QueryParam("user_id"); value != "" {
+		id, err := strconv.Atoi(value)
+		if err != nil {
+			return c.JSON(http.StatusBadRequest, dto.ErrorResponse{Message: "user_id must be an integer"})
+		}
+		args = append(args, id)
+		query += fmt.Sprintf(" AND user_id = $%d", len(args))
+	}
+
+	filters := []struct{ param, column string }{
+		{"email", "email"},
+		{"ip", "ip_address"},
+		{"outcome", "outcome"},
+	}
+	for _, filter := range filters {
+		if value := c.QueryParam(filter.param); value != "" {
+			args = append(args, value)
+			query += fmt.Sprintf(" AND %s = $%d", filter.column, len(args))
+		}
+	}
+
+	if value := c.QueryParam("cursor"); value != "" {
+		beforeID, err := decodeIDCursor(value)
+		if err != nil {
+			return c.JSON(http.StatusBadRequest, dto.ErrorResponse{Message: "Invalid cursor"})
+		}
+		args = append(args, beforeID)
+		query += fmt.Sprintf(" AND id < $%d", len(args))
+	}
+
+	query += fmt.Sprintf(" ORDER BY id DESC LIMIT %d", limit+1)
+
+	rows, err := config.DB.Query(query, args...)
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, dto.ErrorResponse{Message: "Failed to retrieve login attempts"})
+	}
+	defer rows.Close()
+
+	attempts := []models.LoginAttempt{}
+	for rows.Next() {
+		var attempt models.LoginAttempt
+		err := rows.Scan(&attempt.ID, &attempt.UserID, &attempt.Email, &attempt.IPAddress, &attempt.UserAgent, &attempt.Outcome, &attempt.CreatedAt)
+		if err != nil {
+			return c.JSON(http.StatusInternalServerError, dto.ErrorResponse{Message: "Failed to scan login attempt"})
+		}
+		attempts = append(attempts, attempt)
+	}
+
+	if err := rows.Err(); err != nil {
+		return c.JSON(http.StatusInternalServerError, dto.ErrorResponse{Message: "Error occurred during login attempts retrieval"})
+	}
+
+	var nextCursor *string
+	if len(attempts) > limit {
+		attempts = attempts[:limit]
+		cursor := encodeIDCursor(attempts[limit-1].ID)
+		nextCursor = &cursor
+	}
+
+	return c.JSON(http.StatusOK, dto.PageResponse{Data: attempts, NextCursor: nextCursor})
+}
+
+// lockUser loads the account an admin action applies to and locks it until
+// the transaction ends.
+func lockUser(db dbExecutor, userID string) (models.User, error) {
+	var user models.User
+	query := `SELECT id, role, disabled_at, deleted_at FROM users WHERE id = $1 FOR UPDATE`
+	err := db.QueryRow(query, userID).Scan(&user.UserID, &user.Role, &user.DisabledAt, &user.DeletedAt)
+	return user, err
+}
+
+// isLastActiveAdmin reports whether no other enabled administrator exists.
+// The other admins are locked so two concurrent demotions cannot both pass.
+func isLastActiveAdmin(db dbExecutor, userID int) (bool, error) {
+	var others int
+	query := `
+		SELECT COUNT(*) FROM (
+			SELECT id FROM users
+			WHERE role = $1 AND disabled_at IS NULL AND id <> $2
+			FOR UPDATE
+		) admins
+	`
+	err := db.QueryRow(query, models.RoleAdmin, userID).Scan(&others)
+	return others == 0, err
+}
+
+// revokeUserSessions revokes every session of a user, like revokeSession
+// does for a single one.
+func revokeUserSessions(db dbExecutor, userID int) error {
+	return revokeOtherSessions(db, userID, "")
+}
+
+// revokeOtherSessions revokes every session of a user except keepSessionID.
+func revokeOtherSessions(db dbExecutor, userID int, keepSessionID string) error {
+	query := `
+		INSERT INTO revoked_sessions (session_id, user_id, revoked_at, expires_at)
+		SELECT DISTINCT family_id, user_id, NOW(), $2::timestamp
+		FROM refresh_tokens
+		WHERE user_id = $1 AND revoked_at IS NULL AND family_id <> $3
+		ON CONFLICT (session_id) DO NOTHING
+	`
+	if _, err := db.Exec(query, userID, time.Now().Add(accessTokenTTL), keepSessionID); err != nil {
+		return err
+	}
+
+	query = `UPDATE refresh_tokens SET revoked_at = NOW() WHERE user_id = $1 AND revoked_at IS NULL AND family_id <> $2`
+	_, err := db.Exec(query, userID, keepSessionID)
+	return err
+}
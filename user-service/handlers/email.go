@@ -0,0 +1,299 @@
+package handler
+
+import (
+	"database/sql"
+	"fmt"
+	"html/template"
+	"log"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+	"user-service/config"
+	"user-service/dto"
+	"user-service/mailer"
+
+	"github.com/labstack/echo/v4"
+	"golang.org/x/crypto/bcrypt"
+)
+
+// Purposes of single-use tokens sent by email.
+const (
+	tokenVerifyEmail   = "verify_email"
+	tokenResetPassword = "reset_password"
+)
+
+const (
+	verifyEmailTokenTTL   = 48 * time.Hour
+	resetPasswordTokenTTL = time.Hour
+)
+
+// Paths the links in account emails open. They are plain links, so the
+// gateway and this service serve them with GET.
+const (
+	VerifyEmailPath        = "/email/verify"
+	ResetPasswordPath      = "/password/reset"
+	ConfirmEmailChangePath = "/email/change/confirm"
+)
+
+// resetPasswordForm asks for the new password and posts it together with
+// the token from the reset link.
+var resetPasswordForm = template.Must(template.New("reset_password").Parse(`<!DOCTYPE html>
+<html>
+<head><title>Reset your password</title></head>
+<body>
+<form method="post" action="/password/reset">
+<input type="hidden" name="token" value="{{.}}">
+<label>New password <input type="password" name="password" minlength="6" required></label>
+<button type="submit">Reset password</button>
+</form>
+</body>
+</html>
+`))
+
+// issueUserToken creates a single-use token for purpose and invalidates the
+// ones issued before it, so only the latest email works.
+func issueUserToken(db dbExecutor, userID int, purpose string, ttl time.Duration) (string, error) {
+	token, err := randomToken(32)
+	if err != nil {
+		return "", err
+	}
+
+	query := `UPDATE user_tokens SET used_at = NOW() WHERE user_id = $1 AND purpose = $2 AND used_at IS NULL`
+	if _, err := db.Exec(query, userID, purpose); err != nil {
+		return "", err
+	}
+
+	query = `
+		INSERT INTO user_tokens (user_id, purpose, token_hash, expires_at, created_at)
+		VALUES ($1, $2, $3, $4, NOW())
+	`
+	if _, err := db.Exec(query, userID, purpose, hashToken(token), time.Now().Add(ttl)); err != nil {
+		return "", err
+	}
+	return token, nil
+}
+
+// consumeUserToken marks a token as used and returns the user it was issued
+// to. It returns sql.ErrNoRows when the token is unknown, expired or was
+// already used.
+func consumeUserToken(db dbExecutor, token, purpose string) (int, error) {
+	var userID int
+	query := `
+		UPDATE user_tokens SET used_at = NOW()
+		WHERE token_hash = $1 AND purpose = $2 AND used_at IS NULL AND expires_at > NOW()
+		RETURNING user_id
+	`
+	err := db.QueryRow(query, hashToken(token), purpose).Scan(&userID)
+	return userID, err
+}
+
+// tokenLink builds the link an email points to.
+func tokenLink(path, token string) string {
+	return fmt.Sprintf("%s%s?token=%s", config.AppBaseURL, path, url.QueryEscape(token))
+}
+
+func sendVerificationEmail(email, username, token string) error {
+	return config.Mailer.Send(mailer.Message{
+		To:      email,
+		Subject: "Verify your email address",
+		Body: fmt.Sprintf("Hi %s,\n\nPlease confirm your email address by opening the link below:\n\n%s\n\nThe link expires in %d hours.\n",
+			username, tokenLink(VerifyEmailPath, token), int(verifyEmailTokenTTL.Hours())),
+	})
+}
+
+func sendPasswordResetEmail(email, username, token string) error {
+	return config.Mailer.Send(mailer.Message{
+		To:      email,
+		Subject: "Reset your password",
+		Body: fmt.Sprintf("Hi %s,\n\nSomeone asked to reset the password of your account. If that was you, open the link below:\n\n%s\n\nThe link expires in %d minutes. If you did not ask for this you can ignore this email.\n",
+			username, tokenLink(ResetPasswordPath, token), int(resetPasswordTokenTTL.Minutes())),
+	})
+}
+
+// VerifyEmail confirms the address of the account a verification token was
+// sent to. The token comes in the body, or in the query string when the
+// link from the email is opened.
+func VerifyEmail(c echo.Context) error {
+	var req dto.VerifyEmailRequest
+
+	if err := c.Bind(&req); err != nil {
+		return c.JSON(http.StatusBadRequest, dto.ErrorResponse{Message: "Invalid request"})
+	}
+
+	if req.Token == "" {
+		return c.JSON(http.StatusBadRequest, dto.ErrorResponse{Message: "token is required"})
+	}
+
+	tx, err := config.DB.Begin()
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, dto.ErrorResponse{Message: "Failed to start transaction"})
+	}
+	defer tx.Rollback()
+
+	userID, err := consumeUserToken(tx, req.Token, tokenVerifyEmail)
+	if err == sql.ErrNoRows {
+		return c.JSON(http.StatusBadRequest, dto.ErrorResponse{Message: "Invalid or expired token"})
+	} else if err != nil {
+		return c.JSON(http.StatusInternalServerError, dto.ErrorResponse{Message: "Failed to verify email"})
+	}
+
+	query := `UPDATE users SET email_verified_at = COALESCE(email_verified_at, NOW()), updated_at = NOW() WHERE id = $1`
+	if _, err := tx.Exec(query, userID); err != nil {
+		return c.JSON(http.StatusInternalServerError, dto.ErrorResponse{Message: "Failed to verify email"})
+	}
+
+	if err := tx.Commit(); err != nil {
+		return c.JSON(http.StatusInternalServerError, dto.ErrorResponse{Message: "Failed to verify email"})
+	}
+
+	return c.JSON(http.StatusOK, dto.SuccessResponse{Message: "Email verified successfully"})
+}
+
+// ResendVerificationEmail sends a new verification link. The response is
+// the same whether or not the address belongs to an unverified account, so
+// it cannot be used to find registered emails.
+func ResendVerificationEmail(c echo.Context) error {
+	var req dto.EmailRequest
+
+	if err := c.Bind(&req); err != nil {
+		return c.JSON(http.StatusBadRequest, dto.ErrorResponse{Message: "Invalid request"})
+	}
+
+	if !isValidEmail(req.Email) {
+		return c.JSON(http.StatusBadRequest, dto.ErrorResponse{Message: "Invalid email format"})
+	}
+
+	response := dto.SuccessResponse{Message: "If the email belongs to an unverified account, a verification link has been sent"}
+
+	var userID int
+	var username string
+	query := `SELECT id, username FROM users WHERE email = $1 AND email_verified_at IS NULL AND disabled_at IS NULL`
+	err := config.DB.QueryRow(query, req.Email).Scan(&userID, &username)
+	if err == sql.ErrNoRows {
+		return c.JSON(http.StatusOK, response)
+	} else if err != nil {
+		return c.JSON(http.StatusInternalServerError, dto.ErrorResponse{Message: "Failed to retrieve user"})
+	}
+
+	token, err := issueUserToken(config.DB, userID, tokenVerifyEmail, verifyEmailTokenTTL)
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, dto.ErrorResponse{Message: "Failed to create verification token"})
+	}
+
+	if err := sendVerificationEmail(req.Email, username, token); err != nil {
+		log.Printf("Failed to send verification email to user %d: %v\n", userID, err)
+		return c.JSON(http.StatusInternalServerError, dto.ErrorResponse{Message: "Failed to send verification email"})
+	}
+
+	return c.JSON(http.StatusOK, response)
+}
+
+// ForgotPassword emails a password reset link. Like
+// ResendVerificationEmail it answers the same for unknown addresses.
+func ForgotPassword(c echo.Context) error {
+	var req dto.EmailRequest
+
+	if err := c.Bind(&req); err != nil {
+		return c.JSON(http.StatusBadRequest, dto.ErrorResponse{Message: "Invalid request"})
+	}
+
+	if !isValidEmail(req.Email) {
+		return c.JSON(http.StatusBadRequest, dto.ErrorResponse{Message: "Invalid email format"})
+	}
+
+	response := dto.SuccessResponse{Message: "If the email is registered, a password reset link has been sent"}
+
+	var userID int
+	var username string
+	query := `SELECT id, username FROM users WHERE email = $1 AND disabled_at IS NULL`
+	err := config.DB.QueryRow(query, req.Email).Scan(&userID, &username)
+	if err == sql.ErrNoRows {
+		return c.JSON(http.StatusOK, response)
+	} else if err != nil {
+		return c.JSON(http.StatusInternalServerError, dto.ErrorResponse{Message: "Failed to retrieve user"})
+	}
+
+	token, err := issueUserToken(config.DB, userID, tokenResetPassword, resetPasswordTokenTTL)
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, dto.ErrorResponse{Message: "Failed to create reset token"})
+	}
+
+	if err := sendPasswordResetEmail(req.Email, username, token); err != nil {
+		log.Printf("Failed to send password reset email to user %d: %v\n", userID, err)
+		return c.JSON(http.StatusInternalServerError, dto.ErrorResponse{Message: "Failed to send password reset email"})
+	}
+
+	return c.JSON(http.StatusOK, response)
+}
+
+// ResetPasswordForm is the page the reset link opens. It only renders the
+// form; the token is checked when the form is posted to ResetPassword.
+func ResetPasswordForm(c echo.Context) error {
+	token := c.QueryParam("token")
+	if strings.TrimSpace(token) == "" {
+		return c.JSON(http.StatusBadRequest, dto.ErrorResponse{Message: "token is required"})
+	}
+
+	var page strings.Builder
+	if err := resetPasswordForm.Execute(&page, token); err != nil {
+		return c.JSON(http.StatusInternalServerError, dto.ErrorResponse{Message: "Failed to render page"})
+	}
+	return c.HTML(http.StatusOK, page.String())
+}
+
+// ResetPassword sets a new password using a reset token. Every session of
+// the account is revoked, since the reset may be recovering it from
+// someone else. Receiving the link also proves ownership of the address.
+func ResetPassword(c echo.Context) error {
+	var req dto.ResetPasswordRequest
+
+	if err := c.Bind(&req); err != nil {
+		return c.JSON(http.StatusBadRequest, dto.ErrorResponse{Message: "Invalid request"})
+	}
+
+	if strings.TrimSpace(req.Token) == "" {
+		return c.JSON(http.StatusBadRequest, dto.ErrorResponse{Message: "token is required"})
+	}
+
+	if len(req.Password) < 6 {
+		return c.JSON(http.StatusBadRequest, dto.ErrorResponse{Message: "Password must be at least 6 characters"})
+	}
+
+	hashedPassword, err := bcrypt.GenerateFromPassword([]byte(req.Password), bcrypt.DefaultCost)
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, dto.ErrorResponse{Message: "Failed to hash password"})
+	}
+
+	tx, err := config.DB.Begin()
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, dto.ErrorResponse{Message: "Failed to start transaction"})
+	}
+	defer tx.Rollback()
+
+	userID, err := consumeUserToken(tx, req.Token, tokenResetPassword)
+	if err == sql.ErrNoRows {
+		return c.JSON(http.StatusBadRequest, dto.ErrorResponse{Message: "Invalid or expired token"})
+	} else if err != nil {
+		return c.JSON(http.StatusInternalServerError, dto.ErrorResponse{Message: "Failed to reset password"})
+	}
+
+	query := `
+		UPDATE users
+		SET password = $1, email_verified_at = COALESCE(email_verified_at, NOW()), updated_at = NOW()
+		WHERE id = $2
+	`
+	if _, err := tx.Exec(query, string(hashedPassword), userID); err != nil {
+		return c.JSON(http.StatusInternalServerError, dto.ErrorResponse{Message: "Failed to reset password"})
+	}
+
+	if err := revokeUserSessions(tx, userID); err != nil {
+		return c.JSON(http.StatusInternalServerError, dto.ErrorResponse{Message: "Failed to revoke sessions"})
+	}
+
+	if err := tx.Commit(); err != nil {
+		return c.JSON(http.StatusInternalServerError, dto.ErrorResponse{Message: "Failed to reset password"})
+	}
+
+	return c.JSON(http.StatusOK, dto.SuccessResponse{Message: "Password reset successfully"})
+}
@@ -0,0 +1,74 @@
+package handler
+
+import (
+	"database/sql"
+	"net/http"
+	"user-service/config"
+	"user-service/dto"
+	"user-service/models"
+
+	"github.com/labstack/echo/v4"
+)
+
+// ExportUserData returns the account of a user together with its login
+// history. It backs the gateway's personal data export.
+func ExportUserData(c echo.Context) error {
+	userID := c.Param("id")
+
+	query := `
+		SELECT id, username, email, pending_email, role, email_verified_at, totp_enabled_at IS NOT NULL,
+			disabled_at, locked_until, deleted_at, created_at, updated_at
+		FROM users WHERE id = $1
+	`
+
+	var export dto.UserDataExport
+	user := &export.Account
+	err := config.DB.QueryRow(query, userID).Scan(
+		&user.UserID,
+		&user.Username,
+		&user.Email,
+		&user.PendingEmail,
+		&user.Role,
+		&user.EmailVerifiedAt,
+		&user.TwoFactorEnabled,
+		&user.DisabledAt,
+		&user.LockedUntil,
+		&user.DeletedAt,
+		&user.CreatedAt,
+		&user.UpdatedAt,
+	)
+	if err == sql.ErrNoRows {
+		return c.JSON(http.StatusNotFound, dto.ErrorResponse{Message: "User not found"})
+	} else if err != nil {
+		return c.JSON(http.StatusInternalServerError, dto.ErrorResponse{Message: "Failed to retrieve user"})
+	}
+
+	if models.IsHotelStaff(user.Role) {
+		if user.HotelIDs, err = loadHotelIDs(config.DB, user.UserID); err != nil {
+			return c.JSON(http.StatusInternalServerError, dto.ErrorResponse{Message: "Failed to retrieve user"})
+		}
+	}
+
+	query = `SELECT id, user_id, email, ip_address, user_agent, outcome, created_at FROM login_attempts WHERE user_id = $1 ORDER BY id`
+	rows, err := config.DB.Query(query, user.UserID)
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, dto.ErrorResponse{Message: "Failed to retrieve login attempts"})
+	}
+	defer rows.Close()
+
+	export.LoginAttempts = []models.LoginAttempt{}
+	for rows.Next() {
+		var attempt models.LoginAttempt
+		err := rows.Scan(&attempt.ID, &attempt.UserID, &attempt.Email, &attempt.IPAddress, &attempt.UserAgent, &attempt.Outcome, &attempt.CreatedAt)
+		if err != nil {
+			return c.JSON(http.StatusInternalServerError, dto.ErrorResponse{Message: "Failed to scan login attempt"})
+		}
+		export.LoginAttempts = append(export.LoginAttempts, attempt)
+	}
+
+	if err := rows.Err(); err != nil {
+		return c.JSON(http.StatusInternalServerError, dto.ErrorResponse{Message: "Error occurred during login attempts retrieval"})
+	}
+
+	return c.JSON(http.StatusOK, export)
+}
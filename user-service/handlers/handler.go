@@ -2,8 +2,12 @@ package handler
 
 import (
 	"database/sql"
+	"fmt"
+	"log"
+	"math"
 	"net/http"
 	"regexp"
+	"strconv"
 	"time"
 	"user-service/config"
 	"user-service/dto"
@@ -26,6 +30,8 @@ func isUserExists(db *sql.DB, username, email string) (bool, error) {
 	return exists, err
 }
 
+// RegisterUser is the public sign-up. It always creates a guest account;
+// staff and admins are created through the admin API.
 func RegisterUser(c echo.Context) error {
 	var req dto.RegisterUserRequest
 
@@ -33,6 +39,12 @@ func RegisterUser(c echo.Context) error {
 		return c.JSON(http.StatusBadRequest, dto.ErrorResponse{Message: "Invalid request"})
 	}
 
+	return createUser(c, req, models.RoleUser, nil, "User registered successfully")
+}
+
+// createUser validates and inserts a new account with the given role.
+// hotelIDs is required for hotel staff and must be empty otherwise.
+func createUser(c echo.Context, req dto.RegisterUserRequest, role string, hotelIDs []int, message string) error {
 	if req.Username == "" {
 		return c.JSON(http.StatusBadRequest, dto.ErrorResponse{Message: "Username is required"})
 	}
@@ -45,8 +57,16 @@ func RegisterUser(c echo.Context) error {
 		return c.JSON(http.StatusBadRequest, dto.ErrorResponse{Message: "Password must be at least 6 characters"})
 	}
 
-	if req.Role != "user" && req.Role != "admin" {
-		return c.JSON(http.StatusBadRequest, dto.ErrorResponse{Message: "Role must be either 'user' or 'admin"})
+	if !isValidRole(role) {
+		return c.JSON(http.StatusBadRequest, dto.ErrorResponse{Message: "Role must be one of 'user', 'admin', 'hotel_manager' or 'front_desk'"})
+	}
+
+	if models.IsHotelStaff(role) {
+		if err := validateHotelIDs(hotelIDs); err != nil {
+			return c.JSON(http.StatusBadRequest, dto.ErrorResponse{Message: err.Error()})
+		}
+	} else if len(hotelIDs) > 0 {
+		return c.JSON(http.StatusBadRequest, dto.ErrorResponse{Message: "hotel_ids can only be set for hotel staff"})
 	}
 
 	exists, err := isUserExists(config.DB, req.Username, req.Email)
@@ -66,42 +86,83 @@ func RegisterUser(c echo.Context) error {
 		Username:  req.Username,
 		Email:     req.Email,
 		Password:  string(hashedPassword),
-		Role:      req.Role,
+		Role:      role,
 		CreatedAt: time.Now(),
 		UpdatedAt: time.Now(),
 	}
 
+	tx, err := config.DB.Begin()
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, dto.ErrorResponse{Message: "Failed to register user"})
+	}
+	defer tx.Rollback()
+
 	query := `
 		INSERT INTO users (username, email, password, role, created_at, updated_at)
 		VALUES ($1, $2, $3, $4, $5, $6)
 		RETURNING id
 	`
 
-	err = config.DB.QueryRow(query, newUser.Username, newUser.Email, newUser.Password, newUser.Role, newUser.CreatedAt, newUser.UpdatedAt).Scan(&newUser.UserID)
+	err = tx.QueryRow(query, newUser.Username, newUser.Email, newUser.Password, newUser.Role, newUser.CreatedAt, newUser.UpdatedAt).Scan(&newUser.UserID)
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, dto.ErrorResponse{Message: "Failed to register user"})
+	}
+
+	if len(hotelIDs) > 0 {
+		if err := replaceUserHotels(tx, newUser.UserID, hotelIDs); err != nil {
+			return c.JSON(http.StatusInternalServerError, dto.ErrorResponse{Message: "Failed to register user"})
+		}
+	}
+
+	verifyToken, err := issueUserToken(tx, newUser.UserID, tokenVerifyEmail, verifyEmailTokenTTL)
 	if err != nil {
 		return c.JSON(http.StatusInternalServerError, dto.ErrorResponse{Message: "Failed to register user"})
 	}
 
+	if err := tx.Commit(); err != nil {
+		return c.JSON(http.StatusInternalServerError, dto.ErrorResponse{Message: "Failed to register user"})
+	}
+
+	// The account exists either way; a lost email can be sent again.
+	if err := sendVerificationEmail(newUser.Email, newUser.Username, verifyToken); err != nil {
+		log.Printf("Failed to send verification email to user %d: %v\n", newUser.UserID, err)
+	}
+
 	return c.JSON(http.StatusCreated, dto.RegisterUserResponse{
 		UserID:   newUser.UserID,
 		Username: newUser.Username,
 		Email:    newUser.Email,
 		Role:     newUser.Role,
-		Message:  "User registered successfully",
+		HotelIDs: hotelIDs,
+		Message:  message,
 	})
 }
 
-var jwtSecret = []byte("rahasia")
+// createJWT issues a short-lived access token for a login session. Hotel
+// staff also get the hotels they are assigned to, which the gateway uses to
+// scope their requests.
+func createJWT(user models.User, sessionID string) (string, error) {
+	tokenID, err := randomToken(16)
+	if err != nil {
+		return "", err
+	}
 
-func createJWT(userID int, email, role string) (string, error) {
 	claims := jwt.MapClaims{
-		"user_id": userID,
-		"email":   email,
-		"role":    role,
-		"exp":     time.Now().Add(time.Hour * 72).Unix(),
+		"user_id": user.UserID,
+		"email":   user.Email,
+		"role":    user.Role,
+		"sid":     sessionID,
+		"jti":     tokenID,
+		"exp":     time.Now().Add(accessTokenTTL).Unix(),
 	}
-	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
-	return token.SignedString(jwtSecret)
+	if models.IsHotelStaff(user.Role) {
+		claims["hotel_ids"] = user.HotelIDs
+	}
+
+	key := config.ActiveSigningKey
+	token := jwt.NewWithClaims(jwt.GetSigningMethod(key.Alg), claims)
+	token.Header["kid"] = key.ID
+	return token.SignedString(key.Key)
 }
 
 func LoginUser(c echo.Context) error {
@@ -118,28 +179,109 @@ func LoginUser(c echo.Context) error {
 		return c.JSON(http.StatusBadRequest, dto.ErrorResponse{Message: "Password is required"})
 	}
 
+	attempt := loginAttempt{email: req.Email, ip: c.RealIP(), userAgent: c.Request().UserAgent()}
+
+	wait, err := loginRetryAfter(config.DB, attempt.email, attempt.ip)
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, dto.ErrorResponse{Message: "Failed to check login attempts"})
+	}
+	if wait > 0 {
+		attempt.outcome = loginThrottled
+		recordLoginAttempt(config.DB, attempt)
+
+		seconds := int(math.Ceil(wait.Seconds()))
+		c.Response().Header().Set("Retry-After", strconv.Itoa(seconds))
+		return c.JSON(http.StatusTooManyRequests, dto.ErrorResponse{
+			Message: fmt.Sprintf("Too many failed login attempts, try again in %d seconds", seconds),
+		})
+	}
+
 	var user models.User
-	query := `SELECT id, email, password, role FROM users WHERE email = $1`
-	err := config.DB.QueryRow(query, req.Email).Scan(&user.UserID, &user.Email, &user.Password, &user.Role)
+	var locked bool
+	query := `
+		SELECT id, email, password, role, disabled_at, totp_enabled_at IS NOT NULL, COALESCE(locked_until > NOW(), false)
+		FROM users WHERE email = $1
+	`
+	err = config.DB.QueryRow(query, req.Email).Scan(
+		&user.UserID, &user.Email, &user.Password, &user.Role, &user.DisabledAt, &user.TwoFactorEnabled, &locked)
 	if err == sql.ErrNoRows {
+		attempt.outcome = loginInvalidCredentials
+		recordLoginAttempt(config.DB, attempt)
 		return c.JSON(http.StatusUnauthorized, dto.ErrorResponse{Message: "Invalid email or password"})
 	} else if err != nil {
 		return c.JSON(http.StatusInternalServerError, dto.ErrorResponse{Message: "Failed to query user"})
 	}
+	attempt.userID = &user.UserID
+
+	// A locked account is rejected before the password is checked, so the
+	// lock cannot be used to keep guessing.
+	if locked {
+		attempt.outcome = loginLocked
+		recordLoginAttempt(config.DB, attempt)
+		return c.JSON(http.StatusLocked, dto.ErrorResponse{Message: "Account is temporarily locked due to too many failed login attempts"})
+	}
 
 	err = bcrypt.CompareHashAndPassword([]byte(user.Password), []byte(req.Password))
 	if err != nil {
+		attempt.outcome = loginInvalidCredentials
+		recordLoginAttempt(config.DB, attempt)
+		if err := lockIfTooManyFailures(config.DB, user.UserID, user.Email); err != nil {
+			log.Printf("Failed to lock user %d: %v\n", user.UserID, err)
+		}
 		return c.JSON(http.StatusUnauthorized, dto.ErrorResponse{Message: "Invalid email or password"})
 	}
 
-	token, err := createJWT(user.UserID, user.Email, user.Role)
+	if user.DisabledAt != nil {
+		attempt.outcome = loginDisabled
+		recordLoginAttempt(config.DB, attempt)
+		return c.JSON(http.StatusForbidden, dto.ErrorResponse{Message: "Account is disabled"})
+	}
+
+	// Staff always need a second factor; those who have not set one up
+	// yet do so with the challenge before they get a session.
+	if user.TwoFactorEnabled || requiresTwoFactor(user.Role) {
+		challenge, err := createLoginChallenge(config.DB, user.UserID)
+		if err != nil {
+			return c.JSON(http.StatusInternalServerError, dto.ErrorResponse{Message: "Failed to create login challenge"})
+		}
+
+		attempt.outcome = loginChallenged
+		recordLoginAttempt(config.DB, attempt)
+
+		return c.JSON(http.StatusOK, dto.LoginChallengeResponse{
+			TwoFactorRequired:  true,
+			EnrollmentRequired: !user.TwoFactorEnabled,
+			ChallengeToken:     challenge,
+			ExpiresIn:          int(loginChallengeTTL.Seconds()),
+		})
+	}
+
+	response, err := startSession(config.DB, user)
 	if err != nil {
 		return c.JSON(http.StatusInternalServerError, dto.ErrorResponse{Message: "Failed to create token"})
 	}
 
-	return c.JSON(http.StatusOK, dto.LoginUserResponse{
-		Token: token,
-	})
+	attempt.outcome = loginSuccess
+	recordLoginAttempt(config.DB, attempt)
+
+	return c.JSON(http.StatusOK, response)
+}
+
+// startSession opens a new login session for an authenticated user.
+func startSession(db dbExecutor, user models.User) (dto.LoginUserResponse, error) {
+	if models.IsHotelStaff(user.Role) {
+		var err error
+		if user.HotelIDs, err = loadHotelIDs(db, user.UserID); err != nil {
+			return dto.LoginUserResponse{}, err
+		}
+	}
+
+	sessionID, err := newSessionID()
+	if err != nil {
+		return dto.LoginUserResponse{}, err
+	}
+
+	return issueTokens(db, user, sessionID)
 }
 
 func GetUserByID(c echo.Context) error {
@@ -149,7 +291,10 @@ func GetUserByID(c echo.Context) error {
 		return c.JSON(http.StatusBadRequest, dto.ErrorResponse{Message: "User ID is required"})
 	}
 
-	query := `SELECT id, username, email, role, created_at, updated_at FROM users WHERE id = $1`
+	query := `
+		SELECT id, username, email, pending_email, role, email_verified_at, totp_enabled_at IS NOT NULL, disabled_at, created_at, updated_at
+		FROM users WHERE id = $1
+	`
 
 	var user models.User
 
@@ -157,7 +302,11 @@ func GetUserByID(c echo.Context) error {
 		&user.UserID,
 		&user.Username,
 		&user.Email,
+		&user.PendingEmail,
 		&user.Role,
+		&user.EmailVerifiedAt,
+		&user.TwoFactorEnabled,
+		&user.DisabledAt,
 		&user.CreatedAt,
 		&user.UpdatedAt,
 	)
@@ -167,6 +316,12 @@ func GetUserByID(c echo.Context) error {
 		return c.JSON(http.StatusInternalServerError, dto.ErrorResponse{Message: "Failed to retrieve user"})
 	}
 
+	if models.IsHotelStaff(user.Role) {
+		if user.HotelIDs, err = loadHotelIDs(config.DB, user.UserID); err != nil {
+			return c.JSON(http.StatusInternalServerError, dto.ErrorResponse{Message: "Failed to retrieve user"})
+		}
+	}
+
 	return c.JSON(http.StatusOK, user)
 }
 
@@ -0,0 +1,51 @@
+package handler
+
+import (
+	"crypto/ecdsa"
+	"crypto/rsa"
+	"encoding/base64"
+	"math/big"
+	"net/http"
+	"user-service/config"
+	"user-service/dto"
+
+	"github.com/labstack/echo/v4"
+)
+
+// JWKS publishes the public half of every asymmetric signing key so the
+// gateway can verify tokens without sharing secrets. HS256 keys are
+// symmetric and never published.
+func JWKS(c echo.Context) error {
+	keys := []dto.JSONWebKey{}
+
+	for _, key := range config.SigningKeys {
+		switch privateKey := key.Key.(type) {
+		case *rsa.PrivateKey:
+			keys = append(keys, dto.JSONWebKey{
+				Kty: "RSA",
+				Kid: key.ID,
+				Use: "sig",
+				Alg: key.Alg,
+				N:   encodeBase64URL(privateKey.N.Bytes()),
+				E:   encodeBase64URL(big.NewInt(int64(privateKey.E)).Bytes()),
+			})
+		case *ecdsa.PrivateKey:
+			keys = append(keys, dto.JSONWebKey{
+				Kty: "EC",
+				Kid: key.ID,
+				Use: "sig",
+				Alg: key.Alg,
+				Crv: "P-256",
+				X:   encodeBase64URL(privateKey.X.FillBytes(make([]byte, 32))),
+				Y:   encodeBase64URL(privateKey.Y.FillBytes(make([]byte, 32))),
+			})
+		}
+	}
+
+	c.Response().Header().Set("Cache-Control", "public, max-age=300")
+	return c.JSON(http.StatusOK, dto.JWKSResponse{Keys: keys})
+}
+
+func encodeBase64URL(data []byte) string {
+	return base64.RawURLEncoding.EncodeToString(data)
+}
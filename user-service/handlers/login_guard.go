@@ -0,0 +1,143 @@
+package handler
+
+import (
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/lib/pq"
+)
+
+const (
+	// loginWindow is how far back failed logins are counted. A successful
+	// login or an admin unlock starts the count over.
+	loginWindow = 15 * time.Minute
+
+	// freeLoginAttempts failures are allowed before each further attempt
+	// has to wait, starting at one second and doubling up to maxLoginDelay.
+	freeLoginAttempts = 3
+	maxLoginDelay     = time.Minute
+
+	// lockoutThreshold failures lock the account for lockoutDuration.
+	lockoutThreshold = 10
+	lockoutDuration  = 15 * time.Minute
+
+	// ipFailureLimit failures from one IP address within loginWindow block
+	// that address, whichever emails it tried.
+	ipFailureLimit = 50
+)
+
+// Outcomes recorded in login_attempts.
+const (
+	loginSuccess            = "success"
+	loginInvalidCredentials = "invalid_credentials"
+	loginThrottled          = "throttled"
+	loginLocked             = "locked"
+	loginDisabled           = "disabled"
+	loginUnlocked           = "unlocked"
+	loginChallenged         = "challenged"
+	loginInvalidCode        = "invalid_code"
+)
+
+// failedLoginOutcomes are the outcomes that count towards throttling and
+// lockout. A wrong second factor counts like a wrong password so codes
+// cannot be guessed by starting new challenges.
+var failedLoginOutcomes = pq.Array([]string{loginInvalidCredentials, loginInvalidCode})
+
+type loginAttempt struct {
+	userID    *int
+	email     string
+	ip        string
+	userAgent string
+	outcome   string
+}
+
+// recordLoginAttempt adds an attempt to the audit table. A failure is only
+// logged so that it cannot turn into a way around the login checks.
+func recordLoginAttempt(db dbExecutor, attempt loginAttempt) {
+	query := `
+		INSERT INTO login_attempts (user_id, email, ip_address, user_agent, outcome, created_at)
+		VALUES ($1, $2, $3, $4, $5, NOW())
+	`
+	_, err := db.Exec(query, attempt.userID, attempt.email, attempt.ip, attempt.userAgent, attempt.outcome)
+	if err != nil {
+		log.Printf("Failed to record login attempt for %s: %v\n", attempt.email, err)
+	}
+}
+
+func intervalSeconds(d time.Duration) string {
+	return fmt.Sprintf("%d seconds", int(d.Seconds()))
+}
+
+// recentLoginFailures returns the failed logins for email since the window
+// began or the count was last reset, and the seconds since the latest one.
+func recentLoginFailures(db dbExecutor, email string) (int, float64, error) {
+	var failures int
+	var sinceLast float64
+	query := `
+		SELECT COUNT(*), COALESCE(EXTRACT(EPOCH FROM NOW() - MAX(created_at)), 0)
+		FROM login_attempts
+		WHERE email = $1 AND outcome = ANY($2)
+		AND created_at > GREATEST(NOW() - $3::interval, COALESCE(
+			(SELECT MAX(created_at) FROM login_attempts WHERE email = $1 AND outcome IN ($4, $5)),
+			'epoch'))
+	`
+	err := db.QueryRow(query, email, failedLoginOutcomes, intervalSeconds(loginWindow), loginSuccess, loginUnlocked).Scan(&failures, &sinceLast)
+	return failures, sinceLast, err
+}
+
+// loginRetryAfter returns how long a login for email from ip has to wait.
+// It is zero when the attempt may go ahead.
+func loginRetryAfter(db dbExecutor, email, ip string) (time.Duration, error) {
+	failures, sinceLast, err := recentLoginFailures(db, email)
+	if err != nil {
+		return 0, err
+	}
+
+	var wait time.Duration
+	if failures >= freeLoginAttempts {
+		delay := maxLoginDelay
+		if shift := failures - freeLoginAttempts; shift < 6 {
+			delay = time.Second << uint(shift)
+			if delay > maxLoginDelay {
+				delay = maxLoginDelay
+			}
+		}
+		wait = delay - time.Duration(sinceLast*float64(time.Second))
+	}
+
+	var ipFailures int
+	var sinceOldest float64
+	query := `
+		SELECT COUNT(*), COALESCE(EXTRACT(EPOCH FROM NOW() - MIN(created_at)), 0)
+		FROM login_attempts
+		WHERE ip_address = $1 AND outcome = ANY($2) AND created_at > NOW() - $3::interval
+	`
+	if err := db.QueryRow(query, ip, failedLoginOutcomes, intervalSeconds(loginWindow)).Scan(&ipFailures, &sinceOldest); err != nil {
+		return 0, err
+	}
+	if ipFailures >= ipFailureLimit {
+		// The address is blocked until its oldest counted failure leaves the window.
+		if ipWait := loginWindow - time.Duration(sinceOldest*float64(time.Second)); ipWait > wait {
+			wait = ipWait
+		}
+	}
+
+	if wait < 0 {
+		return 0, nil
+	}
+	return wait, nil
+}
+
+// lockIfTooManyFailures locks the account once the failures for email reach
+// lockoutThreshold.
+func lockIfTooManyFailures(db dbExecutor, userID int, email string) error {
+	failures, _, err := recentLoginFailures(db, email)
+	if err != nil || failures < lockoutThreshold {
+		return err
+	}
+
+	log.Printf("Locking user %d after %d failed login attempts\n", userID, failures)
+	_, err = db.Exec(`UPDATE users SET locked_until = NOW() + $2::interval WHERE id = $1`, userID, intervalSeconds(lockoutDuration))
+	return err
+}
@@ -0,0 +1,374 @@
+package handler
+
+import (
+	"database/sql"
+	"fmt"
+	"log"
+	"net/http"
+	"strings"
+	"time"
+	"user-service/config"
+	"user-service/dto"
+	"user-service/mailer"
+	"user-service/models"
+
+	"github.com/labstack/echo/v4"
+	"golang.org/x/crypto/bcrypt"
+)
+
+const (
+	tokenChangeEmail = "change_email"
+
+	changeEmailTokenTTL = 48 * time.Hour
+)
+
+// lockProfile loads the account a self-service request applies to and
+// locks it until the transaction ends.
+func lockProfile(db dbExecutor, userID string) (models.User, error) {
+	var user models.User
+	query := `SELECT id, username, email, password, role, pending_email, deleted_at FROM users WHERE id = $1 FOR UPDATE`
+	err := db.QueryRow(query, userID).Scan(
+		&user.UserID, &user.Username, &user.Email, &user.Password, &user.Role, &user.PendingEmail, &user.DeletedAt)
+	if err == nil && user.DeletedAt != nil {
+		err = sql.ErrNoRows
+	}
+	return user, err
+}
+
+func isEmailTaken(db dbExecutor, email string, userID int) (bool, error) {
+	var taken bool
+	err := db.QueryRow(`SELECT EXISTS (SELECT 1 FROM users WHERE email = $1 AND id <> $2)`, email, userID).Scan(&taken)
+	return taken, err
+}
+
+func sendAccountNotice(email, username, subject, text string) {
+	err := config.Mailer.Send(mailer.Message{
+		To:      email,
+		Subject: subject,
+		Body:    fmt.Sprintf("Hi %s,\n\n%s\n\nIf this was not you, reset your password right away.\n", username, text),
+	})
+	if err != nil {
+		log.Printf("Failed to send %q notice to %s: %v\n", subject, email, err)
+	}
+}
+
+// UpdateProfile changes the username and email of an account. A new email
+// only replaces the current one once it is confirmed through the link sent
+// to it.
+func UpdateProfile(c echo.Context) error {
+	var req dto.UpdateProfileRequest
+
+	if err := c.Bind(&req); err != nil {
+		return c.JSON(http.StatusBadRequest, dto.ErrorResponse{Message: "Invalid request"})
+	}
+
+	if req.Username == nil && req.Email == nil {
+		return c.JSON(http.StatusBadRequest, dto.ErrorResponse{Message: "username or email is required"})
+	}
+
+	tx, err := config.DB.Begin()
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, dto.ErrorResponse{Message: "Failed to start transaction"})
+	}
+	defer tx.Rollback()
+
+	user, err := lockProfile(tx, c.Param("id"))
+	if err == sql.ErrNoRows {
+		return c.JSON(http.StatusNotFound, dto.ErrorResponse{Message: "User not found"})
+	} else if err != nil {
+		return c.JSON(http.StatusInternalServerError, dto.ErrorResponse{Message: "Failed to retrieve user"})
+	}
+
+	if req.Username != nil {
+		username := strings.TrimSpace(*req.Username)
+		if username == "" || len(username) > 50 {
+			return c.JSON(http.StatusBadRequest, dto.ErrorResponse{Message: "Username must be between 1 and 50 characters"})
+		}
+
+		var taken bool
+		query := `SELECT EXISTS (SELECT 1 FROM users WHERE username = $1 AND id <> $2)`
+		if err := tx.QueryRow(query, username, user.UserID).Scan(&taken); err != nil {
+			return c.JSON(http.StatusInternalServerError, dto.ErrorResponse{Message: "Failed to check user existence"})
+		}
+		if taken {
+			return c.JSON(http.StatusConflict, dto.ErrorResponse{Message: "Username already exists"})
+		}
+
+		if _, err := tx.Exec(`UPDATE users SET username = $1, updated_at = NOW() WHERE id = $2`, username, user.UserID); err != nil {
+			return c.JSON(http.StatusInternalServerError, dto.ErrorResponse{Message: "Failed to update profile"})
+		}
+		user.Username = username
+	}
+
+	var verifyToken string
+	if req.Email != nil {
+		email := strings.TrimSpace(*req.Email)
+		if !isValidEmail(email) {
+			return c.JSON(http.StatusBadRequest, dto.ErrorResponse{Message: "Invalid email format"})
+		}
+
+		if email == user.Email {
+			// Asking for the current address again cancels a pending change.
+			user.PendingEmail = nil
+		} else {
+			taken, err := isEmailTaken(tx, email, user.UserID)
+			if err != nil {
+				return c.JSON(http.StatusInternalServerError, dto.ErrorResponse{Message: "Failed to check user existence"})
+			}
+			if taken {
+				return c.JSON(http.StatusConflict, dto.ErrorResponse{Message: "Email already exists"})
+			}
+
+			if verifyToken, err = issueUserToken(tx, user.UserID, tokenChangeEmail, changeEmailTokenTTL); err != nil {
+				return c.JSON(http.StatusInternalServerError, dto.ErrorResponse{Message: "Failed to create verification token"})
+			}
+			user.PendingEmail = &email
+		}
+
+		if _, err := tx.Exec(`UPDATE users SET pending_email = $1, updated_at = NOW() WHERE id = $2`, user.PendingEmail, user.UserID); err != nil {
+			return c.JSON(http.StatusInternalServerError, dto.ErrorResponse{Message: "Failed to update profile"})
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return c.JSON(http.StatusInternalServerError, dto.ErrorResponse{Message: "Failed to update profile"})
+	}
+
+	message := "Profile updated successfully"
+	if verifyToken != "" {
+		err := config.Mailer.Send(mailer.Message{
+			To:      *user.PendingEmail,
+			Subject: "Confirm your new email address",
+			Body: fmt.Sprintf("Hi %s,\n\nPlease confirm that this is your new email address by opening the link below:\n\n%s\n\nThe link expires in %d hours.\n",
+				user.Username, tokenLink(ConfirmEmailChangePath, verifyToken), int(changeEmailTokenTTL.Hours())),
+		})
+		if err != nil {
+			log.Printf("Failed to send email change confirmation to user %d: %v\n", user.UserID, err)
+			return c.JSON(http.StatusInternalServerError, dto.ErrorResponse{Message: "Failed to send verification email"})
+		}
+		message = "Profile updated; confirm the new email address through the link sent to it"
+	}
+
+	return c.JSON(http.StatusOK, dto.ProfileResponse{
+		UserID:       user.UserID,
+		Username:     user.Username,
+		Email:        user.Email,
+		PendingEmail: user.PendingEmail,
+		Message:      message,
+	})
+}
+
+// ConfirmEmailChange replaces the email of an account with the pending one
+// the confirmation token was sent to. The old address is told about it.
+// Like VerifyEmail it also takes the token from the query string.
+func ConfirmEmailChange(c echo.Context) error {
+	var req dto.VerifyEmailRequest
+
+	if err := c.Bind(&req); err != nil {
+		return c.JSON(http.StatusBadRequest, dto.ErrorResponse{Message: "Invalid request"})
+	}
+
+	if req.Token == "" {
+		return c.JSON(http.StatusBadRequest, dto.ErrorResponse{Message: "token is required"})
+	}
+
+	tx, err := config.DB.Begin()
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, dto.ErrorResponse{Message: "Failed to start transaction"})
+	}
+	defer tx.Rollback()
+
+	userID, err := consumeUserToken(tx, req.Token, tokenChangeEmail)
+	if err == sql.ErrNoRows {
+		return c.JSON(http.StatusBadRequest, dto.ErrorResponse{Message: "Invalid or expired token"})
+	} else if err != nil {
+		return c.JSON(http.StatusInternalServerError, dto.ErrorResponse{Message: "Failed to change email"})
+	}
+
+	user, err := lockProfile(tx, fmt.Sprint(userID))
+	if err == sql.ErrNoRows || (err == nil && user.PendingEmail == nil) {
+		return c.JSON(http.StatusBadRequest, dto.ErrorResponse{Message: "Invalid or expired token"})
+	} else if err != nil {
+		return c.JSON(http.StatusInternalServerError, dto.ErrorResponse{Message: "Failed to retrieve user"})
+	}
+
+	taken, err := isEmailTaken(tx, *user.PendingEmail, user.UserID)
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, dto.ErrorResponse{Message: "Failed to check user existence"})
+	}
+	if taken {
+		return c.JSON(http.StatusConflict, dto.ErrorResponse{Message: "Email already exists"})
+	}
+
+	query := `
+		UPDATE users
+		SET email = pending_email, pending_email = NULL, email_verified_at = NOW(), updated_at = NOW()
+		WHERE id = $1
+	`
+	if _, err := tx.Exec(query, user.UserID); err != nil {
+		return c.JSON(http.StatusInternalServerError, dto.ErrorResponse{Message: "Failed to change email"})
+	}
+
+	if err := tx.Commit(); err != nil {
+		return c.JSON(http.StatusInternalServerError, dto.ErrorResponse{Message: "Failed to change email"})
+	}
+
+	sendAccountNotice(user.Email, user.Username, "Your email address was changed",
+		fmt.Sprintf("The email address of your account was changed to %s.", *user.PendingEmail))
+
+	return c.JSON(http.StatusOK, dto.SuccessResponse{Message: "Email changed successfully"})
+}
+
+// ChangePassword sets a new password after checking the current one. All
+// other sessions are revoked; the one making the change stays signed in.
+func ChangePassword(c echo.Context) error {
+	var req dto.ChangePasswordRequest
+
+	if err := c.Bind(&req); err != nil {
+		return c.JSON(http.StatusBadRequest, dto.ErrorResponse{Message: "Invalid request"})
+	}
+
+	if req.CurrentPassword == "" {
+		return c.JSON(http.StatusBadRequest, dto.ErrorResponse{Message: "current_password is required"})
+	}
+
+	if len(req.NewPassword) < 6 {
+		return c.JSON(http.StatusBadRequest, dto.ErrorResponse{Message: "Password must be at least 6 characters"})
+	}
+
+	tx, err := config.DB.Begin()
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, dto.ErrorResponse{Message: "Failed to start transaction"})
+	}
+	defer tx.Rollback()
+
+	user, err := lockProfile(tx, c.Param("id"))
+	if err == sql.ErrNoRows {
+		return c.JSON(http.StatusNotFound, dto.ErrorResponse{Message: "User not found"})
+	} else if err != nil {
+		return c.JSON(http.StatusInternalServerError, dto.ErrorResponse{Message: "Failed to retrieve user"})
+	}
+
+	if err := bcrypt.CompareHashAndPassword([]byte(user.Password), []byte(req.CurrentPassword)); err != nil {
+		return c.JSON(http.StatusForbidden, dto.ErrorResponse{Message: "Current password is incorrect"})
+	}
+
+	hashedPassword, err := bcrypt.GenerateFromPassword([]byte(req.NewPassword), bcrypt.DefaultCost)
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, dto.ErrorResponse{Message: "Failed to hash password"})
+	}
+
+	if _, err := tx.Exec(`UPDATE users SET password = $1, updated_at = NOW() WHERE id = $2`, string(hashedPassword), user.UserID); err != nil {
+		return c.JSON(http.StatusInternalServerError, dto.ErrorResponse{Message: "Failed to change password"})
+	}
+
+	if err := revokeOtherSessions(tx, user.UserID, req.SessionID); err != nil {
+		return c.JSON(http.StatusInternalServerError, dto.ErrorResponse{Message: "Failed to revoke sessions"})
+	}
+
+	if err := tx.Commit(); err != nil {
+		return c.JSON(http.StatusInternalServerError, dto.ErrorResponse{Message: "Failed to change password"})
+	}
+
+	sendAccountNotice(user.Email, user.Username, "Your password was changed", "The password of your account was changed.")
+
+	return c.JSON(http.StatusOK, dto.SuccessResponse{Message: "Password changed successfully"})
+}
+
+// DeleteAccount closes an account after checking its password. The row is
+// kept and anonymized rather than deleted, so bookings and payments in the
+// other services still point to a valid, pseudonymous user ID.
+func DeleteAccount(c echo.Context) error {
+	var req dto.DeleteAccountRequest
+
+	if err := c.Bind(&req); err != nil {
+		return c.JSON(http.StatusBadRequest, dto.ErrorResponse{Message: "Invalid request"})
+	}
+
+	if req.Password == "" {
+		return c.JSON(http.StatusBadRequest, dto.ErrorResponse{Message: "password is required"})
+	}
+
+	tx, err := config.DB.Begin()
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, dto.ErrorResponse{Message: "Failed to start transaction"})
+	}
+	defer tx.Rollback()
+
+	user, err := lockProfile(tx, c.Param("id"))
+	if err == sql.ErrNoRows {
+		return c.JSON(http.StatusNotFound, dto.ErrorResponse{Message: "User not found"})
+	} else if err != nil {
+		return c.JSON(http.StatusInternalServerError, dto.ErrorResponse{Message: "Failed to retrieve user"})
+	}
+
+	if err := bcrypt.CompareHashAndPassword([]byte(user.Password), []byte(req.Password)); err != nil {
+		return c.JSON(http.StatusForbidden, dto.ErrorResponse{Message: "Password is incorrect"})
+	}
+
+	if user.Role == models.RoleAdmin {
+		last, err := isLastActiveAdmin(tx, user.UserID)
+		if err != nil {
+			return c.JSON(http.StatusInternalServerError, dto.ErrorResponse{Message: "Failed to delete account"})
+		}
+		if last {
+			return c.JSON(http.StatusConflict, dto.ErrorResponse{Message: "Cannot delete the last active administrator"})
+		}
+	}
+
+	if err := anonymizeUser(tx, user.UserID); err != nil {
+		log.Printf("Failed to anonymize user %d: %v\n", user.UserID, err)
+		return c.JSON(http.StatusInternalServerError, dto.ErrorResponse{Message: "Failed to delete account"})
+	}
+
+	if err := tx.Commit(); err != nil {
+		return c.JSON(http.StatusInternalServerError, dto.ErrorResponse{Message: "Failed to delete account"})
+	}
+
+	sendAccountNotice(user.Email, user.Username, "Your account was deleted", "Your account was closed and your personal data removed.")
+
+	return c.JSON(http.StatusOK, dto.SuccessResponse{Message: "Account deleted successfully"})
+}
+
+// anonymizeUser replaces the personal data of an account with placeholders
+// derived from its ID, ends its sessions and drops everything that could
+// be used to sign in again.
+func anonymizeUser(db dbExecutor, userID int) error {
+	query := `
+		UPDATE users SET
+			username = 'deleted-user-' || id,
+			email = 'deleted-user-' || id || '@deleted.invalid',
+			pending_email = NULL,
+			password = '',
+			role = $2,
+			email_verified_at = NULL,
+			totp_secret = NULL,
+			totp_enabled_at = NULL,
+			totp_last_counter = 0,
+			locked_until = NULL,
+			disabled_at = COALESCE(disabled_at, NOW()),
+			deleted_at = NOW(),
+			updated_at = NOW()
+		WHERE id = $1
+	`
+	if _, err := db.Exec(query, userID, models.RoleUser); err != nil {
+		return err
+	}
+
+	for _, table := range []string{"user_hotels", "recovery_codes", "user_tokens", "login_challenges"} {
+		if _, err := db.Exec(fmt.Sprintf(`DELETE FROM %s WHERE user_id = $1`, table), userID); err != nil {
+			return err
+		}
+	}
+
+	query = `
+		UPDATE login_attempts
+		SET email = 'deleted-user-' || user_id || '@deleted.invalid', ip_address = '', user_agent = ''
+		WHERE user_id = $1
+	`
+	if _, err := db.Exec(query, userID); err != nil {
+		return err
+	}
+
+	return revokeUserSessions(db, userID)
+}
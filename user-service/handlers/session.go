@@ -0,0 +1,235 @@
+package handler
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"database/sql"
+	"encoding/base64"
+	"encoding/hex"
+	"log"
+	"net/http"
+	"time"
+	"user-service/config"
+	"user-service/dto"
+	"user-service/models"
+
+	"github.com/labstack/echo/v4"
+)
+
+const (
+	accessTokenTTL  = 15 * time.Minute
+	refreshTokenTTL = 30 * 24 * time.Hour
+)
+
+func randomToken(size int) (string, error) {
+	data := make([]byte, size)
+	if _, err := rand.Read(data); err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(data), nil
+}
+
+func newSessionID() (string, error) {
+	data := make([]byte, 16)
+	if _, err := rand.Read(data); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(data), nil
+}
+
+// hashToken is what is stored for refresh, verification and reset tokens,
+// so a leaked table cannot be used to take over accounts.
+func hashToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
+
+// issueTokens creates an access token and a new refresh token for a login
+// session. Every refresh token rotated from the same login shares the
+// session ID as its family.
+func issueTokens(db dbExecutor, user models.User, sessionID string) (dto.LoginUserResponse, error) {
+	refreshToken, err := randomToken(32)
+	if err != nil {
+		return dto.LoginUserResponse{}, err
+	}
+
+	query := `
+		INSERT INTO refresh_tokens (user_id, family_id, token_hash, expires_at, created_at)
+		VALUES ($1, $2, $3, $4, NOW())
+	`
+	_, err = db.Exec(query, user.UserID, sessionID, hashToken(refreshToken), time.Now().Add(refreshTokenTTL))
+	if err != nil {
+		return dto.LoginUserResponse{}, err
+	}
+
+	token, err := createJWT(user, sessionID)
+	if err != nil {
+		return dto.LoginUserResponse{}, err
+	}
+
+	return dto.LoginUserResponse{
+		Token:        token,
+		RefreshToken: refreshToken,
+		ExpiresIn:    int(accessTokenTTL.Seconds()),
+	}, nil
+}
+
+// revokeSession revokes every refresh token of a session and puts the
+// session on the revocation list the gateway checks access tokens against.
+func revokeSession(db dbExecutor, sessionID string, userID int) error {
+	query := `UPDATE refresh_tokens SET revoked_at = NOW() WHERE family_id = $1 AND user_id = $2 AND revoked_at IS NULL`
+	if _, err := db.Exec(query, sessionID, userID); err != nil {
+		return err
+	}
+
+	query = `
+		INSERT INTO revoked_sessions (session_id, user_id, revoked_at, expires_at)
+		VALUES ($1, $2, NOW(), $3)
+		ON CONFLICT (session_id) DO NOTHING
+	`
+	_, err := db.Exec(query, sessionID, userID, time.Now().Add(accessTokenTTL))
+	return err
+}
+
+// RefreshToken exchanges a refresh token for a new access token and a new
+// refresh token. Each refresh token works once; presenting one that was
+// already used means it leaked, so the whole session is revoked.
+func RefreshToken(c echo.Context) error {
+	var req dto.RefreshTokenRequest
+
+	if err := c.Bind(&req); err != nil {
+		return c.JSON(http.StatusBadRequest, dto.ErrorResponse{Message: "Invalid request"})
+	}
+
+	if req.RefreshToken == "" {
+		return c.JSON(http.StatusBadRequest, dto.ErrorResponse{Message: "refresh_token is required"})
+	}
+
+	tx, err := config.DB.Begin()
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, dto.ErrorResponse{Message: "Failed to start transaction"})
+	}
+	defer tx.Rollback()
+
+	var tokenID int
+	var user models.User
+	var sessionID string
+	var expiresAt time.Time
+	var usedAt, revokedAt *time.Time
+	query := `
+		SELECT rt.id, rt.family_id, rt.expires_at, rt.used_at, rt.revoked_at, u.id, u.email, u.role, u.disabled_at, u.totp_enabled_at IS NOT NULL
+		FROM refresh_tokens rt
+		JOIN users u ON u.id = rt.user_id
+		WHERE rt.token_hash = $1
+		FOR UPDATE OF rt
+	`
+	err = tx.QueryRow(query, hashToken(req.RefreshToken)).Scan(
+		&tokenID, &sessionID, &expiresAt, &usedAt, &revokedAt, &user.UserID, &user.Email, &user.Role, &user.DisabledAt, &user.TwoFactorEnabled)
+	if err == sql.ErrNoRows {
+		return c.JSON(http.StatusUnauthorized, dto.ErrorResponse{Message: "Invalid refresh token"})
+	} else if err != nil {
+		return c.JSON(http.StatusInternalServerError, dto.ErrorResponse{Message: "Failed to query refresh token"})
+	}
+
+	if revokedAt != nil || time.Now().After(expiresAt) {
+		return c.JSON(http.StatusUnauthorized, dto.ErrorResponse{Message: "Refresh token has expired or was revoked"})
+	}
+
+	if user.DisabledAt != nil {
+		return c.JSON(http.StatusForbidden, dto.ErrorResponse{Message: "Account is disabled"})
+	}
+
+	// Sessions staff opened before setting up a second factor cannot be
+	// extended.
+	if requiresTwoFactor(user.Role) && !user.TwoFactorEnabled {
+		return c.JSON(http.StatusUnauthorized, dto.ErrorResponse{Message: "Two-factor authentication is required, please log in again"})
+	}
+
+	if usedAt != nil {
+		log.Printf("Refresh token reuse detected for user %d, revoking session %s\n", user.UserID, sessionID)
+		if err := revokeSession(tx, sessionID, user.UserID); err != nil {
+			return c.JSON(http.StatusInternalServerError, dto.ErrorResponse{Message: "Failed to revoke session"})
+		}
+		if err := tx.Commit(); err != nil {
+			return c.JSON(http.StatusInternalServerError, dto.ErrorResponse{Message: "Failed to revoke session"})
+		}
+		return c.JSON(http.StatusUnauthorized, dto.ErrorResponse{Message: "Refresh token was already used; please log in again"})
+	}
+
+	if _, err := tx.Exec(`UPDATE refresh_tokens SET used_at = NOW() WHERE id = $1`, tokenID); err != nil {
+		return c.JSON(http.StatusInternalServerError, dto.ErrorResponse{Message: "Failed to rotate refresh token"})
+	}
+
+	// Role and hotel assignments are read again so changes apply on the
+	// next refresh instead of at the next login.
+	if models.IsHotelStaff(user.Role) {
+		if user.HotelIDs, err = loadHotelIDs(tx, user.UserID); err != nil {
+			return c.JSON(http.StatusInternalServerError, dto.ErrorResponse{Message: "Failed to query user hotels"})
+		}
+	}
+
+	response, err := issueTokens(tx, user, sessionID)
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, dto.ErrorResponse{Message: "Failed to create token"})
+	}
+
+	if err := tx.Commit(); err != nil {
+		return c.JSON(http.StatusInternalServerError, dto.ErrorResponse{Message: "Failed to rotate refresh token"})
+	}
+
+	return c.JSON(http.StatusOK, response)
+}
+
+// Logout ends the session the gateway authenticated the request with.
+func Logout(c echo.Context) error {
+	var req dto.LogoutRequest
+
+	if err := c.Bind(&req); err != nil {
+		return c.JSON(http.StatusBadRequest, dto.ErrorResponse{Message: "Invalid request"})
+	}
+
+	if req.UserID == 0 || req.SessionID == "" {
+		return c.JSON(http.StatusBadRequest, dto.ErrorResponse{Message: "user_id and session_id are required"})
+	}
+
+	tx, err := config.DB.Begin()
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, dto.ErrorResponse{Message: "Failed to start transaction"})
+	}
+	defer tx.Rollback()
+
+	if err := revokeSession(tx, req.SessionID, req.UserID); err != nil {
+		return c.JSON(http.StatusInternalServerError, dto.ErrorResponse{Message: "Failed to revoke session"})
+	}
+
+	if err := tx.Commit(); err != nil {
+		return c.JSON(http.StatusInternalServerError, dto.ErrorResponse{Message: "Failed to revoke session"})
+	}
+
+	return c.JSON(http.StatusOK, dto.SuccessResponse{Message: "Logged out successfully"})
+}
+
+// ListRevokedSessions returns the sessions whose access tokens may still be
+// unexpired. The gateway polls it to reject those tokens.
+func ListRevokedSessions(c echo.Context) error {
+	rows, err := config.DB.Query(`SELECT session_id, expires_at FROM revoked_sessions WHERE expires_at > NOW() ORDER BY revoked_at`)
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, dto.ErrorResponse{Message: "Failed to retrieve revoked sessions"})
+	}
+	defer rows.Close()
+
+	sessions := []dto.RevokedSession{}
+	for rows.Next() {
+		var session dto.RevokedSession
+		if err := rows.Scan(&session.SessionID, &session.ExpiresAt); err != nil {
+			return c.JSON(http.StatusInternalServerError, dto.ErrorResponse{Message: "Failed to scan revoked session"})
+		}
+		sessions = append(sessions, session)
+	}
+
+	if err := rows.Err(); err != nil {
+		return c.JSON(http.StatusInternalServerError, dto.ErrorResponse{Message: "Error occurred during revoked sessions retrieval"})
+	}
+
+	return c.JSON(http.StatusOK, sessions)
+}
@@ -0,0 +1,117 @@
+package handler
+
+import (
+	"database/sql"
+	"errors"
+	"net/http"
+	"user-service/config"
+	"user-service/dto"
+	"user-service/models"
+
+	"github.com/labstack/echo/v4"
+	"github.com/lib/pq"
+)
+
+// dbExecutor is satisfied by both *sql.DB and *sql.Tx.
+type dbExecutor interface {
+	Exec(query string, args ...interface{}) (sql.Result, error)
+	QueryRow(query string, args ...interface{}) *sql.Row
+}
+
+func isValidRole(role string) bool {
+	return role == models.RoleUser || role == models.RoleAdmin || models.IsHotelStaff(role)
+}
+
+// loadHotelIDs returns the hotels a staff member is assigned to.
+func loadHotelIDs(db dbExecutor, userID int) ([]int, error) {
+	var hotelIDs pq.Int64Array
+	query := `SELECT COALESCE(array_agg(hotel_id ORDER BY hotel_id), '{}') FROM user_hotels WHERE user_id = $1`
+	if err := db.QueryRow(query, userID).Scan(&hotelIDs); err != nil {
+		return nil, err
+	}
+
+	ids := make([]int, len(hotelIDs))
+	for i, id := range hotelIDs {
+		ids[i] = int(id)
+	}
+	return ids, nil
+}
+
+// replaceUserHotels sets the hotels a staff member is assigned to.
+func replaceUserHotels(db dbExecutor, userID int, hotelIDs []int) error {
+	if _, err := db.Exec(`DELETE FROM user_hotels WHERE user_id = $1`, userID); err != nil {
+		return err
+	}
+
+	query := `
+		INSERT INTO user_hotels (user_id, hotel_id, created_at)
+		SELECT $1, hotel_id, NOW() FROM unnest($2::int[]) AS hotel_id
+		ON CONFLICT DO NOTHING
+	`
+	_, err := db.Exec(query, userID, pq.Array(hotelIDs))
+	return err
+}
+
+func validateHotelIDs(hotelIDs []int) error {
+	if len(hotelIDs) == 0 {
+		return errors.New("hotel_ids is required for hotel staff")
+	}
+	for _, id := range hotelIDs {
+		if id <= 0 {
+			return errors.New("hotel_ids must contain positive hotel IDs")
+		}
+	}
+	return nil
+}
+
+// UpdateUserHotels replaces the hotels a hotel_manager or front_desk user
+// is assigned to. The change applies from the user's next login.
+func UpdateUserHotels(c echo.Context) error {
+	userID := c.Param("id")
+
+	var req dto.UpdateUserHotelsRequest
+
+	if err := c.Bind(&req); err != nil {
+		return c.JSON(http.StatusBadRequest, dto.ErrorResponse{Message: "Invalid request"})
+	}
+
+	if err := validateHotelIDs(req.HotelIDs); err != nil {
+		return c.JSON(http.StatusBadRequest, dto.ErrorResponse{Message: err.Error()})
+	}
+
+	tx, err := config.DB.Begin()
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, dto.ErrorResponse{Message: "Failed to start transaction"})
+	}
+	defer tx.Rollback()
+
+	var user models.User
+	err = tx.QueryRow(`SELECT id, role FROM users WHERE id = $1 FOR UPDATE`, userID).Scan(&user.UserID, &user.Role)
+	if err == sql.ErrNoRows {
+		return c.JSON(http.StatusNotFound, dto.ErrorResponse{Message: "User not found"})
+	} else if err != nil {
+		return c.JSON(http.StatusInternalServerError, dto.ErrorResponse{Message: "Failed to retrieve user"})
+	}
+
+	if !models.IsHotelStaff(user.Role) {
+		return c.JSON(http.StatusBadRequest, dto.ErrorResponse{Message: "Only hotel_manager and front_desk users can be assigned to hotels"})
+	}
+
+	if err := replaceUserHotels(tx, user.UserID, req.HotelIDs); err != nil {
+		return c.JSON(http.StatusInternalServerError, dto.ErrorResponse{Message: "Failed to assign hotels"})
+	}
+
+	if user.HotelIDs, err = loadHotelIDs(tx, user.UserID); err != nil {
+		return c.JSON(http.StatusInternalServerError, dto.ErrorResponse{Message: "Failed to assign hotels"})
+	}
+
+	if err := tx.Commit(); err != nil {
+		return c.JSON(http.StatusInternalServerError, dto.ErrorResponse{Message: "Failed to assign hotels"})
+	}
+
+	return c.JSON(http.StatusOK, dto.UpdateUserHotelsResponse{
+		UserID:   user.UserID,
+		HotelIDs: user.HotelIDs,
+		Message:  "User hotels updated successfully",
+	})
+}
@@ -0,0 +1,565 @@
+package handler
+
+import (
+	"crypto/rand"
+	"database/sql"
+	"encoding/base32"
+	"net/http"
+	"strings"
+	"time"
+	"user-service/config"
+	"user-service/dto"
+	"user-service/models"
+	"user-service/totp"
+
+	"github.com/labstack/echo/v4"
+)
+
+const (
+	// totpIssuer is the account name authenticator apps show.
+	totpIssuer = "Hotel Booking"
+
+	// loginChallengeTTL is how long the second login step may take.
+	loginChallengeTTL = 5 * time.Minute
+
+	// maxChallengeAttempts wrong codes end a challenge; the password has
+	// to be entered again for a new one.
+	maxChallengeAttempts = 5
+
+	recoveryCodeCount = 10
+)
+
+// requiresTwoFactor reports whether accounts of a role must sign in with a
+// second factor. That is every staff account, since they can change
+// inventory or check guests in.
+func requiresTwoFactor(role string) bool {
+	return role == models.RoleAdmin || models.IsHotelStaff(role)
+}
+
+// twoFactorUser is the part of an account the second factor is checked
+// against.
+type twoFactorUser struct {
+	models.User
+	secret      *string
+	lastCounter int64
+	locked      bool
+}
+
+// lockTwoFactorUser loads a user's second factor and locks the row, so a
+// code is accepted at most once even for concurrent requests.
+func lockTwoFactorUser(db dbExecutor, userID interface{}) (twoFactorUser, error) {
+	var user twoFactorUser
+	query := `
+		SELECT id, email, role, disabled_at, totp_secret, totp_enabled_at IS NOT NULL, totp_last_counter,
+			COALESCE(locked_until > NOW(), false)
+		FROM users WHERE id = $1 FOR UPDATE
+	`
+	err := db.QueryRow(query, userID).Scan(
+		&user.UserID, &user.Email, &user.Role, &user.DisabledAt, &user.secret, &user.TwoFactorEnabled, &user.lastCounter, &user.locked)
+	return user, err
+}
+
+// checkTOTP validates a code against the user's secret and remembers the
+// time step it matched so it cannot be replayed.
+func checkTOTP(db dbExecutor, user twoFactorUser, code string) (bool, error) {
+	if user.secret == nil || code == "" {
+		return false, nil
+	}
+
+	counter, ok := totp.Validate(*user.secret, code, time.Now(), user.lastCounter)
+	if !ok {
+		return false, nil
+	}
+
+	_, err := db.Exec(`UPDATE users SET totp_last_counter = $1 WHERE id = $2`, counter, user.UserID)
+	return err == nil, err
+}
+
+func normalizeRecoveryCode(code string) string {
+	code = strings.ToLower(code)
+	code = strings.ReplaceAll(code, "-", "")
+	return strings.ReplaceAll(code, " ", "")
+}
+
+// useRecoveryCode spends one of the user's recovery codes.
+func useRecoveryCode(db dbExecutor, userID int, code string) (bool, error) {
+	if code == "" {
+		return false, nil
+	}
+
+	query := `UPDATE recovery_codes SET used_at = NOW() WHERE user_id = $1 AND code_hash = $2 AND used_at IS NULL`
+	result, err := db.Exec(query, userID, hashToken(normalizeRecoveryCode(code)))
+	if err != nil {
+		return false, err
+	}
+	rows, err := result.RowsAffected()
+	return rows == 1, err
+}
+
+// checkSecondFactor accepts either a current TOTP code or an unused
+// recovery code.
+func checkSecondFactor(db dbExecutor, user twoFactorUser, code, recoveryCode string) (bool, error) {
+	if recoveryCode != "" {
+		return useRecoveryCode(db, user.UserID, recoveryCode)
+	}
+	return checkTOTP(db, user, code)
+}
+
+// replaceRecoveryCodes discards a user's recovery codes and returns new
+// ones, formatted as xxxxx-xxxxx.
+func replaceRecoveryCodes(db dbExecutor, userID int) ([]string, error) {
+	if _, err := db.Exec(`DELETE FROM recovery_codes WHERE user_id = $1`, userID); err != nil {
+		return nil, err
+	}
+
+	encoding := base32.StdEncoding.WithPadding(base32.NoPadding)
+	codes := make([]string, recoveryCodeCount)
+	for i := range codes {
+		data := make([]byte, 10)
+		if _, err := rand.Read(data); err != nil {
+			return nil, err
+		}
+		code := strings.ToLower(encoding.EncodeToString(data))[:10]
+		codes[i] = code[:5] + "-" + code[5:]
+
+		query := `INSERT INTO recovery_codes (user_id, code_hash, created_at) VALUES ($1, $2, NOW())`
+		if _, err := db.Exec(query, userID, hashToken(normalizeRecoveryCode(codes[i]))); err != nil {
+			return nil, err
+		}
+	}
+	return codes, nil
+}
+
+// startTOTPSetup stores a new secret for the user, which only takes effect
+// once a code generated from it is confirmed.
+func startTOTPSetup(db dbExecutor, user twoFactorUser) (dto.TwoFactorSetupResponse, error) {
+	secret, err := totp.GenerateSecret()
+	if err != nil {
+		return dto.TwoFactorSetupResponse{}, err
+	}
+
+	query := `UPDATE users SET totp_secret = $1, totp_last_counter = 0, updated_at = NOW() WHERE id = $2`
+	if _, err := db.Exec(query, secret, user.UserID); err != nil {
+		return dto.TwoFactorSetupResponse{}, err
+	}
+
+	return dto.TwoFactorSetupResponse{
+		Secret:     secret,
+		OTPAuthURI: totp.URI(totpIssuer, user.Email, secret),
+	}, nil
+}
+
+// enableTOTP turns on the secret set up before and issues recovery codes.
+func enableTOTP(db dbExecutor, userID int) ([]string, error) {
+	query := `UPDATE users SET totp_enabled_at = NOW(), updated_at = NOW() WHERE id = $1`
+	if _, err := db.Exec(query, userID); err != nil {
+		return nil, err
+	}
+	return replaceRecoveryCodes(db, userID)
+}
+
+func createLoginChallenge(db dbExecutor, userID int) (string, error) {
+	token, err := randomToken(32)
+	if err != nil {
+		return "", err
+	}
+
+	query := `INSERT INTO login_challenges (token_hash, user_id, expires_at, created_at) VALUES ($1, $2, $3, NOW())`
+	if _, err := db.Exec(query, hashToken(token), userID, time.Now().Add(loginChallengeTTL)); err != nil {
+		return "", err
+	}
+	return token, nil
+}
+
+// lockLoginChallenge returns the user a pending challenge was issued to. It
+// returns sql.ErrNoRows when the challenge is unknown, expired, used or has
+// had too many wrong codes.
+func lockLoginChallenge(db dbExecutor, token string) (twoFactorUser, error) {
+	var userID int
+	query := `
+		SELECT user_id FROM login_challenges
+		WHERE token_hash = $1 AND used_at IS NULL AND expires_at > NOW() AND failed_attempts < $2
+		FOR UPDATE
+	`
+	if err := db.QueryRow(query, hashToken(token), maxChallengeAttempts).Scan(&userID); err != nil {
+		return twoFactorUser{}, err
+	}
+	return lockTwoFactorUser(db, userID)
+}
+
+// VerifyLoginChallenge is the second login step. It takes the challenge
+// token from LoginUser and a TOTP code or a recovery code, and starts the
+// session. Users enrolling during login confirm their first code here and
+// get their recovery codes with the tokens.
+func VerifyLoginChallenge(c echo.Context) error {
+	var req dto.LoginChallengeRequest
+
+	if err := c.Bind(&req); err != nil {
+		return c.JSON(http.StatusBadRequest, dto.ErrorResponse{Message: "Invalid request"})
+	}
+
+	if req.ChallengeToken == "" || (req.Code == "" && req.RecoveryCode == "") {
+		return c.JSON(http.StatusBadRequest, dto.ErrorResponse{Message: "challenge_token and code or recovery_code are required"})
+	}
+
+	tx, err := config.DB.Begin()
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, dto.ErrorResponse{Message: "Failed to start transaction"})
+	}
+	defer tx.Rollback()
+
+	user, err := lockLoginChallenge(tx, req.ChallengeToken)
+	if err == sql.ErrNoRows {
+		return c.JSON(http.StatusUnauthorized, dto.ErrorResponse{Message: "Invalid or expired challenge, please log in again"})
+	} else if err != nil {
+		return c.JSON(http.StatusInternalServerError, dto.ErrorResponse{Message: "Failed to verify challenge"})
+	}
+
+	attempt := loginAttempt{userID: &user.UserID, email: user.Email, ip: c.RealIP(), userAgent: c.Request().UserAgent()}
+
+	if user.DisabledAt != nil {
+		return c.JSON(http.StatusForbidden, dto.ErrorResponse{Message: "Account is disabled"})
+	}
+
+	if user.locked {
+		return c.JSON(http.StatusLocked, dto.ErrorResponse{Message: "Account is temporarily locked due to too many failed login attempts"})
+	}
+
+	if !user.TwoFactorEnabled && user.secret == nil {
+		return c.JSON(http.StatusBadRequest, dto.ErrorResponse{Message: "Set up two-factor authentication with /login/2fa/setup first"})
+	}
+
+	var ok bool
+	if user.TwoFactorEnabled {
+		ok, err = checkSecondFactor(tx, user, req.Code, req.RecoveryCode)
+	} else {
+		ok, err = checkTOTP(tx, user, req.Code)
+	}
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, dto.ErrorResponse{Message: "Failed to verify code"})
+	}
+
+	if !ok {
+		query := `UPDATE login_challenges SET failed_attempts = failed_attempts + 1 WHERE token_hash = $1`
+		if _, err := tx.Exec(query, hashToken(req.ChallengeToken)); err != nil {
+			return c.JSON(http.StatusInternalServerError, dto.ErrorResponse{Message: "Failed to verify code"})
+		}
+		attempt.outcome = loginInvalidCode
+		recordLoginAttempt(tx, attempt)
+		if err := lockIfTooManyFailures(tx, user.UserID, user.Email); err != nil {
+			return c.JSON(http.StatusInternalServerError, dto.ErrorResponse{Message: "Failed to verify code"})
+		}
+		if err := tx.Commit(); err != nil {
+			return c.JSON(http.StatusInternalServerError, dto.ErrorResponse{Message: "Failed to verify code"})
+		}
+		return c.JSON(http.StatusUnauthorized, dto.ErrorResponse{Message: "Invalid verification code"})
+	}
+
+	if _, err := tx.Exec(`UPDATE login_challenges SET used_at = NOW() WHERE token_hash = $1`, hashToken(req.ChallengeToken)); err != nil {
+		return c.JSON(http.StatusInternalServerError, dto.ErrorResponse{Message: "Failed to verify challenge"})
+	}
+
+	var recoveryCodes []string
+	if !user.TwoFactorEnabled {
+		if recoveryCodes, err = enableTOTP(tx, user.UserID); err != nil {
+			return c.JSON(http.StatusInternalServerError, dto.ErrorResponse{Message: "Failed to enable two-factor authentication"})
+		}
+		// Sessions opened before the second factor was required end here.
+		if err := revokeUserSessions(tx, user.UserID); err != nil {
+			return c.JSON(http.StatusInternalServerError, dto.ErrorResponse{Message: "Failed to revoke sessions"})
+		}
+	}
+
+	response, err := startSession(tx, user.User)
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, dto.ErrorResponse{Message: "Failed to create token"})
+	}
+	response.RecoveryCodes = recoveryCodes
+
+	attempt.outcome = loginSuccess
+	recordLoginAttempt(tx, attempt)
+
+	if err := tx.Commit(); err != nil {
+		return c.JSON(http.StatusInternalServerError, dto.ErrorResponse{Message: "Failed to create token"})
+	}
+
+	return c.JSON(http.StatusOK, response)
+}
+
+// SetupLoginChallenge generates a TOTP secret for a user who has to enroll
+// while logging in, which is how staff get their first one.
+func SetupLoginChallenge(c echo.Context) error {
+	var req dto.LoginChallengeRequest
+
+	if err := c.Bind(&req); err != nil {
+		return c.JSON(http.StatusBadRequest, dto.ErrorResponse{Message: "Invalid request"})
+	}
+
+	if req.ChallengeToken == "" {
+		return c.JSON(http.StatusBadRequest, dto.ErrorResponse{Message: "challenge_token is required"})
+	}
+
+	tx, err := config.DB.Begin()
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, dto.ErrorResponse{Message: "Failed to start transaction"})
+	}
+	defer tx.Rollback()
+
+	user, err := lockLoginChallenge(tx, req.ChallengeToken)
+	if err == sql.ErrNoRows {
+		return c.JSON(http.StatusUnauthorized, dto.ErrorResponse{Message: "Invalid or expired challenge, please log in again"})
+	} else if err != nil {
+		return c.JSON(http.StatusInternalServerError, dto.ErrorResponse{Message: "Failed to verify challenge"})
+	}
+
+	if user.TwoFactorEnabled {
+		return c.JSON(http.StatusConflict, dto.ErrorResponse{Message: "Two-factor authentication is already enabled"})
+	}
+
+	response, err := startTOTPSetup(tx, user)
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, dto.ErrorResponse{Message: "Failed to set up two-factor authentication"})
+	}
+
+	if err := tx.Commit(); err != nil {
+		return c.JSON(http.StatusInternalServerError, dto.ErrorResponse{Message: "Failed to set up two-factor authentication"})
+	}
+
+	return c.JSON(http.StatusOK, response)
+}
+
+// SetupTwoFactor generates a new TOTP secret for a logged in user. It is
+// not used until EnableTwoFactor confirms a code from it.
+func SetupTwoFactor(c echo.Context) error {
+	var req dto.TwoFactorRequest
+
+	if err := c.Bind(&req); err != nil {
+		return c.JSON(http.StatusBadRequest, dto.ErrorResponse{Message: "Invalid request"})
+	}
+
+	tx, err := config.DB.Begin()
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, dto.ErrorResponse{Message: "Failed to start transaction"})
+	}
+	defer tx.Rollback()
+
+	user, err := lockTwoFactorUser(tx, req.UserID)
+	if err == sql.ErrNoRows {
+		return c.JSON(http.StatusNotFound, dto.ErrorResponse{Message: "User not found"})
+	} else if err != nil {
+		return c.JSON(http.StatusInternalServerError, dto.ErrorResponse{Message: "Failed to retrieve user"})
+	}
+
+	if user.TwoFactorEnabled {
+		return c.JSON(http.StatusConflict, dto.ErrorResponse{Message: "Two-factor authentication is already enabled"})
+	}
+
+	response, err := startTOTPSetup(tx, user)
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, dto.ErrorResponse{Message: "Failed to set up two-factor authentication"})
+	}
+
+	if err := tx.Commit(); err != nil {
+		return c.JSON(http.StatusInternalServerError, dto.ErrorResponse{Message: "Failed to set up two-factor authentication"})
+	}
+
+	return c.JSON(http.StatusOK, response)
+}
+
+// EnableTwoFactor confirms a code from the secret of SetupTwoFactor and
+// returns the recovery codes. They are only shown this once.
+func EnableTwoFactor(c echo.Context) error {
+	var req dto.TwoFactorRequest
+
+	if err := c.Bind(&req); err != nil {
+		return c.JSON(http.StatusBadRequest, dto.ErrorResponse{Message: "Invalid request"})
+	}
+
+	if req.Code == "" {
+		return c.JSON(http.StatusBadRequest, dto.ErrorResponse{Message: "code is required"})
+	}
+
+	tx, err := config.DB.Begin()
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, dto.ErrorResponse{Message: "Failed to start transaction"})
+	}
+	defer tx.Rollback()
+
+	user, err := lockTwoFactorUser(tx, req.UserID)
+	if err == sql.ErrNoRows {
+		return c.JSON(http.StatusNotFound, dto.ErrorResponse{Message: "User not found"})
+	} else if err != nil {
+		return c.JSON(http.StatusInternalServerError, dto.ErrorResponse{Message: "Failed to retrieve user"})
+	}
+
+	if user.TwoFactorEnabled {
+		return c.JSON(http.StatusConflict, dto.ErrorResponse{Message: "Two-factor authentication is already enabled"})
+	}
+	if user.secret == nil {
+		return c.JSON(http.StatusBadRequest, dto.ErrorResponse{Message: "Set up two-factor authentication first"})
+	}
+
+	ok, err := checkTOTP(tx, user, req.Code)
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, dto.ErrorResponse{Message: "Failed to verify code"})
+	}
+	if !ok {
+		return c.JSON(http.StatusBadRequest, dto.ErrorResponse{Message: "Invalid verification code"})
+	}
+
+	codes, err := enableTOTP(tx, user.UserID)
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, dto.ErrorResponse{Message: "Failed to enable two-factor authentication"})
+	}
+
+	if err := tx.Commit(); err != nil {
+		return c.JSON(http.StatusInternalServerError, dto.ErrorResponse{Message: "Failed to enable two-factor authentication"})
+	}
+
+	return c.JSON(http.StatusOK, dto.RecoveryCodesResponse{
+		RecoveryCodes: codes,
+		Message:       "Two-factor authentication enabled successfully",
+	})
+}
+
+// DisableTwoFactor turns the second factor off after checking a current
+// code or a recovery code. Staff cannot turn it off.
+func DisableTwoFactor(c echo.Context) error {
+	var req dto.TwoFactorRequest
+
+	if err := c.Bind(&req); err != nil {
+		return c.JSON(http.StatusBadRequest, dto.ErrorResponse{Message: "Invalid request"})
+	}
+
+	tx, err := config.DB.Begin()
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, dto.ErrorResponse{Message: "Failed to start transaction"})
+	}
+	defer tx.Rollback()
+
+	user, err := lockTwoFactorUser(tx, req.UserID)
+	if err == sql.ErrNoRows {
+		return c.JSON(http.StatusNotFound, dto.ErrorResponse{Message: "User not found"})
+	} else if err != nil {
+		return c.JSON(http.StatusInternalServerError, dto.ErrorResponse{Message: "Failed to retrieve user"})
+	}
+
+	if requiresTwoFactor(user.Role) {
+		return c.JSON(http.StatusForbidden, dto.ErrorResponse{Message: "Two-factor authentication is required for staff accounts"})
+	}
+	if !user.TwoFactorEnabled {
+		return c.JSON(http.StatusBadRequest, dto.ErrorResponse{Message: "Two-factor authentication is not enabled"})
+	}
+
+	ok, err := checkSecondFactor(tx, user, req.Code, req.RecoveryCode)
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, dto.ErrorResponse{Message: "Failed to verify code"})
+	}
+	if !ok {
+		return c.JSON(http.StatusBadRequest, dto.ErrorResponse{Message: "Invalid verification code"})
+	}
+
+	if err := clearTwoFactor(tx, user.UserID); err != nil {
+		return c.JSON(http.StatusInternalServerError, dto.ErrorResponse{Message: "Failed to disable two-factor authentication"})
+	}
+
+	if err := tx.Commit(); err != nil {
+		return c.JSON(http.StatusInternalServerError, dto.ErrorResponse{Message: "Failed to disable two-factor authentication"})
+	}
+
+	return c.JSON(http.StatusOK, dto.SuccessResponse{Message: "Two-factor authentication disabled successfully"})
+}
+
+// RegenerateRecoveryCodes replaces the recovery codes after checking a
+// current TOTP code.
+func RegenerateRecoveryCodes(c echo.Context) error {
+	var req dto.TwoFactorRequest
+
+	if err := c.Bind(&req); err != nil {
+		return c.JSON(http.StatusBadRequest, dto.ErrorResponse{Message: "Invalid request"})
+	}
+
+	if req.Code == "" {
+		return c.JSON(http.StatusBadRequest, dto.ErrorResponse{Message: "code is required"})
+	}
+
+	tx, err := config.DB.Begin()
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, dto.ErrorResponse{Message: "Failed to start transaction"})
+	}
+	defer tx.Rollback()
+
+	user, err := lockTwoFactorUser(tx, req.UserID)
+	if err == sql.ErrNoRows {
+		return c.JSON(http.StatusNotFound, dto.ErrorResponse{Message: "User not found"})
+	} else if err != nil {
+		return c.JSON(http.StatusInternalServerError, dto.ErrorResponse{Message: "Failed to retrieve user"})
+	}
+
+	if !user.TwoFactorEnabled {
+		return c.JSON(http.StatusBadRequest, dto.ErrorResponse{Message: "Two-factor authentication is not enabled"})
+	}
+
+	ok, err := checkTOTP(tx, user, req.Code)
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, dto.ErrorResponse{Message: "Failed to verify code"})
+	}
+	if !ok {
+		return c.JSON(http.StatusBadRequest, dto.ErrorResponse{Message: "Invalid verification code"})
+	}
+
+	codes, err := replaceRecoveryCodes(tx, user.UserID)
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, dto.ErrorResponse{Message: "Failed to create recovery codes"})
+	}
+
+	if err := tx.Commit(); err != nil {
+		return c.JSON(http.StatusInternalServerError, dto.ErrorResponse{Message: "Failed to create recovery codes"})
+	}
+
+	return c.JSON(http.StatusOK, dto.RecoveryCodesResponse{
+		RecoveryCodes: codes,
+		Message:       "Recovery codes regenerated successfully",
+	})
+}
+
+// ResetTwoFactor lets an administrator remove the second factor of a user
+// who lost it. The user's sessions are revoked; staff set up a new one at
+// their next login.
+func ResetTwoFactor(c echo.Context) error {
+	tx, err := config.DB.Begin()
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, dto.ErrorResponse{Message: "Failed to start transaction"})
+	}
+	defer tx.Rollback()
+
+	user, err := lockTwoFactorUser(tx, c.Param("id"))
+	if err == sql.ErrNoRows {
+		return c.JSON(http.StatusNotFound, dto.ErrorResponse{Message: "User not found"})
+	} else if err != nil {
+		return c.JSON(http.StatusInternalServerError, dto.ErrorResponse{Message: "Failed to retrieve user"})
+	}
+
+	if err := clearTwoFactor(tx, user.UserID); err != nil {
+		return c.JSON(http.StatusInternalServerError, dto.ErrorResponse{Message: "Failed to reset two-factor authentication"})
+	}
+
+	if err := revokeUserSessions(tx, user.UserID); err != nil {
+		return c.JSON(http.StatusInternalServerError, dto.ErrorResponse{Message: "Failed to revoke sessions"})
+	}
+
+	if err := tx.Commit(); err != nil {
+		return c.JSON(http.StatusInternalServerError, dto.ErrorResponse{Message: "Failed to reset two-factor authentication"})
+	}
+
+	return c.JSON(http.StatusOK, dto.SuccessResponse{Message: "Two-factor authentication reset successfully"})
+}
+
+func clearTwoFactor(db dbExecutor, userID int) error {
+	query := `UPDATE users SET totp_secret = NULL, totp_enabled_at = NULL, totp_last_counter = 0, updated_at = NOW() WHERE id = $1`
+	if _, err := db.Exec(query, userID); err != nil {
+		return err
+	}
+	_, err := db.Exec(`DELETE FROM recovery_codes WHERE user_id = $1`, userID)
+	return err
+}
@@ -0,0 +1,56 @@
+package mailer
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestMemoryMailer(t *testing.T) {
+	m := &MemoryMailer{}
+	msg := Message{To: "guest@example.com", Subject: "Hello", Body: "Hi"}
+	if err := m.Send(msg); err != nil {
+		t.Fatal(err)
+	}
+
+	got := m.Messages()
+	if len(got) != 1 || got[0] != msg {
+		t.Fatalf("messages = %+v, want [%+v]", got, msg)
+	}
+}
+
+func TestFileMailer(t *testing.T) {
+	dir := filepath.Join(t.TempDir(), "mail")
+	m := &FileMailer{Dir: dir, From: "no-reply@example.com"}
+
+	for i := 0; i < 2; i++ {
+		msg := Message{To: "guest@example.com", Subject: "Reset your password", Body: "line one\nline two"}
+		if err := m.Send(msg); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	files, err := filepath.Glob(filepath.Join(dir, "*.eml"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(files) != 2 {
+		t.Fatalf("wrote %d files, want 2", len(files))
+	}
+
+	data, err := os.ReadFile(files[0])
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, want := range []string{
+		"From: no-reply@example.com\r\n",
+		"To: guest@example.com\r\n",
+		"Subject: Reset your password\r\n",
+		"\r\n\r\nline one\r\nline two",
+	} {
+		if !strings.Contains(string(data), want) {
+			t.Errorf("message does not contain %q:\n%s", want, data)
+		}
+	}
+}
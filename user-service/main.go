@@ -10,8 +10,13 @@ import (
 
 func main() {
     e := echo.New()
+    // Requests come through the gateway, which passes the client address
+    // in X-Real-IP.
+    e.IPExtractor = echo.ExtractIPFromRealIPHeader()
 
     config.InitDB()
+    config.InitJWTKeys()
+    config.InitMailer()
 
     router.InitRoutes(e)
 
@@ -0,0 +1,6 @@
+CREATE TABLE IF NOT EXISTS user_hotels (
+    user_id INT NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    hotel_id INT NOT NULL,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    PRIMARY KEY (user_id, hotel_id)
+);
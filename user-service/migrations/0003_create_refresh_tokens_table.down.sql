@@ -0,0 +1,2 @@
+DROP TABLE IF EXISTS revoked_sessions;
+DROP TABLE IF EXISTS refresh_tokens;
@@ -0,0 +1,14 @@
+ALTER TABLE users ADD COLUMN locked_until TIMESTAMP;
+
+CREATE TABLE IF NOT EXISTS login_attempts (
+    id SERIAL PRIMARY KEY,
+    user_id INT REFERENCES users(id) ON DELETE SET NULL,  -- NULL when the email is not registered
+    email VARCHAR(255) NOT NULL,
+    ip_address VARCHAR(45) NOT NULL,
+    user_agent TEXT NOT NULL DEFAULT '',
+    outcome VARCHAR(32) NOT NULL,  -- 'success', 'invalid_credentials', 'throttled', 'locked', 'disabled' or 'unlocked'
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS idx_login_attempts_email ON login_attempts (email, created_at);
+CREATE INDEX IF NOT EXISTS idx_login_attempts_ip_address ON login_attempts (ip_address, created_at);
@@ -0,0 +1,2 @@
+ALTER TABLE users ADD COLUMN pending_email VARCHAR(100);  -- new address waiting for verification
+ALTER TABLE users ADD COLUMN deleted_at TIMESTAMP;        -- set when the account was closed and anonymized
@@ -0,0 +1,14 @@
+package models
+
+import "time"
+
+// LoginAttempt is an entry of the login audit trail.
+type LoginAttempt struct {
+    ID        int       `json:"id"`
+    UserID    *int      `json:"user_id"`
+    Email     string    `json:"email"`
+    IPAddress string    `json:"ip_address"`
+    UserAgent string    `json:"user_agent"`
+    Outcome   string    `json:"outcome"`
+    CreatedAt time.Time `json:"created_at"`
+}
@@ -2,12 +2,31 @@ package models
 
 import "time"
 
+const (
+    RoleUser         = "user"
+    RoleAdmin        = "admin"
+    RoleHotelManager = "hotel_manager"
+    RoleFrontDesk    = "front_desk"
+)
+
+// IsHotelStaff reports whether a role is bound to specific hotels.
+func IsHotelStaff(role string) bool {
+    return role == RoleHotelManager || role == RoleFrontDesk
+}
+
 type User struct {
-    UserID    int       `json:"id"`
-    Username  string    `json:"username"`
-    Email     string    `json:"email"`
-    Password  string    `json:"-"`
-    Role      string    `json:"role"`
-    CreatedAt time.Time `json:"created_at"`
-    UpdatedAt time.Time `json:"updated_at"`
+    UserID           int        `json:"id"`
+    Username         string     `json:"username"`
+    Email            string     `json:"email"`
+    PendingEmail     *string    `json:"pending_email,omitempty"`
+    Password         string     `json:"-"`
+    Role             string     `json:"role"`
+    HotelIDs         []int      `json:"hotel_ids,omitempty"`
+    EmailVerifiedAt  *time.Time `json:"email_verified_at"`
+    TwoFactorEnabled bool       `json:"two_factor_enabled"`
+    DisabledAt       *time.Time `json:"disabled_at,omitempty"`
+    LockedUntil      *time.Time `json:"locked_until,omitempty"`
+    DeletedAt        *time.Time `json:"deleted_at,omitempty"`
+    CreatedAt        time.Time  `json:"created_at"`
+    UpdatedAt        time.Time  `json:"updated_at"`
 }
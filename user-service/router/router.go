@@ -14,6 +14,40 @@ func InitRoutes(e *echo.Echo) {
 
 	e.POST("/register", handler.RegisterUser)
 	e.POST("/login", handler.LoginUser)
+	e.POST("/login/2fa", handler.VerifyLoginChallenge)
+	e.POST("/login/2fa/setup", handler.SetupLoginChallenge)
+	e.GET("/.well-known/jwks.json", handler.JWKS)
+
+	e.GET("/email/verify", handler.VerifyEmail)
+	e.POST("/email/verify", handler.VerifyEmail)
+	e.POST("/email/verify/resend", handler.ResendVerificationEmail)
+	e.POST("/password/forgot", handler.ForgotPassword)
+	e.GET("/password/reset", handler.ResetPasswordForm)
+	e.POST("/password/reset", handler.ResetPassword)
+	e.GET("/email/change/confirm", handler.ConfirmEmailChange)
+	e.POST("/email/change/confirm", handler.ConfirmEmailChange)
+
+	e.POST("/token/refresh", handler.RefreshToken)
+	e.POST("/logout", handler.Logout)
+	e.GET("/token/revocations", handler.ListRevokedSessions)
 
 	e.GET("/user/:id", handler.GetUserByID)
+	e.PATCH("/user/:id", handler.UpdateProfile)
+	e.DELETE("/user/:id", handler.DeleteAccount)
+	e.PUT("/user/:id/password", handler.ChangePassword)
+	e.PUT("/user/:id/hotels", handler.UpdateUserHotels)
+	e.GET("/user/:id/export", handler.ExportUserData)
+
+	e.POST("/2fa/setup", handler.SetupTwoFactor)
+	e.POST("/2fa/enable", handler.EnableTwoFactor)
+	e.POST("/2fa/disable", handler.DisableTwoFactor)
+	e.POST("/2fa/recovery-codes", handler.RegenerateRecoveryCodes)
+
+	e.POST("/admin/users", handler.CreateUser)
+	e.GET("/admin/users", handler.ListUsers)
+	e.PUT("/admin/users/:id/role", handler.UpdateUserRole)
+	e.PUT("/admin/users/:id/status", handler.UpdateUserStatus)
+	e.POST("/admin/users/:id/unlock", handler.UnlockUser)
+	e.GET("/admin/login-attempts", handler.ListLoginAttempts)
+	e.DELETE("/admin/users/:id/2fa", handler.ResetTwoFactor)
 }
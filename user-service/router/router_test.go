@@ -0,0 +1,27 @@
+package router
+
+import (
+	"net/http"
+	"testing"
+	handler "user-service/handlers"
+
+	"github.com/labstack/echo/v4"
+)
+
+// TestEmailLinkRoutes checks that the links sent in account emails can be
+// opened, which browsers and mail clients always do with GET.
+func TestEmailLinkRoutes(t *testing.T) {
+	e := echo.New()
+	InitRoutes(e)
+
+	registered := map[string]bool{}
+	for _, route := range e.Routes() {
+		registered[route.Method+" "+route.Path] = true
+	}
+
+	for _, path := range []string{handler.VerifyEmailPath, handler.ResetPasswordPath, handler.ConfirmEmailChangePath} {
+		if !registered[http.MethodGet+" "+path] {
+			t.Errorf("email link %s has no GET route", path)
+		}
+	}
+}
@@ -0,0 +1,75 @@
+package totp
+
+import (
+	"encoding/base32"
+	"strings"
+	"testing"
+	"time"
+)
+
+// rfcSecret is the SHA1 key of the RFC 6238 test vectors.
+var rfcSecret = base32.StdEncoding.WithPadding(base32.NoPadding).EncodeToString([]byte("12345678901234567890"))
+
+func TestCodeRFC6238(t *testing.T) {
+	// The RFC lists eight digit codes; the last six are what a six digit
+	// authenticator shows.
+	tests := []struct {
+		unix int64
+		want string
+	}{
+		{59, "94287082"},
+		{1111111109, "07081804"},
+		{1111111111, "14050471"},
+		{1234567890, "89005924"},
+		{2000000000, "69279037"},
+		{20000000000, "65353130"},
+	}
+
+	for _, tt := range tests {
+		got, err := Code(rfcSecret, Counter(time.Unix(tt.unix, 0)))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if want := tt.want[2:]; got != want {
+			t.Errorf("Code at %d = %s, want %s", tt.unix, got, want)
+		}
+	}
+}
+
+func TestValidate(t *testing.T) {
+	now := time.Unix(1234567890, 0)
+	current := Counter(now)
+	previous, _ := Code(rfcSecret, current-1)
+	tooOld, _ := Code(rfcSecret, current-2)
+
+	if counter, ok := Validate(rfcSecret, "005924", now, 0); !ok || counter != current {
+		t.Fatalf("current code: counter = %d, ok = %v", counter, ok)
+	}
+	if _, ok := Validate(rfcSecret, previous, now, 0); !ok {
+		t.Fatal("code of the previous step should be accepted")
+	}
+	if _, ok := Validate(rfcSecret, tooOld, now, 0); ok {
+		t.Fatal("code two steps old should be rejected")
+	}
+	if _, ok := Validate(rfcSecret, "005924", now, current); ok {
+		t.Fatal("a code must not be accepted twice")
+	}
+	if _, ok := Validate(rfcSecret, "12345", now, 0); ok {
+		t.Fatal("short code should be rejected")
+	}
+}
+
+func TestGenerateSecretAndURI(t *testing.T) {
+	secret, err := GenerateSecret()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := Code(secret, 1); err != nil {
+		t.Fatalf("generated secret does not decode: %v", err)
+	}
+
+	uri := URI("Hotel Booking", "admin@example.com", secret)
+	if !strings.HasPrefix(uri, "otpauth://totp/Hotel%20Booking:admin@example.com?") || !strings.Contains(uri, "secret="+secret) {
+		t.Fatalf("unexpected URI %s", uri)
+	}
+}